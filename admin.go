@@ -0,0 +1,334 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AdminHandler serves an HTTP API to inspect and manage the plugins:
+//
+//	GET    /plugins                list plugins, ?selector=k=v,k2=v2 filters by labels
+//	POST   /plugins                start the plugin in the request body
+//	GET    /plugins/{key}          describe a plugin
+//	DELETE /plugins/{key}          stop a plugin
+//	POST   /plugins/{key}/restart  restart a plugin, optionally with a new PluginInfo
+//	POST   /plugins/{key}/unquarantine  let a quarantined plugin run again
+//	GET    /events                 stream events as newline delimited JSON, ?since=RFC3339 replays stored ones first
+//	GET    /logs                   stream plugin stderr lines as newline delimited JSON, ?plugin=key for one plugin
+//	PUT    /log-level              set the log level, ?level=debug&plugin=key for one plugin
+//	       /debug/pprof/{key}/...  proxy the pprof endpoints of a plugin
+//	GET    /inventory              the plugin binaries as CycloneDX, or SPDX with ?format=spdx
+//	GET    /metrics                plugin metrics in the Prometheus text format
+//	GET    /dashboard/             a web UI listing plugins and their health, tailing logs and restarting plugins
+//
+// With ManagerConfig.AdminAuth, callers authenticate with a bearer token or
+// TLS client certificate and each route requires a Role.
+func (m *Manager[C]) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plugins", m.guard("list", m.adminList))
+	mux.HandleFunc("POST /plugins", m.guard("start", m.adminStart))
+	mux.HandleFunc("GET /plugins/{key}", m.guard("describe", m.adminDescribe))
+	mux.HandleFunc("DELETE /plugins/{key}", m.guard("stop", m.adminStop))
+	mux.HandleFunc("POST /plugins/{key}/restart", m.guard("restart", m.adminRestart))
+	mux.HandleFunc("POST /plugins/{key}/unquarantine", m.guard("unquarantine", m.adminUnquarantine))
+	mux.HandleFunc("GET /events", m.guard("events", m.adminEvents))
+	mux.HandleFunc("GET /logs", m.guard("logs", m.adminLogs))
+	mux.HandleFunc("PUT /log-level", m.guard("log-level", m.adminLogLevel))
+	mux.HandleFunc("GET /metrics", m.guard("metrics", m.adminMetrics))
+	mux.HandleFunc("GET /inventory", m.guard("inventory", m.adminInventory))
+	mux.HandleFunc("GET /restart-plan", m.guard("plan-restart", m.adminPlanRestart))
+	mux.HandleFunc("GET /doctor", m.guard("doctor", m.adminDoctor))
+	mux.HandleFunc("/debug/pprof/{key}/{path...}", m.guard("pprof", m.adminPprof))
+	// The dashboard holds no data; it authenticates its own API calls.
+	mux.Handle("GET /dashboard/", dashboardHandler())
+	return mux
+}
+
+func (m *Manager[C]) adminList(w http.ResponseWriter, r *http.Request) {
+	selector, err := parseSelector(r.URL.Query().Get("selector"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	infos, err := m.ListPlugins(MatchLabels(selector))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	for i := range infos {
+		infos[i] = infos[i].Redacted()
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (m *Manager[C]) adminStart(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pm, err := ParsePluginInfo(body)
+	if err != nil {
+		writeAdminError(w, &badRequestError{err})
+		return
+	}
+	if _, ok := m.getPlugin(pm.Key); ok {
+		http.Error(w, fmt.Sprintf("plugin %v already exists", pm.Key), http.StatusConflict)
+		return
+	}
+	p, err := m.StartPlugin(pm)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, p.Info.Redacted())
+}
+
+func (m *Manager[C]) adminDescribe(w http.ResponseWriter, r *http.Request) {
+	d, err := m.DescribePlugin(r.PathValue("key"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	d.Info = d.Info.Redacted()
+	writeJSON(w, http.StatusOK, d)
+}
+
+func (m *Manager[C]) adminStop(w http.ResponseWriter, r *http.Request) {
+	if err := m.StopPlugin(PluginInfo{Key: r.PathValue("key")}); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager[C]) adminRestart(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	info, err := m.restartByKey(r.PathValue("key"), body)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info.Redacted())
+}
+
+func (m *Manager[C]) adminInventory(w http.ResponseWriter, r *http.Request) {
+	report, err := m.InventoryReport(InventoryFormat(r.URL.Query().Get("format")))
+	if err != nil {
+		if !errors.Is(err, ErrManagerClosed) {
+			err = &badRequestError{err}
+		}
+		writeAdminError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(report)
+}
+
+func (m *Manager[C]) adminPlanRestart(w http.ResponseWriter, r *http.Request) {
+	plan, err := m.PlanRestart(r.URL.Query()["plugin"])
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (m *Manager[C]) adminDoctor(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.Doctor())
+}
+
+func (m *Manager[C]) adminUnquarantine(w http.ResponseWriter, r *http.Request) {
+	if err := m.Unquarantine(r.PathValue("key")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *Manager[C]) adminEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	var events <-chan Event
+	var cancel func()
+	if since := r.URL.Query().Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if events, cancel, err = m.SubscribeSince(t, 64); err != nil {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+	} else {
+		events, cancel = m.Subscribe(64)
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func (m *Manager[C]) adminLogs(w http.ResponseWriter, r *http.Request) {
+	flusher, _ := w.(http.Flusher)
+	lines, cancel := m.SubscribeLogs(r.URL.Query().Get("plugin"), 256)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case l := <-lines:
+			if err := enc.Encode(l); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// ObserverHandler serves the read-only part of AdminHandler: GET /plugins,
+// GET /plugins/{key}, GET /events, GET /logs and GET /metrics.
+func (m *Manager[C]) ObserverHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /plugins", m.guard("list", m.adminList))
+	mux.HandleFunc("GET /plugins/{key}", m.guard("describe", m.adminDescribe))
+	mux.HandleFunc("GET /events", m.guard("events", m.adminEvents))
+	mux.HandleFunc("GET /logs", m.guard("logs", m.adminLogs))
+	mux.HandleFunc("GET /metrics", m.guard("metrics", m.adminMetrics))
+	return mux
+}
+
+// restartByKey restarts a running plugin with the PluginInfo encoded in
+// infoJSON, or with its current one when infoJSON is empty.
+func (m *Manager[C]) restartByKey(key string, infoJSON []byte) (PluginInfo, error) {
+	p, ok := m.getPlugin(key)
+	if !ok {
+		return PluginInfo{}, fmt.Errorf("%w: %v", ErrPluginNotFound, key)
+	}
+	pm := p.Info
+	if len(bytes.TrimSpace(infoJSON)) > 0 {
+		var err error
+		if pm, err = ParsePluginInfo(infoJSON); err != nil {
+			return PluginInfo{}, &badRequestError{err}
+		}
+		pm.Key = key
+	}
+	if err := m.RestartPlugin(pm); err != nil {
+		return PluginInfo{}, err
+	}
+	p, ok = m.getPlugin(key)
+	if !ok {
+		return PluginInfo{}, fmt.Errorf("%w: %v", ErrPluginNotFound, key)
+	}
+	return p.Info, nil
+}
+
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *badRequestError) Unwrap() error {
+	return e.err
+}
+
+// parseSelector parses label selectors of the form "k=v,k2=v2".
+func parseSelector(s string) (map[string]string, error) {
+	selector := make(map[string]string)
+	if s == "" {
+		return selector, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label selector %q", kv)
+		}
+		selector[k] = v
+	}
+	return selector, nil
+}
+
+func adminStatus(err error) int {
+	var bad *badRequestError
+	switch {
+	case errors.As(err, &bad),
+		errors.Is(err, ErrBinaryNotFound),
+		errors.Is(err, ErrNotExecutable):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPluginNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrManagerClosed),
+		errors.Is(err, ErrGateTimeout),
+		errors.Is(err, ErrShedLoad),
+		errors.Is(err, ErrFDExhausted):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrQuarantined):
+		return http.StatusConflict
+	case errors.Is(err, ErrAdmissionDenied),
+		errors.Is(err, ErrNotApproved),
+		errors.Is(err, ErrVulnerable),
+		errors.Is(err, ErrLicenseExpired),
+		errors.Is(err, ErrLicenseInvalid),
+		errors.Is(err, ErrProvenance),
+		errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeAdminError writes err as text, or as JSON listing its issues when it
+// is a ValidationError.
+func writeAdminError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, adminStatus(err), map[string]any{"error": "invalid plugin info", "issues": verr.Issues})
+		return
+	}
+	http.Error(w, err.Error(), adminStatus(err))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrAdmissionDenied = errors.New("plugin denied admission")
+
+// AdmissionRequest is what admission controllers decide on: the plugin as
+// requested plus metadata computed by the manager.
+type AdmissionRequest struct {
+	Manager     string     `json:"manager"`
+	HostVersion string     `json:"hostVersion,omitempty"`
+	Plugin      PluginInfo `json:"plugin"`
+	// Checksum is the sha256 of the binary that is about to be launched.
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+	// ChecksumVerified reports whether the plugin declared a checksum, which
+	// is verified before launch.
+	ChecksumVerified bool `json:"checksumVerified"`
+	// Provenance is set when the plugin has a verified attestation or SBOM.
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// AdmissionController enforces policies on the plugins the manager starts.
+// Admit returns an error, usually an *AdmissionDeniedError, to refuse a
+// plugin.
+type AdmissionController interface {
+	Admit(ctx context.Context, req AdmissionRequest) error
+}
+
+type AdmissionControllerFunc func(ctx context.Context, req AdmissionRequest) error
+
+func (f AdmissionControllerFunc) Admit(ctx context.Context, req AdmissionRequest) error {
+	return f(ctx, req)
+}
+
+type AdmissionDeniedError struct {
+	Key     string
+	Reasons []string
+}
+
+func (e *AdmissionDeniedError) Error() string {
+	if len(e.Reasons) == 0 {
+		return fmt.Sprintf("plugin %v denied admission", e.Key)
+	}
+	return fmt.Sprintf("plugin %v denied admission: %v", e.Key, e.Reasons)
+}
+
+func (e *AdmissionDeniedError) Unwrap() error {
+	return ErrAdmissionDenied
+}
+
+func (m *Manager[C]) admit(pm PluginInfo, prov *Provenance) error {
+	if len(m.config.Admission) == 0 {
+		return nil
+	}
+	fi, err := os.Stat(pm.BinPath)
+	if err != nil {
+		return err
+	}
+	checksum, err := m.binaryDigest(pm.BinPath)
+	if err != nil {
+		return err
+	}
+	req := AdmissionRequest{
+		Manager:          m.Name,
+		HostVersion:      m.config.HostVersion,
+		Plugin:           pm,
+		Checksum:         checksum,
+		Size:             fi.Size(),
+		ChecksumVerified: pm.Checksum != "",
+		Provenance:       prov,
+	}
+	for _, c := range m.config.Admission {
+		if err := c.Admit(context.Background(), req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
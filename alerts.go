@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// Alert is an event delivered to an AlertSink. Suppressed counts the
+// alerts of the route dropped by its rate limit since the last one sent.
+type Alert struct {
+	Event
+	Suppressed int
+}
+
+func (a Alert) Summary() string {
+	s := fmt.Sprintf("plugin %v: %v", a.Key, a.Type)
+	if a.Message != "" {
+		s += ": " + a.Message
+	}
+	if a.Suppressed > 0 {
+		s += fmt.Sprintf(" (%v more alerts suppressed)", a.Suppressed)
+	}
+	return s
+}
+
+type AlertSink interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// AlertConfig sends events to alert sinks, for teams without an
+// observability stack to route them.
+type AlertConfig struct {
+	Routes []AlertRoute
+	// Timeout bounds each Send. Defaults to 10s.
+	Timeout time.Duration
+}
+
+type AlertRoute struct {
+	Sink AlertSink
+	// Types selects the events sent to Sink. Defaults to failed exits,
+	// EventRestartPrevented and EventQuarantined.
+	Types []EventType
+	// RateLimit drops alerts over the limit. Defaults to one a minute with
+	// bursts of 5.
+	RateLimit Limit
+}
+
+func alertDefaults(config *AlertConfig) {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	for i := range config.Routes {
+		if config.Routes[i].RateLimit.Rate == 0 {
+			config.Routes[i].RateLimit = Limit{Rate: 1.0 / 60, Burst: 5}
+		}
+	}
+}
+
+// startAlerts subscribes a sender for every route.
+func (m *Manager[C]) startAlerts(ctx context.Context, config *AlertConfig) {
+	for _, route := range config.Routes {
+		match := failureEvents
+		if len(route.Types) > 0 {
+			types := make(map[EventType]bool, len(route.Types))
+			for _, t := range route.Types {
+				types[t] = true
+			}
+			match = func(e Event) bool { return types[e.Type] }
+		}
+		limit := newTokenBucket(route.RateLimit)
+		suppressed := 0
+		m.forwardEvents(ctx, 64, func(e Event) {
+			if !match(e) {
+				return
+			}
+			if limit.reserve(time.Now()) > 0 {
+				limit.cancel()
+				suppressed++
+				return
+			}
+			sendCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+			err := route.Sink.Send(sendCtx, Alert{Event: e, Suppressed: suppressed})
+			cancel()
+			if err != nil {
+				m.config.Logger.Warn("failed to send alert", "event", e.Type, LogKeyReason, err)
+				return
+			}
+			suppressed = 0
+		})
+	}
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%v answered %v", url, resp.Status)
+	}
+	return nil
+}
+
+// SlackSink posts alerts to a Slack incoming webhook.
+type SlackSink struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (s *SlackSink) Send(ctx context.Context, a Alert) error {
+	return postJSON(ctx, s.Client, s.WebhookURL, map[string]string{"text": a.Summary()})
+}
+
+// PagerDutySink triggers PagerDuty incidents through the Events API v2.
+// Incidents are deduplicated per plugin and event type, and EventRecovered
+// and EventUnquarantined resolve those opened by EventDegraded and
+// EventQuarantined; route them to the sink for that.
+type PagerDutySink struct {
+	RoutingKey string
+	// Severity defaults to "error".
+	Severity string
+	// URL defaults to PagerDutyEventsURL.
+	URL    string
+	Client *http.Client
+}
+
+func (s *PagerDutySink) Send(ctx context.Context, a Alert) error {
+	action, dedup := "trigger", a.Key+"/"+string(a.Type)
+	switch a.Type {
+	case EventRecovered:
+		action, dedup = "resolve", a.Key+"/"+string(EventDegraded)
+	case EventUnquarantined:
+		action, dedup = "resolve", a.Key+"/"+string(EventQuarantined)
+	}
+	severity := s.Severity
+	if severity == "" {
+		severity = "error"
+	}
+	url := s.URL
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+	source, _ := os.Hostname()
+	return postJSON(ctx, s.Client, url, map[string]any{
+		"routing_key":  s.RoutingKey,
+		"event_action": action,
+		"dedup_key":    dedup,
+		"payload": map[string]any{
+			"summary":   a.Summary(),
+			"source":    source,
+			"severity":  severity,
+			"timestamp": a.Time.Format(time.RFC3339),
+			"component": a.Key,
+			"group":     string(a.Type),
+			"custom_details": map[string]any{
+				"generation": a.Generation,
+				"labels":     a.Labels,
+				"suppressed": a.Suppressed,
+			},
+		},
+	})
+}
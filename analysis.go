@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const EventAnalysis EventType = "analysis"
+
+var ErrAnalysisRollback = errors.New("analyzer decided to roll back")
+
+type Decision int
+
+const (
+	DecisionContinue Decision = iota
+	DecisionPromote
+	DecisionRollback
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionPromote:
+		return "promote"
+	case DecisionRollback:
+		return "rollback"
+	default:
+		return "continue"
+	}
+}
+
+// AnalysisWindow holds the call outcomes of the old and new instances
+// observed between Start and End.
+type AnalysisWindow struct {
+	Key   string
+	Start time.Time
+	End   time.Time
+	Old   InstanceStats
+	New   InstanceStats
+}
+
+type Analyzer interface {
+	Analyze(window AnalysisWindow) Decision
+}
+
+type AnalyzerFunc func(window AnalysisWindow) Decision
+
+func (f AnalyzerFunc) Analyze(window AnalysisWindow) Decision {
+	return f(window)
+}
+
+type AnalysisResult struct {
+	Decision Decision
+	Window   AnalysisWindow
+}
+
+func (m *Manager[C]) analyze(analyzer Analyzer, window AnalysisWindow) Decision {
+	decision := analyzer.Analyze(window)
+	m.events.publish(Event{
+		Type:    EventAnalysis,
+		Key:     window.Key,
+		Message: fmt.Sprintf("analyzer decided to %v", decision),
+		Data:    AnalysisResult{Decision: decision, Window: window},
+	})
+	return decision
+}
+
+func (m *Manager[C]) analyzeDeployment(pluginKey string, d *deployment[C]) {
+	clock := m.config.Clock
+	ticker := clock.NewTicker(d.opts.AnalysisInterval)
+	defer ticker.Stop()
+
+	start := clock.Now()
+	for range ticker.C() {
+		m.mu.RLock()
+		current := m.deploys[pluginKey] == d
+		m.mu.RUnlock()
+		if !current {
+			return
+		}
+
+		d.mu.Lock()
+		window := AnalysisWindow{
+			Key:   pluginKey,
+			Start: start,
+			End:   clock.Now(),
+			Old:   d.blueWindow,
+			New:   d.greenWindow,
+		}
+		d.blueWindow, d.greenWindow = InstanceStats{}, InstanceStats{}
+		d.mu.Unlock()
+		start = window.End
+
+		switch m.analyze(d.opts.Analyzer, window) {
+		case DecisionPromote:
+			m.Promote(pluginKey)
+			return
+		case DecisionRollback:
+			m.Rollback(pluginKey)
+			return
+		}
+	}
+}
+
+// analyzeBatch compares calls served by a restarted batch with calls served
+// by the members still pending over one analysis interval.
+func (m *Manager[C]) analyzeBatch(
+	group string,
+	batch []PluginInfo,
+	pending []PluginInfo,
+	opts RollingRestartOptions,
+) Decision {
+	keys := func(infos []PluginInfo) []string {
+		out := make([]string, 0, len(infos))
+		for _, info := range infos {
+			out = append(out, info.Key)
+		}
+		return out
+	}
+	newKeys, oldKeys := keys(batch), keys(pending)
+
+	clock := m.config.Clock
+	start := clock.Now()
+	newBefore, oldBefore := m.stats.totals(newKeys), m.stats.totals(oldKeys)
+	sleep(m.ctx, clock, opts.AnalysisInterval)
+	newAfter, oldAfter := m.stats.totals(newKeys), m.stats.totals(oldKeys)
+
+	return m.analyze(opts.Analyzer, AnalysisWindow{
+		Key:   group,
+		Start: start,
+		End:   clock.Now(),
+		Old:   oldAfter.sub(oldBefore),
+		New:   newAfter.sub(newBefore),
+	})
+}
+
+func (s InstanceStats) sub(before InstanceStats) InstanceStats {
+	out := InstanceStats{
+		Calls:  s.Calls - before.Calls,
+		Errors: s.Errors - before.Errors,
+	}
+	if out.Calls > 0 {
+		out.ErrorRate = float64(out.Errors) / float64(out.Calls)
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	EventApproved EventType = "approved"
+	EventRejected EventType = "rejected"
+)
+
+var ErrNotApproved = errors.New("plugin binary not approved")
+
+// Approver decides whether a binary that has never been approved before may
+// be launched. Approve may block, e.g. while waiting for an operator.
+type Approver interface {
+	Approve(ctx context.Context, pm PluginInfo, checksum string) (bool, error)
+}
+
+type ApproverFunc func(ctx context.Context, pm PluginInfo, checksum string) (bool, error)
+
+func (f ApproverFunc) Approve(ctx context.Context, pm PluginInfo, checksum string) (bool, error) {
+	return f(ctx, pm, checksum)
+}
+
+type Approval struct {
+	Checksum   string    `json:"checksum"`
+	Key        string    `json:"key"`
+	Version    string    `json:"version,omitempty"`
+	ApprovedAt time.Time `json:"approvedAt"`
+}
+
+// ApprovalStore records the binaries that were approved.
+type ApprovalStore interface {
+	Approved(checksum string) (bool, error)
+	Record(a Approval) error
+}
+
+// FileApprovalStore keeps approvals in a JSON file.
+type FileApprovalStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileApprovalStore(path string) *FileApprovalStore {
+	return &FileApprovalStore{path: path}
+}
+
+func (s *FileApprovalStore) load() (map[string]Approval, error) {
+	approvals := make(map[string]Approval)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return approvals, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &approvals); err != nil {
+		return nil, fmt.Errorf("reading approvals %v: %w", s.path, err)
+	}
+	return approvals, nil
+}
+
+func (s *FileApprovalStore) Approved(checksum string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	approvals, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	_, ok := approvals[checksum]
+	return ok, nil
+}
+
+func (s *FileApprovalStore) Record(a Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	approvals, err := s.load()
+	if err != nil {
+		return err
+	}
+	approvals[a.Checksum] = a
+
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// checkApproval asks the configured Approver about binaries that were not
+// approved before. Approvals are requested one at a time, so concurrent
+// loads of new binaries queue behind each other. It runs without m.mu, and
+// the request is canceled after ApprovalTimeout or on shutdown.
+func (m *Manager[C]) checkApproval(pm PluginInfo) error {
+	if m.config.Approver == nil {
+		return nil
+	}
+	checksum, err := m.binaryDigest(pm.BinPath)
+	if err != nil {
+		return err
+	}
+
+	m.approveMu.Lock()
+	defer m.approveMu.Unlock()
+
+	store := m.config.ApprovalStore
+	if ok, err := store.Approved(checksum); err != nil || ok {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.ApprovalTimeout)
+	defer cancel()
+	ok, err := m.config.Approver.Approve(ctx, pm, checksum)
+	if err != nil {
+		return fmt.Errorf("approving plugin %v: %w", pm.Key, err)
+	}
+	if !ok {
+		m.events.publish(pluginEvent(EventRejected, pm, checksum))
+		return fmt.Errorf("%w: plugin %v sha256:%v", ErrNotApproved, pm.Key, checksum)
+	}
+
+	if err := store.Record(Approval{
+		Checksum:   checksum,
+		Key:        pm.Key,
+		Version:    pm.Version,
+		ApprovedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	m.events.publish(pluginEvent(EventApproved, pm, checksum))
+	return nil
+}
@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ArtifactCache is a content addressed store of plugin binaries. Artifacts
+// are stored under their sha256 digest and evicted least recently used first
+// once the cache grows past its size limit. Artifacts run by a manager are
+// never evicted.
+type ArtifactCache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	// pinned counts the users of each digest that must not be evicted.
+	pinned map[string]int
+}
+
+// NewArtifactCache creates a cache in dir. A maxBytes of zero disables
+// eviction.
+func NewArtifactCache(dir string, maxBytes int64) (*ArtifactCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &ArtifactCache{dir: dir, maxBytes: maxBytes}, nil
+}
+
+func (c *ArtifactCache) path(digest string) string {
+	return filepath.Join(c.dir, digest)
+}
+
+// Get returns the path of the artifact with the given digest and marks it as
+// recently used.
+func (c *ArtifactCache) Get(digest string) (string, bool) {
+	digest, err := normalizeChecksum(digest)
+	if err != nil || digest == "" {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(digest)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return path, true
+}
+
+// Put stores the content of r and returns its digest and path. If digest is
+// not empty the content must match it.
+func (c *ArtifactCache) Put(r io.Reader, digest string) (string, string, error) {
+	digest, err := normalizeChecksum(digest)
+	if err != nil {
+		return "", "", err
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".incoming-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if digest != "" && digest != sum {
+		return "", "", fmt.Errorf("artifact digest %v does not match expected %v", sum, digest)
+	}
+	if err := os.Chmod(tmp.Name(), 0o700); err != nil {
+		return "", "", err
+	}
+
+	c.mu.Lock()
+	path := c.path(sum)
+	err = os.Rename(tmp.Name(), path)
+	c.mu.Unlock()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := c.evict(sum); err != nil {
+		return "", "", err
+	}
+	return sum, path, nil
+}
+
+// Evict removes least recently used artifacts until the cache fits within
+// its size limit, sparing the ones in use.
+func (c *ArtifactCache) Evict() error {
+	return c.evict("")
+}
+
+// evict is Evict, also sparing keep.
+func (c *ArtifactCache) evict(keep string) error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size()
+	}
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if e.Name() == keep || c.pinned[e.Name()] > 0 {
+			continue
+		}
+		if err := os.Remove(c.path(e.Name())); err != nil {
+			return err
+		}
+		total -= e.Size()
+	}
+	return nil
+}
+
+// pin keeps the artifact with the given digest, fetched or not yet, from
+// being evicted until the returned func is called.
+func (c *ArtifactCache) pin(digest string) func() {
+	digest, err := normalizeChecksum(digest)
+	if err != nil || digest == "" {
+		return func() {}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pinned == nil {
+		c.pinned = make(map[string]int)
+	}
+	c.pinned[digest]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.pinned[digest]--; c.pinned[digest] <= 0 {
+				delete(c.pinned, digest)
+			}
+		})
+	}
+}
+
+// VerifyCache re-hashes every artifact and removes the ones whose content no
+// longer matches their digest. It returns the digests that were removed.
+func (c *ArtifactCache) VerifyCache() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []string{}
+	for _, e := range entries {
+		sum, err := fileSHA256(c.path(e.Name()))
+		if err != nil {
+			return removed, err
+		}
+		if sum == e.Name() {
+			continue
+		}
+		if err := os.Remove(c.path(e.Name())); err != nil {
+			return removed, err
+		}
+		removed = append(removed, e.Name())
+	}
+	return removed, nil
+}
+
+func (c *ArtifactCache) entries() ([]os.FileInfo, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := []os.FileInfo{}
+	for _, d := range dirEntries {
+		if d.IsDir() || d.Name()[0] == '.' {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
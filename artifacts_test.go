@@ -0,0 +1,83 @@
+package manager
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestArtifactCacheEviction(t *testing.T) {
+	cache, err := NewArtifactCache(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(content string) string {
+		t.Helper()
+		digest, _, err := cache.Put(strings.NewReader(content), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return digest
+	}
+	cached := func(digest string) bool {
+		_, err := os.Stat(cache.path(digest))
+		return err == nil
+	}
+
+	a := put("aaaaaaaa")
+	unpin := cache.pin(a)
+	b := put("bbbbbbbb")
+	if !cached(a) || !cached(b) {
+		t.Fatalf("cached a, b = %v, %v, want the pinned and the just added artifact kept", cached(a), cached(b))
+	}
+
+	unpin()
+	c := put("cccccccc")
+	if cached(a) || cached(b) || !cached(c) {
+		t.Errorf("cached a, b, c = %v, %v, %v, want only c", cached(a), cached(b), cached(c))
+	}
+}
+
+func TestArtifactCacheKeepsRunningBinaries(t *testing.T) {
+	bin, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := fileSHA256(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, bin)
+	}))
+	defer mirror.Close()
+
+	cache, err := NewArtifactCache(t.TempDir(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestManager(t, ManagerConfig{Fetcher: NewFetcher(cache, nil)})
+	pm := PluginInfo{Key: "fetched", Checksum: "sha256:" + digest, Mirrors: []string{mirror.URL}}
+	if _, err := m.StartPlugin(pm); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := cache.Put(strings.NewReader("other"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(digest); !ok {
+		t.Fatal("binary of a running plugin was evicted")
+	}
+
+	if err := m.StopPlugin(pm); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Evict(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(digest); ok {
+		t.Error("binary of a stopped plugin was kept")
+	}
+}
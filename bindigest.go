@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"os"
+	"sort"
+)
+
+type digestEntry struct {
+	fi     os.FileInfo
+	digest string
+}
+
+// binaryDigest returns the sha256 of a plugin binary, hashing it again only
+// when its identity, size or modification time changed, so keys sharing a
+// binary do not each hash it on every start.
+func (m *Manager[C]) binaryDigest(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	m.digestMu.Lock()
+	e, ok := m.digests[path]
+	m.digestMu.Unlock()
+	if ok && os.SameFile(e.fi, fi) && e.fi.Size() == fi.Size() && e.fi.ModTime().Equal(fi.ModTime()) {
+		return e.digest, nil
+	}
+
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	m.digestMu.Lock()
+	m.digests[path] = digestEntry{fi: fi, digest: digest}
+	m.digestMu.Unlock()
+	return digest, nil
+}
+
+// sharedBinaries sets SharedBinary on plugins started from the same binary
+// path as others in infos.
+func sharedBinaries(infos []PluginInfo) {
+	keys := make(map[string][]string)
+	for _, info := range infos {
+		keys[info.BinPath] = append(keys[info.BinPath], info.Key)
+	}
+	for i, info := range infos {
+		shared := keys[info.BinPath]
+		if len(shared) < 2 {
+			continue
+		}
+		others := make([]string, 0, len(shared)-1)
+		for _, key := range shared {
+			if key != info.Key {
+				others = append(others, key)
+			}
+		}
+		sort.Strings(others)
+		infos[i].SharedBinary = others
+	}
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const EventBinaryChanged EventType = "binary_changed"
+
+var ErrBinaryLocked = errors.New("plugin binary is locked for writing")
+
+const binaryLockPoll = 100 * time.Millisecond
+
+// LockBinary takes an exclusive advisory lock on a plugin binary, for
+// deployment tools that overwrite binaries in place. Plugins using the
+// binary are not restarted or reloaded until unlock is called. Tools that
+// rename a new file over the binary need no lock.
+func LockBinary(ctx context.Context, path string) (unlock func() error, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			return func() error {
+				unlockFile(f)
+				return f.Close()
+			}, nil
+		}
+		if err := sleepCtx(ctx, binaryLockPoll); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+}
+
+// awaitWriters waits until no tool holds LockBinary on path.
+func awaitWriters(ctx context.Context, clock Clock, path string, timeout time.Duration) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	deadline := clock.Now().Add(timeout)
+	for {
+		locked, err := tryRLockFile(f)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return unlockFile(f)
+		}
+		if clock.Now().After(deadline) {
+			return fmt.Errorf("%w: %v after %v", ErrBinaryLocked, path, timeout)
+		}
+		if err := sleep(ctx, clock, binaryLockPoll); err != nil {
+			return ErrManagerClosed
+		}
+	}
+}
+
+// openBinary opens the binary a plugin is started from. The handle is held
+// while the plugin runs, keeping the file from being overwritten on Windows
+// and recording what it was to notice when it is replaced.
+func openBinary(path string) (*os.File, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+func sameBinary(a, b os.FileInfo) bool {
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// watchBinaries reloads plugins whose binary was replaced every interval
+// until ctx is done.
+func (m *Manager[C]) watchBinaries(ctx context.Context, interval time.Duration) {
+	ticker := m.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			m.reloadReplaced()
+		}
+	}
+}
+
+func (m *Manager[C]) reloadReplaced() {
+	m.mu.Lock()
+	var replaced []*pluginInstance[C]
+	for _, p := range m.plugins {
+		if p.binInfo == nil {
+			continue
+		}
+		if fi, err := os.Stat(p.Info.BinPath); err == nil && !sameBinary(p.binInfo, fi) {
+			// Report each change once, even if the reload is refused.
+			p.binInfo = fi
+			replaced = append(replaced, p)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, p := range replaced {
+		m.reloadBinary(p.Info)
+	}
+}
+
+// reloadBinary restarts a plugin from its replaced binary once it is fully
+// written, keeping the running instance if the new binary fails its
+// checksum.
+func (m *Manager[C]) reloadBinary(pm PluginInfo) {
+	log := m.logFor(pm)
+	err := awaitWriters(m.ctx, m.config.Clock, pm.BinPath, m.config.BinaryLockTimeout)
+	if err == nil {
+		var digest string
+		if digest, err = m.binaryDigest(pm.BinPath); err == nil {
+			err = matchChecksum(pm, digest)
+		}
+	}
+	if err != nil {
+		log.Error("plugin binary was replaced, keeping the running plugin", LogKeyReason, err)
+		m.events.publish(pluginEvent(EventChecksumChanged, pm, err.Error()))
+		return
+	}
+	log.Info("plugin binary was replaced, reloading plugin")
+	m.unstage(pm.Key)
+	m.events.publish(pluginEvent(EventBinaryChanged, pm, "binary replaced, reloading plugin"))
+	if err := m.RestartPlugin(pm); err != nil {
+		log.Error("failed to reload plugin", LogKeyReason, err)
+	}
+}
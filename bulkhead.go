@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrBulkheadFull = errors.New("plugin has too many calls in flight")
+
+type BulkheadStats struct {
+	InFlight int
+	Max      int
+	Rejected int64
+	// Saturation is InFlight divided by Max.
+	Saturation float64
+}
+
+type bulkhead struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	rejected int64
+}
+
+func (b *bulkhead) acquire() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inFlight >= b.max {
+		b.rejected++
+		return false
+	}
+	b.inFlight++
+	return true
+}
+
+func (b *bulkhead) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inFlight--
+}
+
+func (b *bulkhead) snapshot() BulkheadStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BulkheadStats{
+		InFlight:   b.inFlight,
+		Max:        b.max,
+		Rejected:   b.rejected,
+		Saturation: float64(b.inFlight) / float64(b.max),
+	}
+}
+
+type bulkheads struct {
+	mu      sync.Mutex
+	plugins map[string]*bulkhead
+}
+
+func newBulkheads() *bulkheads {
+	return &bulkheads{plugins: make(map[string]*bulkhead)}
+}
+
+// get returns the bulkhead of a plugin, or nil if its calls are not
+// limited.
+func (b *bulkheads) get(info PluginInfo, defaultMax int) *bulkhead {
+	limit := defaultMax
+	if info.MaxInFlight > 0 {
+		limit = info.MaxInFlight
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.plugins[info.Key]
+	if !ok {
+		h = &bulkhead{}
+		b.plugins[info.Key] = h
+	}
+	h.mu.Lock()
+	h.max = limit
+	h.mu.Unlock()
+	return h
+}
+
+func (m *Manager[C]) BulkheadStats(pluginKey string) (BulkheadStats, bool) {
+	m.bulkheads.mu.Lock()
+	h, ok := m.bulkheads.plugins[pluginKey]
+	m.bulkheads.mu.Unlock()
+	if !ok {
+		return BulkheadStats{}, false
+	}
+	return h.snapshot(), true
+}
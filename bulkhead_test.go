@@ -0,0 +1,51 @@
+package manager
+
+import "testing"
+
+func TestBulkhead(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxInFlight int
+		defaultMax  int
+		max         int // 0 when calls are not limited
+	}{
+		{name: "unlimited"},
+		{name: "default", defaultMax: 2, max: 2},
+		{name: "plugin limit", maxInFlight: 3, max: 3},
+		{name: "plugin limit overrides default", maxInFlight: 1, defaultMax: 4, max: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newBulkheads()
+			b := bs.get(PluginInfo{Key: "p", MaxInFlight: tt.maxInFlight}, tt.defaultMax)
+			if tt.max == 0 {
+				if b != nil {
+					t.Fatal("got a bulkhead for unlimited calls")
+				}
+				return
+			}
+
+			for i := 0; i < tt.max; i++ {
+				if !b.acquire() {
+					t.Fatalf("call %v rejected, want %v in flight", i, tt.max)
+				}
+			}
+			if b.acquire() {
+				t.Fatal("call over the limit accepted")
+			}
+			stats := b.snapshot()
+			want := BulkheadStats{InFlight: tt.max, Max: tt.max, Rejected: 1, Saturation: 1}
+			if stats != want {
+				t.Errorf("stats = %+v, want %+v", stats, want)
+			}
+
+			b.release()
+			if !b.acquire() {
+				t.Fatal("call rejected after a release")
+			}
+			if again := bs.get(PluginInfo{Key: "p", MaxInFlight: tt.maxInFlight}, tt.defaultMax); again != b {
+				t.Error("get returned a new bulkhead for the same plugin")
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package manager
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	bundleManifest  = "manifest.json"
+	bundleChecksums = "SHA256SUMS"
+	bundleSignature = "SHA256SUMS.sig"
+	bundleBinDir    = "bin"
+)
+
+// ErrBundleSignature is returned by ImportBundle for bundles that are not
+// signed by one of ManagerConfig.BundleKeys.
+var ErrBundleSignature = errors.New("bundle is not signed by a trusted key")
+
+// ExportBundle writes the binaries and manifests of the given plugins into a
+// single tar file at dest, for transport into environments without network
+// access. The checksums of the bundle cover its manifest and binaries and
+// are signed with ManagerConfig.BundleSigningKey when it is set.
+func (m *Manager[C]) ExportBundle(pluginKeys []string, dest string) error {
+	infos := make([]PluginInfo, 0, len(pluginKeys))
+	for _, key := range pluginKeys {
+		p, ok := m.getPlugin(key)
+		if !ok {
+			return fmt.Errorf("%w: %v", ErrPluginNotFound, key)
+		}
+		infos = append(infos, p.Info)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(f)
+
+	err = writeBundle(tw, infos, m.config.BundleSigningKey)
+	if cerr := tw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
+
+func writeBundle(tw *tar.Writer, infos []PluginInfo, key ed25519.PrivateKey) error {
+	manifest := make([]PluginInfo, 0, len(infos))
+	var sums strings.Builder
+	for _, info := range infos {
+		sum, err := fileSHA256(info.BinPath)
+		if err != nil {
+			return err
+		}
+		if info.Checksum != "" {
+			want, err := normalizeChecksum(info.Checksum)
+			if err != nil {
+				return err
+			}
+			if want != sum {
+				return fmt.Errorf("plugin %v binary does not match its checksum", info.Key)
+			}
+		}
+
+		name := path.Join(bundleBinDir, info.Key)
+		if err := addFile(tw, name, info.BinPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(&sums, "%v  %v\n", sum, name)
+
+		info.BinPath = name
+		info.Checksum = sum
+		info.Mirrors = nil
+		info.Restarts = 0
+		manifest = append(manifest, info)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBytes(tw, bundleManifest, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(&sums, "%x  %v\n", sha256.Sum256(data), bundleManifest)
+	if err := addBytes(tw, bundleChecksums, []byte(sums.String()), 0o644); err != nil {
+		return err
+	}
+	if key == nil {
+		return nil
+	}
+	sig := ed25519.Sign(key, []byte(sums.String()))
+	return addBytes(tw, bundleSignature, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), 0o644)
+}
+
+func addFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o755,
+		Size:    st.Size(),
+		ModTime: st.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func addBytes(tw *tar.Writer, name string, data []byte, mode int64) error {
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: int64(len(data))})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// ImportBundle unpacks a bundle created by ExportBundle into the manager's
+// data directory, verifies its signature when ManagerConfig.BundleKeys is
+// set and every binary against the bundled checksums, and loads the
+// plugins. Either all plugins of the bundle are loaded or none.
+func (m *Manager[C]) ImportBundle(src string) ([]PluginInfo, error) {
+	root := filepath.Join(m.config.DataDir, "bundles")
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, err
+	}
+	staging, err := os.MkdirTemp(root, ".import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractBundle(src, staging); err != nil {
+		return nil, err
+	}
+	infos, err := verifyBundle(staging, m.config.BundleKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(root, "bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(dir)
+	if err := os.Rename(staging, dir); err != nil {
+		return nil, err
+	}
+
+	for i := range infos {
+		infos[i].BinPath = filepath.Join(dir, filepath.FromSlash(infos[i].BinPath))
+	}
+	if err := m.loadAll(infos); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return infos, nil
+}
+
+func extractBundle(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("unexpected bundle entry %v", hdr.Name)
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || strings.HasPrefix(name, "..") {
+			return fmt.Errorf("invalid bundle entry %v", hdr.Name)
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(hdr.Mode)&0o755)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func verifyBundle(dir string, keys []ed25519.PublicKey) ([]PluginInfo, error) {
+	sumsData, err := os.ReadFile(filepath.Join(dir, bundleChecksums))
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		if err := verifyBundleSignature(filepath.Join(dir, bundleSignature), sumsData, keys); err != nil {
+			return nil, err
+		}
+	}
+	sums, err := parseChecksums(sumsData)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, bundleManifest))
+	if err != nil {
+		return nil, err
+	}
+	// Bundles written before manifests were checksummed have no sum for
+	// it, which only signed bundles require.
+	if want, ok := sums[bundleManifest]; ok || len(keys) > 0 {
+		if got := fmt.Sprintf("%x", sha256.Sum256(data)); got != want {
+			return nil, errors.New("bundle manifest does not match its checksum")
+		}
+	}
+	infos, err := ParsePluginInfos(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	for _, info := range infos {
+		if !filepath.IsLocal(filepath.FromSlash(info.BinPath)) {
+			return nil, fmt.Errorf("bundled binary of plugin %v is outside the bundle", info.Key)
+		}
+		want, ok := sums[info.BinPath]
+		if !ok || want != info.Checksum {
+			return nil, fmt.Errorf("bundle has no matching checksum for plugin %v", info.Key)
+		}
+		got, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(info.BinPath)))
+		if err != nil {
+			return nil, err
+		}
+		if got != want {
+			return nil, fmt.Errorf("bundled binary of plugin %v does not match its checksum", info.Key)
+		}
+	}
+	return infos, nil
+}
+
+func verifyBundleSignature(file string, signed []byte, keys []ed25519.PublicKey) error {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: bundle is not signed", ErrBundleSignature)
+	}
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBundleSignature, err)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, signed, sig) {
+			return nil
+		}
+	}
+	return ErrBundleSignature
+}
+
+func parseChecksums(data []byte) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line %q", scanner.Text())
+		}
+		sums[name] = sum
+	}
+	return sums, scanner.Err()
+}
+
+// loadAll loads every plugin, after the plugins it depends on, or, if one
+// fails, stops the ones already loaded. Plugins that are already running
+// are not replaced.
+func (m *Manager[C]) loadAll(infos []PluginInfo) error {
+	infos, err := dependencyOrder(infos)
+	if err != nil {
+		return err
+	}
+	for _, pm := range infos {
+		if _, ok := m.getPlugin(pm.Key); ok {
+			return fmt.Errorf("plugin %v is already running", pm.Key)
+		}
+	}
+
+	loaded := []*pluginInstance[C]{}
+	for _, pm := range infos {
+		p, err := m.loadPlugin(pm, m.killed)
+		if err != nil {
+			for _, p := range loaded {
+				p.Stop()
+			}
+			return err
+		}
+		loaded = append(loaded, p)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		for _, p := range loaded {
+			p.Stop()
+		}
+		return ErrManagerClosed
+	}
+	for _, p := range loaded {
+		if _, ok := m.plugins[p.Info.Key]; ok {
+			for _, p := range loaded {
+				p.Stop()
+			}
+			return fmt.Errorf("plugin %v is already running", p.Info.Key)
+		}
+	}
+	for _, p := range loaded {
+		m.plugins[p.Info.Key] = p
+		m.watchLocked(p)
+		m.spawnStandby(p.Info)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerifyBundle(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	otherPub, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	tests := []struct {
+		name   string
+		sign   ed25519.PrivateKey
+		keys   []ed25519.PublicKey
+		tamper string
+		err    error
+	}{
+		{name: "unsigned, no keys"},
+		{name: "signed, no keys", sign: priv},
+		{name: "signed", sign: priv, keys: []ed25519.PublicKey{pub}},
+		{name: "signed by one of the keys", sign: priv, keys: []ed25519.PublicKey{otherPub, pub}},
+		{name: "unsigned", keys: []ed25519.PublicKey{pub}, err: ErrBundleSignature},
+		{name: "untrusted key", sign: otherPriv, keys: []ed25519.PublicKey{pub}, err: ErrBundleSignature},
+		{name: "tampered checksums", sign: priv, keys: []ed25519.PublicKey{pub}, tamper: bundleChecksums, err: ErrBundleSignature},
+		{name: "tampered manifest", sign: priv, keys: []ed25519.PublicKey{pub}, tamper: bundleManifest},
+		{name: "tampered binary", sign: priv, keys: []ed25519.PublicKey{pub}, tamper: "bin/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			bin := filepath.Join(dir, "plugin")
+			if err := os.WriteFile(bin, []byte("binary"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			bundle := filepath.Join(dir, "bundle.tar")
+			f, err := os.Create(bundle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tw := tar.NewWriter(f)
+			if err := writeBundle(tw, []PluginInfo{{Key: "a", BinPath: bin}}, tt.sign); err != nil {
+				t.Fatal(err)
+			}
+			tw.Close()
+			f.Close()
+
+			out := filepath.Join(dir, "out")
+			if err := extractBundle(bundle, out); err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != "" {
+				f, err := os.OpenFile(filepath.Join(out, tt.tamper), os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				f.WriteString("\n")
+				f.Close()
+			}
+
+			infos, err := verifyBundle(out, tt.keys)
+			switch {
+			case tt.tamper != "" && tt.err == nil:
+				if err == nil {
+					t.Fatal("tampered bundle verified")
+				}
+			case !errors.Is(err, tt.err):
+				t.Fatalf("verifyBundle: %v, want %v", err, tt.err)
+			case err == nil && (len(infos) != 1 || infos[0].Key != "a"):
+				t.Fatalf("verifyBundle returned %+v", infos)
+			}
+		})
+	}
+}
+
+func TestVerifyBundleRejectsBinaryOutsideBundle(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "evil"), []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("binary")))
+	manifest := fmt.Sprintf(`[{"key":"a","binPath":"../evil","checksum":"%v"}]`, sum)
+	files := map[string]string{
+		bundleManifest:  manifest,
+		bundleChecksums: sum + "  ../evil\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(out, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := verifyBundle(out, nil); err == nil {
+		t.Fatal("verified a bundle whose binary is outside it")
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	var started []string
+	m := newTestManager(t, ManagerConfig{
+		DataDir: t.TempDir(),
+		Admission: []AdmissionController{AdmissionControllerFunc(func(ctx context.Context, req AdmissionRequest) error {
+			started = append(started, req.Plugin.Key)
+			return nil
+		})},
+	})
+	b := testPluginInfo(t, "b")
+	a := testPluginInfo(t, "a")
+	a.DependsOn = []string{"b"}
+	for _, pm := range []PluginInfo{b, a} {
+		if _, err := m.StartPlugin(pm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundle := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := m.ExportBundle([]string{"a", "b"}, bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	// Running plugins are not replaced.
+	before, err := m.DescribePlugin("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ImportBundle(bundle); err == nil {
+		t.Fatal("imported a bundle of running plugins")
+	}
+	if after, err := m.DescribePlugin("a"); err != nil || after.Info.Generation != before.Info.Generation {
+		t.Fatalf("plugin a was replaced: generation %v, want %v (%v)", after.Info.Generation, before.Info.Generation, err)
+	}
+
+	for _, pm := range []PluginInfo{a, b} {
+		if err := m.StopPlugin(pm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started = nil
+	if _, err := m.ImportBundle(bundle); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "a"}; !reflect.DeepEqual(started, want) {
+		t.Errorf("bundle plugins started in order %v, want %v", started, want)
+	}
+}
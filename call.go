@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (m *Manager[C]) Call(
+	ctx context.Context,
+	pluginKey string,
+	method string,
+	fn func(context.Context, C) error,
+) error {
+	if m.isClosed() {
+		return ErrManagerClosed
+	}
+
+	p, d, ok := m.route(pluginKey, RouteAttributesFrom(ctx))
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+
+	if err := m.shed(p); err != nil {
+		return err
+	}
+	if h := m.bulkheads.get(p.Info, m.config.MaxInFlight); h != nil {
+		if !h.acquire() {
+			return ErrBulkheadFull
+		}
+		if m.config.LoadShedding != nil {
+			m.notePressure(p)
+			defer m.notePressure(p)
+		}
+		defer h.release()
+	}
+	if err := m.limiter.wait(ctx, pluginKey); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p.inFlight.Add(1)
+	start := time.Now()
+	err := fn(ctx, p.Impl)
+	elapsed := time.Since(start)
+	p.inFlight.Add(-1)
+
+	m.stats.observe(pluginKey, method, elapsed, err)
+	if m.config.Metrics != nil {
+		m.config.Metrics.ObserveCall(pluginKey, method, p.Info.Labels, elapsed, err)
+	}
+	if d != nil {
+		m.observeDeployment(pluginKey, d, p, err)
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	EventCertRotationStarted  EventType = "cert_rotation_started"
+	EventCertRotated          EventType = "cert_rotated"
+	EventCertRotationFailed   EventType = "cert_rotation_failed"
+	EventCertRotationFinished EventType = "cert_rotation_finished"
+)
+
+// Rekeyer is implemented by plugin clients able to replace the keys of
+// their connection to the plugin without a restart. RotateCertificates
+// restarts plugins whose client does not implement it or fails to rekey.
+type Rekeyer interface {
+	Rekey(ctx context.Context) error
+}
+
+// Ways a plugin's certificates were rotated, the Data of EventCertRotated.
+const (
+	RotatedByRekey   = "rekey"
+	RotatedByRestart = "restart"
+)
+
+// RotateCertificates replaces the certificates of every plugin connected
+// over TLS. With AutoMTLS a restart generates new certificates; with
+// TLSConfig the new instance uses whatever certificates it provides then.
+func (m *Manager[C]) RotateCertificates(ctx context.Context) error {
+	if m.isClosed() {
+		return ErrManagerClosed
+	}
+	m.mu.Lock()
+	instances := make([]*pluginInstance[C], 0, len(m.plugins))
+	for _, p := range m.plugins {
+		if p.tls {
+			instances = append(instances, p)
+		}
+	}
+	m.mu.Unlock()
+
+	m.events.publish(Event{Type: EventCertRotationStarted, Message: fmt.Sprintf("rotating certificates of %v plugins", len(instances))})
+	var errs []error
+	for _, p := range instances {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		method, err := m.rotate(ctx, p)
+		if err != nil {
+			err = fmt.Errorf("rotating certificates of plugin %v: %w", p.Info.Key, err)
+			m.logFor(p.Info).Error("failed to rotate certificates", LogKeyReason, err)
+			m.events.publish(pluginEvent(EventCertRotationFailed, p.Info, err.Error()))
+			errs = append(errs, err)
+			continue
+		}
+		e := pluginEvent(EventCertRotated, p.Info, "certificates rotated by "+method)
+		e.Data = method
+		m.events.publish(e)
+	}
+	m.events.publish(Event{
+		Type:    EventCertRotationFinished,
+		Message: fmt.Sprintf("rotated certificates of %v of %v plugins", len(instances)-len(errs), len(instances)),
+	})
+	return errors.Join(errs...)
+}
+
+func (m *Manager[C]) rotate(ctx context.Context, p *pluginInstance[C]) (string, error) {
+	if r, ok := any(p.Impl).(Rekeyer); ok {
+		err := r.Rekey(ctx)
+		if err == nil {
+			return RotatedByRekey, nil
+		}
+		m.logFor(p.Info).Warn("plugin failed to rekey, restarting it", LogKeyReason, err)
+	}
+	return RotatedByRestart, m.RestartPlugin(p.Info)
+}
+
+// rotateCertificates calls RotateCertificates every interval until ctx is
+// done.
+func (m *Manager[C]) rotateCertificates(ctx context.Context, interval time.Duration) {
+	ticker := m.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			m.RotateCertificates(ctx)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the supervisor, restart delays, health checks
+// and the janitor. SimulatedClock lets tests drive them without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// sleep waits for d on clock, or until ctx is done.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// SimulatedClock only moves when Advance is called, firing the tickers,
+// timers and functions that are due in order. Like the time package, it
+// drops ticks nobody received and runs AfterFunc functions in their own
+// goroutine.
+type SimulatedClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	seq     int
+	pending []*simTimer
+}
+
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	c := &SimulatedClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+type simTimer struct {
+	clock  *SimulatedClock
+	when   time.Time
+	period time.Duration
+	seq    int
+	c      chan time.Time
+	f      func()
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *SimulatedClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for SimulatedClock.NewTicker")
+	}
+	return simTicker{c.schedule(d, d, nil)}
+}
+
+func (c *SimulatedClock) NewTimer(d time.Duration) Timer {
+	return c.schedule(d, 0, nil)
+}
+
+func (c *SimulatedClock) AfterFunc(d time.Duration, f func()) Timer {
+	return c.schedule(d, 0, f)
+}
+
+func (c *SimulatedClock) schedule(d, period time.Duration, f func()) *simTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &simTimer{clock: c, when: c.now.Add(d), period: period, seq: c.seq, f: f}
+	if f == nil {
+		t.c = make(chan time.Time, 1)
+	}
+	c.pending = append(c.pending, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		sort.Slice(c.pending, func(i, j int) bool {
+			a, b := c.pending[i], c.pending[j]
+			return a.when.Before(b.when) || a.when.Equal(b.when) && a.seq < b.seq
+		})
+		if len(c.pending) == 0 || c.pending[0].when.After(target) {
+			break
+		}
+		t := c.pending[0]
+		c.now = t.when
+		if t.period > 0 {
+			t.when = t.when.Add(t.period)
+		} else {
+			c.pending = c.pending[1:]
+		}
+		if t.f != nil {
+			go t.f()
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Waiters returns the number of tickers, timers and functions waiting for
+// the clock.
+func (c *SimulatedClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// BlockUntil waits until at least n tickers, timers and functions wait for
+// the clock, so that advancing it fires the ones the code under test is
+// about to schedule.
+func (c *SimulatedClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.pending) < n {
+		c.cond.Wait()
+	}
+}
+
+type simTicker struct{ *simTimer }
+
+func (t simTicker) Stop() { t.simTimer.Stop() }
+
+func (t *simTimer) C() <-chan time.Time { return t.c }
+
+func (t *simTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.pending {
+		if p == t {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,301 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NodeStatus is what a cluster member reports about itself.
+type NodeStatus struct {
+	ID        string         `json:"id"`
+	Heartbeat time.Time      `json:"heartbeat"`
+	Plugins   []PluginHealth `json:"plugins,omitempty"`
+}
+
+type PluginHealth struct {
+	Key        string `json:"key"`
+	Generation uint64 `json:"generation"`
+	Degraded   bool   `json:"degraded,omitempty"`
+}
+
+// ClusterStore holds the state shared by the members of a cluster. It is
+// meant to be backed by a consistent store such as etcd or Consul.
+type ClusterStore interface {
+	// DesiredPlugins returns the plugins that should run somewhere in the
+	// cluster.
+	DesiredPlugins(ctx context.Context) ([]PluginInfo, error)
+	ReportNode(ctx context.Context, status NodeStatus) error
+	Nodes(ctx context.Context) ([]NodeStatus, error)
+	// Placement maps plugin keys to the IDs of the nodes running them.
+	Placement(ctx context.Context) (map[string]string, error)
+	SetPlacement(ctx context.Context, placement map[string]string) error
+	// TryLead acquires or renews the cluster leadership for ttl and reports
+	// whether nodeID holds it.
+	TryLead(ctx context.Context, nodeID string, ttl time.Duration) (bool, error)
+}
+
+type ClusterOptions struct {
+	NodeID string
+	Store  ClusterStore
+	// Interval between heartbeats and reconciliations. Defaults to 10s.
+	Interval time.Duration
+	// NodeTTL is how long a node is considered alive after its last
+	// heartbeat. Defaults to three intervals.
+	NodeTTL time.Duration
+}
+
+// ClusterNode runs the plugins the cluster leader places on this manager.
+type ClusterNode[C any] struct {
+	m    *Manager[C]
+	opts ClusterOptions
+
+	mu     sync.Mutex
+	leader bool
+	err    error
+}
+
+func (m *Manager[C]) JoinCluster(opts ClusterOptions) (*ClusterNode[C], error) {
+	if opts.NodeID == "" || opts.Store == nil {
+		return nil, errors.New("cluster node needs an ID and a store")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.NodeTTL == 0 {
+		opts.NodeTTL = 3 * opts.Interval
+	}
+	return &ClusterNode[C]{m: m, opts: opts}, nil
+}
+
+// Run takes part in the cluster until ctx is done.
+func (n *ClusterNode[C]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.opts.Interval)
+	defer ticker.Stop()
+	for {
+		err := n.sync(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			n.m.config.Logger.Error("cluster sync failed", "node", n.opts.NodeID, LogKeyReason, err)
+		}
+		n.mu.Lock()
+		n.err = err
+		n.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsLeader reports whether the node held the leadership at its last sync.
+func (n *ClusterNode[C]) IsLeader() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.leader
+}
+
+// LastError returns the error of the last sync.
+func (n *ClusterNode[C]) LastError() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.err
+}
+
+func (n *ClusterNode[C]) sync(ctx context.Context) error {
+	store := n.opts.Store
+	if err := store.ReportNode(ctx, n.status()); err != nil {
+		return err
+	}
+
+	leader, err := store.TryLead(ctx, n.opts.NodeID, n.opts.NodeTTL)
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	n.leader = leader
+	n.mu.Unlock()
+
+	desired, err := store.DesiredPlugins(ctx)
+	if err != nil {
+		return err
+	}
+	if leader {
+		if err := n.place(ctx, desired); err != nil {
+			return err
+		}
+	}
+
+	placement, err := store.Placement(ctx)
+	if err != nil {
+		return err
+	}
+	var mine []PluginInfo
+	for _, pm := range desired {
+		if placement[pm.Key] == n.opts.NodeID {
+			mine = append(mine, pm)
+		}
+	}
+	result, err := n.m.Reconcile(mine)
+	if len(result.Started)+len(result.Stopped)+len(result.Restarted) > 0 {
+		n.m.config.Logger.Info(
+			"reconciled cluster placement",
+			"node", n.opts.NodeID,
+			"started", result.Started,
+			"stopped", result.Stopped,
+			"restarted", result.Restarted,
+		)
+	}
+	return err
+}
+
+func (n *ClusterNode[C]) status() NodeStatus {
+	status := NodeStatus{ID: n.opts.NodeID, Heartbeat: time.Now()}
+	n.m.mu.RLock()
+	defer n.m.mu.RUnlock()
+	for _, key := range sortedKeys(n.m.plugins) {
+		p := n.m.plugins[key]
+		status.Plugins = append(status.Plugins, PluginHealth{
+			Key:        key,
+			Generation: p.Info.Generation,
+			Degraded:   p.pings.snapshot().Degraded,
+		})
+	}
+	return status
+}
+
+// place assigns desired plugins to live nodes. Plugins stay where they are
+// while their node is alive; the others go to the least loaded node.
+func (n *ClusterNode[C]) place(ctx context.Context, desired []PluginInfo) error {
+	store := n.opts.Store
+	nodes, err := store.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := store.Placement(ctx)
+	if err != nil {
+		return err
+	}
+
+	load := make(map[string]int)
+	for _, node := range nodes {
+		if time.Since(node.Heartbeat) <= n.opts.NodeTTL {
+			load[node.ID] = 0
+		}
+	}
+	if len(load) == 0 {
+		return nil
+	}
+
+	placement := make(map[string]string, len(desired))
+	var unplaced []string
+	for _, pm := range desired {
+		if node, ok := current[pm.Key]; ok {
+			if _, alive := load[node]; alive {
+				placement[pm.Key] = node
+				load[node]++
+				continue
+			}
+		}
+		unplaced = append(unplaced, pm.Key)
+	}
+	sort.Strings(unplaced)
+	for _, key := range unplaced {
+		node := leastLoaded(load)
+		placement[key] = node
+		load[node]++
+	}
+	return store.SetPlacement(ctx, placement)
+}
+
+func leastLoaded(load map[string]int) string {
+	var best string
+	for _, node := range sortedKeys(load) {
+		if best == "" || load[node] < load[best] {
+			best = node
+		}
+	}
+	return best
+}
+
+// MemoryClusterStore is a ClusterStore for managers sharing a process, and
+// a reference for implementations backed by etcd or Consul.
+type MemoryClusterStore struct {
+	mu          sync.Mutex
+	desired     []PluginInfo
+	nodes       map[string]NodeStatus
+	placement   map[string]string
+	leader      string
+	leaseExpiry time.Time
+}
+
+func NewMemoryClusterStore() *MemoryClusterStore {
+	return &MemoryClusterStore{
+		nodes:     make(map[string]NodeStatus),
+		placement: make(map[string]string),
+	}
+}
+
+func (s *MemoryClusterStore) SetDesiredPlugins(desired []PluginInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.desired = append([]PluginInfo(nil), desired...)
+}
+
+func (s *MemoryClusterStore) DesiredPlugins(ctx context.Context) ([]PluginInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PluginInfo(nil), s.desired...), nil
+}
+
+func (s *MemoryClusterStore) ReportNode(ctx context.Context, status NodeStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes[status.ID] = status
+	return nil
+}
+
+func (s *MemoryClusterStore) Nodes(ctx context.Context) ([]NodeStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodes := make([]NodeStatus, 0, len(s.nodes))
+	for _, id := range sortedKeys(s.nodes) {
+		nodes = append(nodes, s.nodes[id])
+	}
+	return nodes, nil
+}
+
+func (s *MemoryClusterStore) Placement(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	placement := make(map[string]string, len(s.placement))
+	for k, v := range s.placement {
+		placement[k] = v
+	}
+	return placement, nil
+}
+
+func (s *MemoryClusterStore) SetPlacement(ctx context.Context, placement map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.placement = make(map[string]string, len(placement))
+	for k, v := range placement {
+		s.placement[k] = v
+	}
+	return nil
+}
+
+func (s *MemoryClusterStore) TryLead(ctx context.Context, nodeID string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.leader == nodeID || now.After(s.leaseExpiry) {
+		s.leader = nodeID
+		s.leaseExpiry = now.Add(ttl)
+		return true, nil
+	}
+	return false, nil
+}
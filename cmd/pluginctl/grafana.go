@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+)
+
+// grafana writes a Grafana dashboard and Prometheus alert rules for the
+// metrics the managers serve.
+func grafana(args []string) error {
+	fs := flag.NewFlagSet("grafana", flag.ExitOnError)
+	dir := fs.String("o", ".", "output directory")
+	namespaces := fs.String("namespace", "", "comma separated manager names to restrict to")
+	datasource := fs.String("datasource", "", "uid of the Prometheus data source, picked in the dashboard by default")
+	title := fs.String("title", "", "dashboard title")
+	errorRatio := fs.Float64("max-error-ratio", 0, "ratio of failed calls to alert on, default 0.05")
+	latency := fs.Duration("max-p99", 0, "p99 call latency to alert on, default 1s")
+	restarts := fs.Int("max-restarts", 0, "restarts in 15 minutes to alert on, default 3")
+	fs.Parse(args)
+
+	config := manager.ObservabilityConfig{
+		Title:         *title,
+		Datasource:    *datasource,
+		MaxErrorRatio: *errorRatio,
+		MaxP99Latency: *latency,
+		MaxRestarts:   *restarts,
+	}
+	if *namespaces != "" {
+		config.Namespaces = strings.Split(*namespaces, ",")
+	}
+	dashboard, err := manager.GrafanaDashboard(config)
+	if err != nil {
+		return err
+	}
+	rules, err := manager.PrometheusRules(config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	for name, data := range map[string][]byte{
+		"plugin-manager-dashboard.json": dashboard,
+		"plugin-manager.rules.json":     rules,
+	} {
+		path := filepath.Join(*dir, name)
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		fmt.Println("wrote", path)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Command pluginctl inspects plugin managers through their control or
+// observer service.
+//
+//	pluginctl [flags] top [-interval 2s] [-plugin key]
+//	pluginctl grafana [-o dir] [-namespace a,b] [-datasource uid]
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	addr := flag.String("addr", "unix:///run/plugin-manager/control.sock", "address of the control or observer service")
+	token := flag.String("token", os.Getenv("PLUGINCTL_TOKEN"), "bearer token, defaults to $PLUGINCTL_TOKEN")
+	ca := flag.String("ca", "", "CA certificate file, enables TLS")
+	cert := flag.String("cert", "", "client certificate file for mTLS")
+	key := flag.String("key", "", "client key file for mTLS")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage:\n  pluginctl [flags] top [-interval 2s] [-plugin key]\n  pluginctl grafana [-o dir] [-namespace a,b] [-datasource uid]\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch flag.Arg(0) {
+	case "top":
+		var conn *grpc.ClientConn
+		conn, err = dial(*addr, *token, *ca, *cert, *key)
+		if err == nil {
+			err = top(ctx, manager.NewObserver(conn), flag.Args()[1:])
+			conn.Close()
+		}
+	case "grafana":
+		err = grafana(flag.Args()[1:])
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "pluginctl:", err)
+		os.Exit(1)
+	}
+}
+
+func dial(addr, token, ca, cert, key string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		config := &tls.Config{RootCAs: x509.NewCertPool()}
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %v", ca)
+		}
+		if cert != "" {
+			pair, err := tls.LoadX509KeyPair(cert, key)
+			if err != nil {
+				return nil, err
+			}
+			config.Certificates = []tls.Certificate{pair}
+		}
+		creds = credentials.NewTLS(config)
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
+	return grpc.Dial(addr, opts...)
+}
+
+// bearerToken authenticates with ManagerConfig.AdminAuth tokens. It is also
+// sent without TLS, for services on unix sockets.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (bearerToken) RequireTransportSecurity() bool {
+	return false
+}
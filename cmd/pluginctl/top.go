@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"golang.org/x/term"
+)
+
+// paneLines is how many event and log lines top keeps.
+const paneLines = 500
+
+type row struct {
+	key      string
+	state    string
+	restarts int
+	gen      uint64
+	cpu      float64
+	rss      uint64
+	ping     time.Duration
+}
+
+type sample struct {
+	at  time.Time
+	cpu time.Duration
+}
+
+type topView struct {
+	mu      sync.Mutex
+	rows    []row
+	err     error
+	pane    []string
+	samples map[string]sample
+}
+
+// top shows the plugins of a manager, refreshed every interval, above the
+// events and log lines it streams. q or ctrl-c quits.
+func top(ctx context.Context, o *manager.Observer, args []string) error {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	interval := fs.Duration("interval", 2*time.Second, "refresh interval")
+	plugin := fs.String("plugin", "", "only show the logs of this plugin")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	v := &topView{samples: make(map[string]sample)}
+	go func() {
+		err := o.WatchEvents(ctx, time.Time{}, func(e manager.Event) error {
+			line := fmt.Sprintf("%v %-14v %v", e.Time.Format("15:04:05"), e.Type, e.Key)
+			if e.Message != "" {
+				line += ": " + e.Message
+			}
+			v.append(line)
+			return nil
+		})
+		if err != nil {
+			v.append("events: " + err.Error())
+		}
+	}()
+	go func() {
+		err := o.WatchLogs(ctx, *plugin, func(l manager.LogLine) error {
+			v.append(fmt.Sprintf("%v %v#%v %v", l.Time.Format("15:04:05"), l.Key, l.Generation, l.Line))
+			return nil
+		})
+		if err != nil {
+			v.append("logs: " + err.Error())
+		}
+	}()
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+		go func() {
+			b := make([]byte, 1)
+			for {
+				if _, err := os.Stdin.Read(b); err != nil || b[0] == 'q' || b[0] == 3 {
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	fmt.Print("\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[H\x1b[2J")
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	redraw := time.NewTicker(250 * time.Millisecond)
+	defer redraw.Stop()
+	v.poll(ctx, o)
+	v.render()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			v.poll(ctx, o)
+		case <-redraw.C:
+			v.render()
+		}
+	}
+}
+
+func (v *topView) append(line string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pane = append(v.pane, line)
+	if len(v.pane) > paneLines {
+		v.pane = v.pane[len(v.pane)-paneLines:]
+	}
+}
+
+func (v *topView) poll(ctx context.Context, o *manager.Observer) {
+	infos, err := o.ListPlugins(ctx, nil)
+	if err != nil {
+		v.mu.Lock()
+		v.err = err
+		v.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	rows := make([]row, 0, len(infos))
+	samples := make(map[string]sample, len(infos))
+	for _, info := range infos {
+		r := row{key: info.Key, state: "running", restarts: info.Restarts, gen: info.Generation}
+		d, err := o.DescribePlugin(ctx, info.Key)
+		if err != nil {
+			r.state = "unknown"
+			rows = append(rows, r)
+			continue
+		}
+		switch {
+		case d.Quarantine != nil:
+			r.state = "quarantined"
+		case d.Pings.Degraded:
+			r.state = "degraded"
+		}
+		r.ping = d.Pings.Last
+		if d.Resources != nil {
+			r.rss = d.Resources.RSS
+			s := sample{at: now, cpu: d.Resources.CPUTime}
+			v.mu.Lock()
+			prev, ok := v.samples[info.Key]
+			v.mu.Unlock()
+			if ok && s.cpu >= prev.cpu {
+				r.cpu = 100 * float64(s.cpu-prev.cpu) / float64(now.Sub(prev.at))
+			}
+			samples[info.Key] = s
+		}
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].key < rows[j].key })
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rows, v.samples, v.err = rows, samples, nil
+}
+
+func (v *topView) render() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 120, 40
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	lines := []string{fmt.Sprintf("pluginctl top - %v plugins - %v", len(v.rows), time.Now().Format("15:04:05"))}
+	if v.err != nil {
+		lines = append(lines, "error: "+v.err.Error())
+	}
+	lines = append(lines, "", fmt.Sprintf("%-24v %-12v %8v %5v %6v %9v %10v", "KEY", "STATE", "RESTARTS", "GEN", "CPU%", "RSS", "PING"))
+	for _, r := range v.rows {
+		cpu, rss := "-", "-"
+		if r.rss > 0 {
+			cpu, rss = fmt.Sprintf("%.1f", r.cpu), formatBytes(r.rss)
+		}
+		lines = append(lines, fmt.Sprintf("%-24v %-12v %8v %5v %6v %9v %10v",
+			r.key, r.state, r.restarts, r.gen, cpu, rss, r.ping.Round(time.Microsecond)))
+	}
+	lines = append(lines, "", strings.Repeat("-", width))
+	if n := height - len(lines) - 1; n > 0 {
+		pane := v.pane
+		if len(pane) > n {
+			pane = pane[len(pane)-n:]
+		}
+		lines = append(lines, pane...)
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		if len(line) > width {
+			line = line[:width]
+		}
+		// \r because the terminal is in raw mode.
+		b.WriteString(line + "\r\n")
+	}
+	os.Stdout.WriteString(b.String())
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,399 @@
+// Command plugingen generates implementations of a plugin interface:
+//
+//	//go:generate go run github.com/joshwizzy/go-plugin-manager/cmd/plugingen -type Greeter -kind proxy
+//
+// With -kind proxy it generates a proxy implementing the interface by calling
+// the live instance of a plugin through a manager.Handle, which callers can
+// hold on to across restarts. With -kind wasm it generates bindings
+// implementing the interface by calling the functions of a WebAssembly
+// module with wasm.Module.Invoke, for runners serving modules with
+// wasm.Serve.
+//
+// The interface is read from the package in -src, the current directory by
+// default, and the code is generated into the package in the current
+// directory, qualifying the types of the source package when they differ.
+// Interfaces embedding other interfaces are not supported.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the plugin interface")
+	kind := flag.String("kind", "", "what to generate: proxy or wasm")
+	src := flag.String("src", ".", "directory of the package declaring the interface")
+	out := flag.String("o", "", "output file, defaults to <type>_<kind>_gen.go")
+	flag.Parse()
+	if *typeName == "" || *kind == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		// Without the suffix, a name ending in _wasm would be a build
+		// constraint.
+		*out = strings.ToLower(*typeName) + "_" + *kind + "_gen.go"
+	}
+	if err := run(*typeName, *kind, *src, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "plugingen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(typeName, kind, src, out string) error {
+	gen, ok := generators[kind]
+	if !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	srcPkg, err := parsePackage(src)
+	if err != nil {
+		return err
+	}
+	dstPkg, err := parsePackage(filepath.Dir(out))
+	if err != nil {
+		return err
+	}
+	var qualifier string
+	if !samePath(src, filepath.Dir(out)) {
+		if dstPkg.name == srcPkg.name {
+			return fmt.Errorf("output package %v has the name of the source package", dstPkg.name)
+		}
+		qualifier = srcPkg.name
+	}
+	iface, err := readInterface(srcPkg, typeName, qualifier)
+	if err != nil {
+		return err
+	}
+	if qualifier != "" {
+		importPath, err := goList(src)
+		if err != nil {
+			return err
+		}
+		iface.imports[importPath] = qualifier
+		iface.typ = qualifier + "." + iface.typ
+	}
+
+	var buf bytes.Buffer
+	gen(&buf, iface)
+	code, err := format.Source(header(dstPkg.name, iface.imports, buf.Bytes()))
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	return os.WriteFile(out, code, 0o644)
+}
+
+var generators = map[string]func(*bytes.Buffer, *plugin){
+	"proxy": genProxy,
+	"wasm":  genWasm,
+}
+
+type plugin struct {
+	name    string // unqualified name of the interface
+	typ     string // name of the interface in the generated code
+	methods []method
+	imports map[string]string // import path to package name
+}
+
+type method struct {
+	name     string
+	ctx      bool // the first parameter is a context.Context
+	params   []string
+	variadic bool
+	results  []string // excluding the trailing error
+	err      bool
+}
+
+// args returns the parameters of m as a0, a1..., excluding the context.
+func (m method) args() []string {
+	args := make([]string, len(m.params))
+	for i := range m.params {
+		args[i] = fmt.Sprintf("a%v", i)
+	}
+	return args
+}
+
+// signature returns the parameters and results of m with named results
+// r0, r1... and err.
+func (m method) signature() string {
+	var params, results []string
+	if m.ctx {
+		params = append(params, "ctx context.Context")
+	}
+	for i, t := range m.params {
+		if m.variadic && i == len(m.params)-1 {
+			t = "..." + strings.TrimPrefix(t, "[]")
+		}
+		params = append(params, fmt.Sprintf("a%v %v", i, t))
+	}
+	for i, t := range m.results {
+		results = append(results, fmt.Sprintf("r%v %v", i, t))
+	}
+	if m.err {
+		results = append(results, "err error")
+	}
+	return fmt.Sprintf("(%v) (%v)", strings.Join(params, ", "), strings.Join(results, ", "))
+}
+
+// call returns the call of m on impl.
+func (m method) call(impl, ctx string) string {
+	args := m.args()
+	if m.variadic {
+		args[len(args)-1] += "..."
+	}
+	if m.ctx {
+		args = append([]string{ctx}, args...)
+	}
+	return fmt.Sprintf("%v.%v(%v)", impl, m.name, strings.Join(args, ", "))
+}
+
+// ret returns the final return statement of methods without an error
+// result.
+func (m method) ret() string {
+	if len(m.results) == 0 {
+		return ""
+	}
+	return "return\n"
+}
+
+// ctxExpr returns the context the generated method makes its call with.
+func (m method) ctxExpr() string {
+	if m.ctx {
+		return "ctx"
+	}
+	return "context.Background()"
+}
+
+type pkg struct {
+	name  string
+	files []*ast.File
+}
+
+func parsePackage(dir string) (*pkg, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	var p *pkg
+	for _, file := range matches {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if ast.IsGenerated(f) {
+			continue
+		}
+		if p == nil {
+			p = &pkg{name: f.Name.Name}
+		}
+		if f.Name.Name == p.name {
+			p.files = append(p.files, f)
+		}
+	}
+	if p == nil {
+		return nil, fmt.Errorf("no Go package in %v", dir)
+	}
+	return p, nil
+}
+
+func readInterface(p *pkg, typeName, qualifier string) (*plugin, error) {
+	for _, f := range p.files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != typeName {
+					continue
+				}
+				it, ok := ts.Type.(*ast.InterfaceType)
+				if !ok {
+					return nil, fmt.Errorf("%v is not an interface", typeName)
+				}
+				if ts.TypeParams != nil {
+					return nil, fmt.Errorf("%v is generic", typeName)
+				}
+				return interfaceMethods(f, typeName, it, qualifier)
+			}
+		}
+	}
+	return nil, fmt.Errorf("interface %v not found in package %v", typeName, p.name)
+}
+
+func interfaceMethods(f *ast.File, typeName string, it *ast.InterfaceType, qualifier string) (*plugin, error) {
+	p := &plugin{name: typeName, typ: typeName, imports: map[string]string{"context": "context"}}
+	imports := fileImports(f)
+	// Parameters sharing a type share its expression, which must only be
+	// qualified once.
+	qualified := make(map[*ast.Ident]bool)
+	typeString := func(expr ast.Expr) (string, error) {
+		var err error
+		ast.Inspect(expr, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				x, ok := n.X.(*ast.Ident)
+				if !ok {
+					return false
+				}
+				path, ok := imports[x.Name]
+				if !ok {
+					err = fmt.Errorf("unknown package %v", x.Name)
+					return false
+				}
+				p.imports[path] = x.Name
+				return false
+			case *ast.Ident:
+				if qualifier != "" && ast.IsExported(n.Name) && !qualified[n] {
+					qualified[n] = true
+					n.Name = qualifier + "." + n.Name
+				}
+			}
+			return true
+		})
+		return types.ExprString(expr), err
+	}
+
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("%v embeds %v, which is not supported", typeName, types.ExprString(field.Type))
+		}
+		m := method{name: field.Names[0].Name}
+		for i, param := range fieldList(ft.Params) {
+			t, err := typeString(param)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", typeName, m.name, err)
+			}
+			if i == 0 && t == "context.Context" {
+				m.ctx = true
+				continue
+			}
+			if ell, ok := param.(*ast.Ellipsis); ok {
+				m.variadic = true
+				t = "[]" + types.ExprString(ell.Elt)
+			}
+			m.params = append(m.params, t)
+		}
+		results := fieldList(ft.Results)
+		for i, result := range results {
+			t, err := typeString(result)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", typeName, m.name, err)
+			}
+			if i == len(results)-1 && t == "error" {
+				m.err = true
+				continue
+			}
+			m.results = append(m.results, t)
+		}
+		p.methods = append(p.methods, m)
+	}
+	return p, nil
+}
+
+// fieldList returns the type of each parameter or result in fl.
+func fieldList(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	var exprs []ast.Expr
+	for _, field := range fl.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			exprs = append(exprs, field.Type)
+		}
+	}
+	return exprs
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// fileImports maps the names f refers to its imports by to their paths.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		p := strings.Trim(spec.Path.Value, `"`)
+		name := path.Base(p)
+		if majorVersion.MatchString(name) {
+			name = path.Base(path.Dir(p))
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "go-"), "go.")
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = p
+	}
+	return imports
+}
+
+func header(pkgName string, imports map[string]string, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by plugingen. DO NOT EDIT.\n\npackage %v\n\nimport (\n", pkgName)
+	paths := make([]string, 0, len(imports))
+	for p := range imports {
+		paths = append(paths, p)
+	}
+	// Standard library imports go first, in their own group.
+	sort.Slice(paths, func(i, j int) bool {
+		if si, sj := isStd(paths[i]), isStd(paths[j]); si != sj {
+			return si
+		}
+		return paths[i] < paths[j]
+	})
+	for i, p := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(p) {
+			buf.WriteString("\n")
+		}
+		if name := imports[p]; name != path.Base(p) {
+			fmt.Fprintf(&buf, "\t%v %q\n", name, p)
+		} else {
+			fmt.Fprintf(&buf, "\t%q\n", p)
+		}
+	}
+	buf.WriteString(")\n\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func isStd(importPath string) bool {
+	return !strings.Contains(strings.Split(importPath, "/")[0], ".")
+}
+
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+func goList(dir string) (string, error) {
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}", ".")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("resolving the import path of %v: %w", dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// genProxy generates a type implementing the interface by calling the live
+// instance of a plugin through a manager.Handle.
+func genProxy(buf *bytes.Buffer, p *plugin) {
+	p.imports["github.com/joshwizzy/go-plugin-manager"] = "manager"
+	name := p.name + "Proxy"
+	fmt.Fprintf(buf, `// %[1]v implements %[2]v by calling the live instance of a plugin
+// through Handle, so that it keeps working after the plugin is restarted.
+// Failed calls of methods without an error result are reported to OnError.
+type %[1]v struct {
+	Handle  *manager.Handle[%[2]v]
+	OnError func(method string, err error)
+}
+
+var _ %[2]v = %[1]v{}
+`, name, p.typ)
+
+	for _, m := range p.methods {
+		results := make([]string, len(m.results))
+		for i := range m.results {
+			results[i] = fmt.Sprintf("r%v", i)
+		}
+		fmt.Fprintf(buf, "\nfunc (p %v) %v%v {\n", name, m.name, m.signature())
+		call := fmt.Sprintf("p.Handle.Call(%v, %q, func(ctx context.Context, impl %v) error {\n", m.ctxExpr(), m.name, p.typ)
+		switch {
+		case m.err && len(results) == 0:
+			call += fmt.Sprintf("return %v\n", m.call("impl", "ctx"))
+		case m.err:
+			call += fmt.Sprintf("var err error\n%v = %v\nreturn err\n", strings.Join(append(results, "err"), ", "), m.call("impl", "ctx"))
+		case len(results) > 0:
+			call += fmt.Sprintf("%v = %v\nreturn nil\n", strings.Join(results, ", "), m.call("impl", "ctx"))
+		default:
+			call += fmt.Sprintf("%v\nreturn nil\n", m.call("impl", "ctx"))
+		}
+		call += "})"
+		if m.err {
+			fmt.Fprintf(buf, "err = %v\nreturn\n}\n", call)
+			continue
+		}
+		fmt.Fprintf(buf, "if err := %v; err != nil && p.OnError != nil {\np.OnError(%q, err)\n}\n%v}\n", call, m.name, m.ret())
+	}
+}
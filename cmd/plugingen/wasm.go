@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// genWasm generates a type implementing the interface by invoking the
+// module function named after each method.
+func genWasm(buf *bytes.Buffer, p *plugin) {
+	p.imports["github.com/joshwizzy/go-plugin-manager/wasm"] = "wasm"
+	name := p.name + "Wasm"
+	fmt.Fprintf(buf, `// %[1]v implements %[2]v by invoking the functions of a WebAssembly
+// module named after its methods. Failed calls of methods without an error
+// result are reported to OnError.
+type %[1]v struct {
+	Module  *wasm.Module
+	OnError func(method string, err error)
+}
+
+var _ %[2]v = %[1]v{}
+`, name, p.typ)
+
+	for _, m := range p.methods {
+		results := make([]string, len(m.results))
+		for i := range m.results {
+			results[i] = fmt.Sprintf("&r%v", i)
+		}
+		fmt.Fprintf(buf, "\nfunc (b %v) %v%v {\n", name, m.name, m.signature())
+		invoke := fmt.Sprintf("b.Module.Invoke(%v, %q, []any{%v}, []any{%v})", m.ctxExpr(), m.name, strings.Join(m.args(), ", "), strings.Join(results, ", "))
+		if m.err {
+			fmt.Fprintf(buf, "\terr = %v\n\treturn\n}\n", invoke)
+			continue
+		}
+		fmt.Fprintf(buf, "\tif err := %v; err != nil && b.OnError != nil {\n\t\tb.OnError(%q, err)\n\t}\n%v}\n", invoke, m.name, m.ret())
+	}
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Compatibility string
+
+const (
+	// CompatibilityUnknown means the host or the plugin declares no version
+	// constraints.
+	CompatibilityUnknown    Compatibility = "unknown"
+	CompatibilityCompatible Compatibility = "compatible"
+)
+
+var ErrIncompatibleHost = errors.New("plugin is incompatible with host version")
+
+type IncompatibleHostError struct {
+	Key            string
+	HostVersion    string
+	MinHostVersion string
+	MaxHostVersion string
+}
+
+func (e *IncompatibleHostError) Error() string {
+	return fmt.Sprintf(
+		"plugin %v requires host version in [%v, %v], host is %v",
+		e.Key,
+		orAny(e.MinHostVersion),
+		orAny(e.MaxHostVersion),
+		e.HostVersion,
+	)
+}
+
+func (e *IncompatibleHostError) Unwrap() error {
+	return ErrIncompatibleHost
+}
+
+func orAny(v string) string {
+	if v == "" {
+		return "*"
+	}
+	return v
+}
+
+// checkHostVersion reports whether the plugin accepts the host version.
+func checkHostVersion(hostVersion string, pm PluginInfo) (Compatibility, error) {
+	if hostVersion == "" || (pm.MinHostVersion == "" && pm.MaxHostVersion == "") {
+		return CompatibilityUnknown, nil
+	}
+	host, err := parseVersion(hostVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid host version: %w", err)
+	}
+
+	incompatible := &IncompatibleHostError{
+		Key:            pm.Key,
+		HostVersion:    hostVersion,
+		MinHostVersion: pm.MinHostVersion,
+		MaxHostVersion: pm.MaxHostVersion,
+	}
+	if pm.MinHostVersion != "" {
+		min, err := parseVersion(pm.MinHostVersion)
+		if err != nil {
+			return "", fmt.Errorf("plugin %v has invalid min host version: %w", pm.Key, err)
+		}
+		if compareVersions(host, min) < 0 {
+			return "", incompatible
+		}
+	}
+	if pm.MaxHostVersion != "" {
+		max, err := parseVersion(pm.MaxHostVersion)
+		if err != nil {
+			return "", fmt.Errorf("plugin %v has invalid max host version: %w", pm.Key, err)
+		}
+		if compareVersions(host, max) > 0 {
+			return "", incompatible
+		}
+	}
+	return CompatibilityCompatible, nil
+}
+
+// parseVersion parses a MAJOR[.MINOR[.PATCH]] version with an optional "v"
+// prefix. Pre-release and build suffixes are ignored.
+func parseVersion(v string) ([3]int, error) {
+	var out [3]int
+	s := strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 || s == "" {
+		return out, fmt.Errorf("invalid version %q", v)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return out, fmt.Errorf("invalid version %q", v)
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,54 @@
+package manager
+
+import "context"
+
+// Plugins is the part of Manager most components depend on, for injecting
+// the manager behind an interface.
+type Plugins[C any] interface {
+	GetPlugin(pluginKey string) (C, error)
+	ListPlugins(filters ...Filter) ([]PluginInfo, error)
+	Call(ctx context.Context, pluginKey string, method string, fn func(context.Context, C) error) error
+}
+
+// Hooks start and stop the manager with the application, in the shape of
+// fx.Hook.
+type Hooks struct {
+	OnStart func(context.Context) error
+	OnStop  func(context.Context) error
+}
+
+// Hooks returns the hooks loading plugins and starting the manager, and
+// shutting it down. OnStop returns ctx's error if the shutdown outlasts it.
+func (m *Manager[C]) Hooks(plugins []PluginInfo) Hooks {
+	return Hooks{
+		OnStart: func(context.Context) error {
+			if err := m.LoadPlugins(plugins); err != nil {
+				return err
+			}
+			return m.Start()
+		},
+		OnStop: func(ctx context.Context) error {
+			done := make(chan error, 1)
+			go func() {
+				done <- m.Shutdown()
+			}()
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Provide creates a manager, loads plugins and starts it, returning a
+// cleanup function shutting it down, as google/wire providers do.
+func Provide[C any](name string, config *ManagerConfig, plugins []PluginInfo) (*Manager[C], func(), error) {
+	m := NewManager[C](name, config)
+	if err := m.Hooks(plugins).OnStart(context.Background()); err != nil {
+		m.Shutdown()
+		return nil, nil, err
+	}
+	return m, func() { m.Shutdown() }, nil
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrNotConformant = errors.New("plugin does not implement interface")
+
+// ConformanceError describes how a dispensed plugin fails to implement the
+// manager's interface.
+type ConformanceError struct {
+	Key string
+	// Got is the type dispensed by the plugin client and Want the interface
+	// the manager expects.
+	Got  string
+	Want string
+	// Missing lists the methods Got lacks or has with another signature,
+	// or that the plugin reported it does not serve.
+	Missing []string
+}
+
+func (e *ConformanceError) Error() string {
+	msg := fmt.Sprintf("plugin %v: %v does not implement %v", e.Key, e.Got, e.Want)
+	if len(e.Missing) > 0 {
+		msg += ": missing " + strings.Join(e.Missing, ", ")
+	}
+	return msg
+}
+
+func (e *ConformanceError) Unwrap() error {
+	return ErrNotConformant
+}
+
+// Describer is implemented by plugin clients that can ask the plugin which
+// methods of the interface it serves. ManagerConfig.StrictConformance
+// requires it.
+type Describer interface {
+	DescribeMethods() ([]string, error)
+}
+
+// conform asserts raw to C, explaining what is missing when it does not.
+func conform[C any](key string, raw any) (C, error) {
+	if impl, ok := raw.(C); ok {
+		return impl, nil
+	}
+	var zero C
+	want := reflect.TypeOf((*C)(nil)).Elem()
+	e := &ConformanceError{Key: key, Got: fmt.Sprintf("%T", raw), Want: want.String()}
+	if want.Kind() != reflect.Interface || raw == nil {
+		return zero, e
+	}
+	got := reflect.ValueOf(raw)
+	for i := 0; i < want.NumMethod(); i++ {
+		method := want.Method(i)
+		m := got.MethodByName(method.Name)
+		if !m.IsValid() {
+			e.Missing = append(e.Missing, method.Name)
+		} else if m.Type() != method.Type {
+			e.Missing = append(e.Missing, fmt.Sprintf("%v (has %v, want %v)", method.Name, m.Type(), method.Type))
+		}
+	}
+	return zero, e
+}
+
+// checkDescribed asks the plugin which methods it serves and checks they
+// cover the interface.
+func checkDescribed[C any](key string, impl C) error {
+	want := reflect.TypeOf((*C)(nil)).Elem()
+	d, ok := any(impl).(Describer)
+	if !ok {
+		return fmt.Errorf("%w: plugin %v client %T cannot describe the plugin's methods", ErrNotConformant, key, impl)
+	}
+	served, err := d.DescribeMethods()
+	if err != nil {
+		return fmt.Errorf("describing plugin %v: %w", key, err)
+	}
+	e := &ConformanceError{Key: key, Got: "plugin", Want: want.String()}
+	if want.Kind() == reflect.Interface {
+		for i := 0; i < want.NumMethod(); i++ {
+			if name := want.Method(i).Name; name != "DescribeMethods" && !contains(served, name) {
+				e.Missing = append(e.Missing, name)
+			}
+		}
+	}
+	if len(e.Missing) > 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"fmt"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+type ConnectionInfo struct {
+	Protocol        goplugin.Protocol
+	ProtocolVersion int
+	Network         string
+	Address         string
+	TLS             bool
+	Pid             int
+}
+
+func (m *Manager[C]) ConnectionInfo(pluginKey string) (ConnectionInfo, error) {
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return ConnectionInfo{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	return p.ConnectionInfo(), nil
+}
+
+func (p *pluginInstance[T]) ConnectionInfo() ConnectionInfo {
+	info := ConnectionInfo{
+		ProtocolVersion: p.client.NegotiatedVersion(),
+		TLS:             p.tls,
+	}
+	rc := p.client.ReattachConfig()
+	if rc == nil {
+		return info
+	}
+	info.Protocol = rc.Protocol
+	info.Pid = rc.Pid
+	if rc.Addr != nil {
+		info.Network = rc.Addr.Network()
+		info.Address = rc.Addr.String()
+	}
+	return info
+}
@@ -0,0 +1,66 @@
+// Package consullock implements manager.Locker on top of Consul.
+package consullock
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+	manager "github.com/joshwizzy/go-plugin-manager"
+)
+
+// Locker takes locks under Prefix in Consul's KV store. A manager that dies
+// loses its locks once its session expires.
+type Locker struct {
+	Client *api.Client
+	Prefix string
+	// SessionTTL defaults to Consul's default, 15s.
+	SessionTTL string
+}
+
+func (l *Locker) Lock(ctx context.Context, name string) (manager.Lease, error) {
+	lock, err := l.Client.LockOpts(&api.LockOptions{
+		Key:        path.Join(l.Prefix, name),
+		SessionTTL: l.SessionTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stop, acquired := make(chan struct{}), make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stop)
+		case <-acquired:
+		}
+	}()
+	lost, err := lock.Lock(stop)
+	close(acquired)
+	if err != nil {
+		return nil, err
+	}
+	if lost == nil {
+		return nil, ctx.Err()
+	}
+	return &lease{lock: lock, lost: lost}, nil
+}
+
+type lease struct {
+	lock *api.Lock
+	lost <-chan struct{}
+	once sync.Once
+}
+
+func (l *lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *lease) Unlock() error {
+	var err error
+	l.once.Do(func() {
+		err = l.lock.Unlock()
+	})
+	return err
+}
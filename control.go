@@ -0,0 +1,259 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/joshwizzy/go-plugin-manager/controlpb"
+)
+
+// RegisterControlService registers the gRPC control service, which mirrors
+// AdminHandler, on s.
+func (m *Manager[C]) RegisterControlService(s *grpc.Server) {
+	controlpb.RegisterControlServer(s, &controlServer[C]{m: m})
+}
+
+// RegisterObserverService registers a read-only control service on s: it
+// lists, describes and streams events and logs, and fails every other call
+// with PermissionDenied. Serve it apart from the control service, for
+// instance on its own socket, to dashboards and support tooling, which
+// read it with an Observer.
+func (m *Manager[C]) RegisterObserverService(s *grpc.Server) {
+	controlpb.RegisterControlServer(s, &controlServer[C]{m: m, readOnly: true})
+}
+
+var errReadOnly = status.Error(codes.PermissionDenied, "the observer service is read-only")
+
+type controlServer[C any] struct {
+	controlpb.UnimplementedControlServer
+	m        *Manager[C]
+	readOnly bool
+}
+
+func (s *controlServer[C]) ListPlugins(ctx context.Context, req *controlpb.ListPluginsRequest) (*controlpb.ListPluginsResponse, error) {
+	if err := s.authorize(ctx, "list", ""); err != nil {
+		return nil, err
+	}
+	infos, err := s.m.ListPlugins(MatchLabels(req.Selector))
+	if err != nil {
+		return nil, controlError(err)
+	}
+	resp := &controlpb.ListPluginsResponse{}
+	for _, info := range infos {
+		p, err := pluginProto(info)
+		if err != nil {
+			return nil, controlError(err)
+		}
+		resp.Plugins = append(resp.Plugins, p)
+	}
+	return resp, nil
+}
+
+func (s *controlServer[C]) DescribePlugin(ctx context.Context, req *controlpb.DescribePluginRequest) (*controlpb.DescribePluginResponse, error) {
+	if err := s.authorize(ctx, "describe", req.Key); err != nil {
+		return nil, err
+	}
+	d, err := s.m.DescribePlugin(req.Key)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	d.Info = d.Info.Redacted()
+	p, err := pluginProto(d.Info)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	description, err := json.Marshal(d)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	return &controlpb.DescribePluginResponse{Plugin: p, DescriptionJson: description}, nil
+}
+
+func (s *controlServer[C]) StartPlugin(ctx context.Context, req *controlpb.StartPluginRequest) (*controlpb.StartPluginResponse, error) {
+	if s.readOnly {
+		return nil, errReadOnly
+	}
+	// Like the admin API, callers are authorized before their request is
+	// parsed, so before its key is known.
+	if err := s.authorize(ctx, "start", ""); err != nil {
+		return nil, err
+	}
+	pm, err := ParsePluginInfo(req.InfoJson)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, ok := s.m.getPlugin(pm.Key); ok {
+		return nil, status.Errorf(codes.AlreadyExists, "plugin %v already exists", pm.Key)
+	}
+	started, err := s.m.StartPlugin(pm)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	p, err := pluginProto(started.Info)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	return &controlpb.StartPluginResponse{Plugin: p}, nil
+}
+
+func (s *controlServer[C]) StopPlugin(ctx context.Context, req *controlpb.StopPluginRequest) (*controlpb.StopPluginResponse, error) {
+	if s.readOnly {
+		return nil, errReadOnly
+	}
+	if err := s.authorize(ctx, "stop", req.Key); err != nil {
+		return nil, err
+	}
+	if err := s.m.StopPlugin(PluginInfo{Key: req.Key}); err != nil {
+		return nil, controlError(err)
+	}
+	return &controlpb.StopPluginResponse{}, nil
+}
+
+func (s *controlServer[C]) RestartPlugin(ctx context.Context, req *controlpb.RestartPluginRequest) (*controlpb.RestartPluginResponse, error) {
+	if s.readOnly {
+		return nil, errReadOnly
+	}
+	if err := s.authorize(ctx, "restart", req.Key); err != nil {
+		return nil, err
+	}
+	info, err := s.m.restartByKey(req.Key, req.InfoJson)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	p, err := pluginProto(info)
+	if err != nil {
+		return nil, controlError(err)
+	}
+	return &controlpb.RestartPluginResponse{Plugin: p}, nil
+}
+
+func (s *controlServer[C]) Unquarantine(ctx context.Context, req *controlpb.UnquarantineRequest) (*controlpb.UnquarantineResponse, error) {
+	if s.readOnly {
+		return nil, errReadOnly
+	}
+	if err := s.authorize(ctx, "unquarantine", req.Key); err != nil {
+		return nil, err
+	}
+	if err := s.m.Unquarantine(req.Key); err != nil {
+		return nil, controlError(err)
+	}
+	return &controlpb.UnquarantineResponse{}, nil
+}
+
+func (s *controlServer[C]) StreamEvents(req *controlpb.StreamEventsRequest, stream controlpb.Control_StreamEventsServer) error {
+	if err := s.authorize(stream.Context(), "events", ""); err != nil {
+		return err
+	}
+	types := make(map[string]bool, len(req.Types))
+	for _, t := range req.Types {
+		types[t] = true
+	}
+	var events <-chan Event
+	var cancel func()
+	if req.Since != nil {
+		var err error
+		if events, cancel, err = s.m.SubscribeSince(req.Since.AsTime(), 64); err != nil {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+	} else {
+		events, cancel = s.m.Subscribe(64)
+	}
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case e := <-events:
+			if len(types) > 0 && !types[string(e.Type)] {
+				continue
+			}
+			var data []byte
+			if e.Data != nil {
+				data, _ = json.Marshal(e.Data)
+			}
+			if err := stream.Send(&controlpb.Event{
+				Type:       string(e.Type),
+				Key:        e.Key,
+				Generation: e.Generation,
+				Labels:     e.Labels,
+				Time:       timestamppb.New(e.Time),
+				Message:    e.Message,
+				DataJson:   data,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *controlServer[C]) StreamLogs(req *controlpb.StreamLogsRequest, stream controlpb.Control_StreamLogsServer) error {
+	if err := s.authorize(stream.Context(), "logs", req.Key); err != nil {
+		return err
+	}
+	lines, cancel := s.m.SubscribeLogs(req.Key, 256)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case l := <-lines:
+			if err := stream.Send(&controlpb.LogLine{
+				Key:        l.Key,
+				Generation: l.Generation,
+				Time:       timestamppb.New(l.Time),
+				Line:       l.Line,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func pluginProto(info PluginInfo) (*controlpb.Plugin, error) {
+	info = info.Redacted()
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	return &controlpb.Plugin{
+		Key:        info.Key,
+		Version:    info.Version,
+		Generation: info.Generation,
+		Restarts:   int32(info.Restarts),
+		Labels:     info.Labels,
+		InfoJson:   data,
+	}, nil
+}
+
+// controlError maps errors to gRPC status codes the same way AdminHandler
+// maps them to HTTP ones.
+func controlError(err error) error {
+	var code codes.Code
+	switch adminStatus(err) {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusConflict:
+		code = codes.FailedPrecondition
+	default:
+		code = codes.Internal
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
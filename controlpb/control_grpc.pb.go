@@ -0,0 +1,408 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package controlpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ControlClient is the client API for Control service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ControlClient interface {
+	ListPlugins(ctx context.Context, in *ListPluginsRequest, opts ...grpc.CallOption) (*ListPluginsResponse, error)
+	DescribePlugin(ctx context.Context, in *DescribePluginRequest, opts ...grpc.CallOption) (*DescribePluginResponse, error)
+	StartPlugin(ctx context.Context, in *StartPluginRequest, opts ...grpc.CallOption) (*StartPluginResponse, error)
+	StopPlugin(ctx context.Context, in *StopPluginRequest, opts ...grpc.CallOption) (*StopPluginResponse, error)
+	RestartPlugin(ctx context.Context, in *RestartPluginRequest, opts ...grpc.CallOption) (*RestartPluginResponse, error)
+	Unquarantine(ctx context.Context, in *UnquarantineRequest, opts ...grpc.CallOption) (*UnquarantineResponse, error)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Control_StreamEventsClient, error)
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Control_StreamLogsClient, error)
+}
+
+type controlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewControlClient(cc grpc.ClientConnInterface) ControlClient {
+	return &controlClient{cc}
+}
+
+func (c *controlClient) ListPlugins(ctx context.Context, in *ListPluginsRequest, opts ...grpc.CallOption) (*ListPluginsResponse, error) {
+	out := new(ListPluginsResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/ListPlugins", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) DescribePlugin(ctx context.Context, in *DescribePluginRequest, opts ...grpc.CallOption) (*DescribePluginResponse, error) {
+	out := new(DescribePluginResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/DescribePlugin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) StartPlugin(ctx context.Context, in *StartPluginRequest, opts ...grpc.CallOption) (*StartPluginResponse, error) {
+	out := new(StartPluginResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/StartPlugin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) StopPlugin(ctx context.Context, in *StopPluginRequest, opts ...grpc.CallOption) (*StopPluginResponse, error) {
+	out := new(StopPluginResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/StopPlugin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) RestartPlugin(ctx context.Context, in *RestartPluginRequest, opts ...grpc.CallOption) (*RestartPluginResponse, error) {
+	out := new(RestartPluginResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/RestartPlugin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) Unquarantine(ctx context.Context, in *UnquarantineRequest, opts ...grpc.CallOption) (*UnquarantineResponse, error) {
+	out := new(UnquarantineResponse)
+	err := c.cc.Invoke(ctx, "/pluginmanager.control.v1.Control/Unquarantine", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *controlClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (Control_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Control_ServiceDesc.Streams[0], "/pluginmanager.control.v1.Control/StreamEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Control_StreamEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type controlStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlStreamEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *controlClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (Control_StreamLogsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Control_ServiceDesc.Streams[1], "/pluginmanager.control.v1.Control/StreamLogs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &controlStreamLogsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Control_StreamLogsClient interface {
+	Recv() (*LogLine, error)
+	grpc.ClientStream
+}
+
+type controlStreamLogsClient struct {
+	grpc.ClientStream
+}
+
+func (x *controlStreamLogsClient) Recv() (*LogLine, error) {
+	m := new(LogLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ControlServer is the server API for Control service.
+// All implementations must embed UnimplementedControlServer
+// for forward compatibility
+type ControlServer interface {
+	ListPlugins(context.Context, *ListPluginsRequest) (*ListPluginsResponse, error)
+	DescribePlugin(context.Context, *DescribePluginRequest) (*DescribePluginResponse, error)
+	StartPlugin(context.Context, *StartPluginRequest) (*StartPluginResponse, error)
+	StopPlugin(context.Context, *StopPluginRequest) (*StopPluginResponse, error)
+	RestartPlugin(context.Context, *RestartPluginRequest) (*RestartPluginResponse, error)
+	Unquarantine(context.Context, *UnquarantineRequest) (*UnquarantineResponse, error)
+	StreamEvents(*StreamEventsRequest, Control_StreamEventsServer) error
+	StreamLogs(*StreamLogsRequest, Control_StreamLogsServer) error
+	mustEmbedUnimplementedControlServer()
+}
+
+// UnimplementedControlServer must be embedded to have forward compatible implementations.
+type UnimplementedControlServer struct {
+}
+
+func (UnimplementedControlServer) ListPlugins(context.Context, *ListPluginsRequest) (*ListPluginsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPlugins not implemented")
+}
+func (UnimplementedControlServer) DescribePlugin(context.Context, *DescribePluginRequest) (*DescribePluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribePlugin not implemented")
+}
+func (UnimplementedControlServer) StartPlugin(context.Context, *StartPluginRequest) (*StartPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartPlugin not implemented")
+}
+func (UnimplementedControlServer) StopPlugin(context.Context, *StopPluginRequest) (*StopPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopPlugin not implemented")
+}
+func (UnimplementedControlServer) RestartPlugin(context.Context, *RestartPluginRequest) (*RestartPluginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestartPlugin not implemented")
+}
+func (UnimplementedControlServer) Unquarantine(context.Context, *UnquarantineRequest) (*UnquarantineResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unquarantine not implemented")
+}
+func (UnimplementedControlServer) StreamEvents(*StreamEventsRequest, Control_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedControlServer) StreamLogs(*StreamLogsRequest, Control_StreamLogsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedControlServer) mustEmbedUnimplementedControlServer() {}
+
+// UnsafeControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ControlServer will
+// result in compilation errors.
+type UnsafeControlServer interface {
+	mustEmbedUnimplementedControlServer()
+}
+
+func RegisterControlServer(s grpc.ServiceRegistrar, srv ControlServer) {
+	s.RegisterService(&Control_ServiceDesc, srv)
+}
+
+func _Control_ListPlugins_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPluginsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).ListPlugins(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/ListPlugins",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).ListPlugins(ctx, req.(*ListPluginsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_DescribePlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribePluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).DescribePlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/DescribePlugin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).DescribePlugin(ctx, req.(*DescribePluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_StartPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).StartPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/StartPlugin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).StartPlugin(ctx, req.(*StartPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_StopPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).StopPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/StopPlugin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).StopPlugin(ctx, req.(*StopPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_RestartPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestartPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).RestartPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/RestartPlugin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).RestartPlugin(ctx, req.(*RestartPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_Unquarantine_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnquarantineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ControlServer).Unquarantine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pluginmanager.control.v1.Control/Unquarantine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ControlServer).Unquarantine(ctx, req.(*UnquarantineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Control_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).StreamEvents(m, &controlStreamEventsServer{stream})
+}
+
+type Control_StreamEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type controlStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlStreamEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Control_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ControlServer).StreamLogs(m, &controlStreamLogsServer{stream})
+}
+
+type Control_StreamLogsServer interface {
+	Send(*LogLine) error
+	grpc.ServerStream
+}
+
+type controlStreamLogsServer struct {
+	grpc.ServerStream
+}
+
+func (x *controlStreamLogsServer) Send(m *LogLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Control_ServiceDesc is the grpc.ServiceDesc for Control service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Control_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pluginmanager.control.v1.Control",
+	HandlerType: (*ControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListPlugins",
+			Handler:    _Control_ListPlugins_Handler,
+		},
+		{
+			MethodName: "DescribePlugin",
+			Handler:    _Control_DescribePlugin_Handler,
+		},
+		{
+			MethodName: "StartPlugin",
+			Handler:    _Control_StartPlugin_Handler,
+		},
+		{
+			MethodName: "StopPlugin",
+			Handler:    _Control_StopPlugin_Handler,
+		},
+		{
+			MethodName: "RestartPlugin",
+			Handler:    _Control_RestartPlugin_Handler,
+		},
+		{
+			MethodName: "Unquarantine",
+			Handler:    _Control_Unquarantine_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Control_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _Control_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "control.proto",
+}
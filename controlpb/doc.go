@@ -0,0 +1,5 @@
+// Package controlpb contains the protobuf definitions of the manager's
+// gRPC control service.
+package controlpb
+
+//go:generate buf generate --template buf.gen.yaml
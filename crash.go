@@ -0,0 +1,180 @@
+package manager
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// CrashDumpConfig collects post-mortem artifacts of plugins that crash or
+// hang. Each plugin gets its own directory under Dir, which defaults to
+// "crashes" in DataDir.
+type CrashDumpConfig struct {
+	Dir string
+	// CoreDumps raises the core file size limit of plugins to CoreLimit
+	// bytes, or unlimited when zero, and sets GOTRACEBACK=crash so Go
+	// plugins dump core when they panic. Plugins run in their dump
+	// directory, where the kernel writes cores when core_pattern is a
+	// relative path. It requires prlimit from util-linux.
+	CoreDumps bool
+	CoreLimit uint64
+	// GoroutineDumps sends SIGQUIT to unresponsive plugins and waits up to
+	// QuitTimeout, 2s by default, before killing them. Go plugins print the
+	// stacks of all goroutines on SIGQUIT, which are saved from their
+	// stderr.
+	GoroutineDumps bool
+	QuitTimeout    time.Duration
+}
+
+// CrashReport is the Data of EventExited events.
+type CrashReport struct {
+	Key        string     `json:"key"`
+	Generation uint64     `json:"generation"`
+	Exit       ExitReason `json:"exit"`
+	// Dir is the dump directory of the plugin, if dumps are enabled.
+	Dir           string `json:"dir,omitempty"`
+	CoreDump      string `json:"coreDump,omitempty"`
+	GoroutineDump string `json:"goroutineDump,omitempty"`
+}
+
+// crashDumps holds what an instance needs to collect its artifacts.
+type crashDumps struct {
+	dir         string
+	cores       bool
+	quit        bool
+	quitTimeout time.Duration
+	stderr      *tailBuffer
+}
+
+// prepareCrashDumps creates the dump directory of pm and sets up cmd to
+// leave artifacts in it.
+func (m *Manager[C]) prepareCrashDumps(cmd *exec.Cmd, pm PluginInfo) (*crashDumps, error) {
+	config := m.config.CrashDumps
+	dumps := &crashDumps{
+		dir:         filepath.Join(config.Dir, url.PathEscape(pm.Key)),
+		cores:       config.CoreDumps,
+		quit:        config.GoroutineDumps,
+		quitTimeout: config.QuitTimeout,
+	}
+	if dumps.quitTimeout == 0 {
+		dumps.quitTimeout = 2 * time.Second
+	}
+	if err := os.MkdirAll(dumps.dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	if config.CoreDumps {
+		path, err := exec.LookPath("prlimit")
+		if err != nil {
+			return nil, fmt.Errorf("enabling core dumps: %w", err)
+		}
+		limit := "unlimited"
+		if config.CoreLimit > 0 {
+			limit = strconv.FormatUint(config.CoreLimit, 10)
+		}
+		cmd.Path = path
+		cmd.Args = append([]string{path, "--core=" + limit, "--"}, cmd.Args...)
+		cmd.Dir = dumps.dir
+		cmd.Env = append(cmd.Env, "GOTRACEBACK=crash")
+	}
+	if config.GoroutineDumps {
+		dumps.stderr = &tailBuffer{max: 1 << 20}
+	}
+	return dumps, nil
+}
+
+// quit asks a hung plugin to dump its goroutines and reports whether it
+// exited.
+func (p *pluginInstance[T]) quit() bool {
+	if p.dumps == nil || !p.dumps.quit || p.cmd.Process == nil {
+		return false
+	}
+	p.dumps.stderr.Reset()
+	if err := p.cmd.Process.Signal(syscall.SIGQUIT); err != nil {
+		return false
+	}
+	return p.waitExited(p.dumps.quitTimeout)
+}
+
+// crashReport collects the artifacts left by the exit of the plugin, with
+// its sensitive values masked.
+func (p *pluginInstance[T]) crashReport(exit ExitReason) CrashReport {
+	exit.Err = p.Info.redact(exit.Err)
+	report := CrashReport{Key: p.Info.Key, Generation: p.Info.Generation, Exit: exit}
+	if p.dumps == nil {
+		return report
+	}
+	report.Dir = p.dumps.dir
+	stamp := fmt.Sprintf("%v-%v", p.Info.Generation, exit.Time.UTC().Format("20060102T150405"))
+
+	if p.dumps.cores && exit.Failed() {
+		report.CoreDump = newestCore(p.dumps.dir, p.started)
+	}
+	if p.dumps.quit && exit.Kind == ExitUnresponsive {
+		if out := p.dumps.stderr.Bytes(); len(out) > 0 {
+			path := filepath.Join(p.dumps.dir, "goroutines-"+stamp+".txt")
+			if err := os.WriteFile(path, []byte(p.Info.redact(string(out))), 0o600); err == nil {
+				report.GoroutineDump = path
+			}
+		}
+	}
+	return report
+}
+
+// newestCore finds the latest core file written to dir since the plugin
+// started.
+func newestCore(dir string, since time.Time) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var newest string
+	var newestTime time.Time
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "core") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().Before(since) || info.ModTime().Before(newestTime) {
+			continue
+		}
+		newest, newestTime = filepath.Join(dir, e.Name()), info.ModTime()
+	}
+	return newest
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = append(b.data[:0], b.data[len(b.data)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.data...)
+}
+
+func (b *tailBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = b.data[:0]
+}
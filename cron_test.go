@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// A Tuesday.
+	from := time.Date(2030, 1, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+		err  bool
+	}{
+		{expr: "* * * * *", next: time.Date(2030, 1, 1, 10, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", next: time.Date(2030, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{expr: "5-50/20 * * * *", next: time.Date(2030, 1, 1, 10, 45, 0, 0, time.UTC)},
+		{expr: "0,30 8-9 * * *", next: time.Date(2030, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 1-5", next: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", next: time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * 3 *", next: time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// A restricted day of month and day of week match if either does.
+		{expr: "0 0 15 * 0", next: time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{expr: "30 10 1 1 *", next: time.Date(2031, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{expr: "0 0 31 2 *"},
+		{expr: "", err: true},
+		{expr: "* * * *", err: true},
+		{expr: "* * * * * *", err: true},
+		{expr: "60 * * * *", err: true},
+		{expr: "* 24 * * *", err: true},
+		{expr: "* * 0 * *", err: true},
+		{expr: "* * * 13 *", err: true},
+		{expr: "* * * * 7", err: true},
+		{expr: "*/0 * * * *", err: true},
+		{expr: "*/x * * * *", err: true},
+		{expr: "a * * * *", err: true},
+		{expr: "5-1 * * * *", err: true},
+		{expr: "1-x * * * *", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			spec, err := parseCron(tt.expr)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseCron(%q) = %v, want error %v", tt.expr, err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if got := spec.next(from); !got.Equal(tt.next) {
+				t.Errorf("next(%v) = %v, want %v", from, got, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package manager
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// dashboardHandler serves the single-page dashboard. It only calls the
+// admin API with relative URLs, so it keeps working when AdminHandler is
+// mounted under a prefix.
+func dashboardHandler() http.Handler {
+	files, _ := fs.Sub(dashboardFiles, "dashboard")
+	return http.StripPrefix("/dashboard/", http.FileServerFS(files))
+}
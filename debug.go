@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"expvar"
+	"fmt"
+	"runtime"
+)
+
+// DebugInfo is a snapshot of the manager's internals for quick inspection.
+type DebugInfo struct {
+	// Plugins counts the plugins by state: running, degraded, exited,
+	// quarantined and standby, for singletons held elsewhere.
+	Plugins         map[string]int
+	Restarts        int
+	PendingRestarts int
+	DelayedRestarts int
+	SupervisorAlive bool
+	// Watchers counts the running health check goroutines, one per plugin.
+	Watchers      int
+	Goroutines    int
+	Subscriptions int
+}
+
+func (m *Manager[C]) Debug() DebugInfo {
+	superv := m.SupervisorStatus()
+	d := DebugInfo{
+		Plugins:         map[string]int{},
+		Restarts:        superv.Restarts,
+		PendingRestarts: superv.PendingRestarts,
+		DelayedRestarts: superv.DelayedRestarts,
+		SupervisorAlive: superv.Alive,
+		Goroutines:      runtime.NumGoroutine(),
+		Subscriptions:   m.events.subscribers(),
+	}
+
+	m.mu.Lock()
+	for _, p := range m.plugins {
+		switch {
+		case p.client.Exited():
+			d.Plugins["exited"]++
+		case p.pings.snapshot().Degraded:
+			d.Plugins["degraded"]++
+		default:
+			d.Plugins["running"]++
+		}
+		p.watchMu.Lock()
+		if p.watching && !p.stopped {
+			d.Watchers++
+		}
+		p.watchMu.Unlock()
+	}
+	for key := range m.singletons {
+		if _, ok := m.plugins[key]; !ok {
+			d.Plugins["standby"]++
+		}
+	}
+	m.mu.Unlock()
+
+	d.Plugins["quarantined"] = len(m.Quarantined())
+	return d
+}
+
+// PublishExpvar publishes Debug under "pluginmanager.<name>" in expvar,
+// served at /debug/vars by expvar's handler.
+func (m *Manager[C]) PublishExpvar() error {
+	name := "pluginmanager." + m.Name
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %v is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() any { return m.Debug() }))
+	return nil
+}
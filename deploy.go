@@ -0,0 +1,304 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	EventDeployed   EventType = "deployed"
+	EventPromoted   EventType = "promoted"
+	EventRolledBack EventType = "rolled_back"
+)
+
+type DeployOptions struct {
+	// Percent of calls routed to the new instance.
+	Percent int
+	// Rules route the calls made with matching RouteAttributes, taking
+	// precedence over Percent.
+	Rules []RouteRule
+	// HashAttribute, when set, splits calls carrying this attribute by its
+	// value instead of at random.
+	HashAttribute string
+	// MaxErrorRate, when positive, rolls the deployment back as soon as the
+	// new instance's error rate exceeds it after MinCalls calls.
+	MaxErrorRate float64
+	MinCalls     int64
+	// PromoteAfter, when positive, promotes the new instance once it has
+	// served this many calls without exceeding MaxErrorRate.
+	PromoteAfter int64
+	// Analyzer, when set, is consulted every AnalysisInterval with the calls
+	// served by both instances during the interval.
+	Analyzer         Analyzer
+	AnalysisInterval time.Duration
+}
+
+type InstanceStats struct {
+	Calls     int64
+	Errors    int64
+	ErrorRate float64
+}
+
+type DeploymentStatus struct {
+	Key      string
+	Old      PluginInfo
+	New      PluginInfo
+	Percent  int
+	Rules    []RouteRule
+	OldStats InstanceStats
+	NewStats InstanceStats
+}
+
+type deployment[C any] struct {
+	mu       sync.Mutex
+	green    *pluginInstance[C]
+	opts     DeployOptions
+	blue     InstanceStats
+	greenRun InstanceStats
+
+	blueWindow  InstanceStats
+	greenWindow InstanceStats
+}
+
+func (s *InstanceStats) observe(err error) {
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.ErrorRate = float64(s.Errors) / float64(s.Calls)
+}
+
+// Deploy starts a new instance of an already running plugin under the same
+// key and routes Call traffic to it by opts.Rules and opts.Percent until it
+// is promoted or rolled back.
+func (m *Manager[C]) Deploy(pm PluginInfo, opts DeployOptions) error {
+	if opts.Percent < 0 || opts.Percent > 100 {
+		return fmt.Errorf("invalid traffic percentage %v", opts.Percent)
+	}
+	if err := validateRouteRules(opts.Rules); err != nil {
+		return err
+	}
+	if opts.Analyzer != nil && opts.AnalysisInterval <= 0 {
+		opts.AnalysisInterval = time.Minute
+	}
+	if m.isClosed() {
+		return ErrManagerClosed
+	}
+	if _, ok := m.getPlugin(pm.Key); !ok {
+		return fmt.Errorf("%w: %v", ErrPluginNotFound, pm.Key)
+	}
+
+	m.mu.RLock()
+	_, exists := m.deploys[pm.Key]
+	m.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("plugin %v already has a deployment in progress", pm.Key)
+	}
+
+	killed := make(chan PluginInfo, 1)
+	green, err := m.loadPlugin(pm, killed)
+	if err != nil {
+		return err
+	}
+
+	d := &deployment[C]{green: green, opts: opts}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		green.Stop()
+		return ErrManagerClosed
+	}
+	if _, exists := m.deploys[pm.Key]; exists {
+		m.mu.Unlock()
+		green.Stop()
+		return fmt.Errorf("plugin %v already has a deployment in progress", pm.Key)
+	}
+	m.deploys[pm.Key] = d
+	m.watchLocked(green)
+	m.mu.Unlock()
+
+	go m.watchDeployed(green, killed)
+	if opts.Analyzer != nil {
+		go m.analyzeDeployment(pm.Key, d)
+	}
+
+	m.events.publish(pluginEvent(
+		EventDeployed,
+		green.Info,
+		fmt.Sprintf("routing %v%% of calls to new instance", opts.Percent),
+	))
+	return nil
+}
+
+// watchDeployed handles the exit of an instance started by Deploy. While the
+// deployment is in progress a crash rolls it back; once promoted, crashes are
+// forwarded to the supervisor like any other plugin.
+func (m *Manager[C]) watchDeployed(p *pluginInstance[C], killed chan PluginInfo) {
+	<-p.done
+
+	var info PluginInfo
+	select {
+	case info = <-killed:
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	d, ok := m.deploys[info.Key]
+	if ok && d.green == p {
+		delete(m.deploys, info.Key)
+		m.mu.Unlock()
+		p.Stop()
+		m.events.publish(pluginEvent(EventRolledBack, info, "new instance exited"))
+		return
+	}
+	current := m.plugins[info.Key] == p
+	m.mu.Unlock()
+
+	if current {
+		select {
+		case m.killed <- info:
+		default:
+		}
+	}
+}
+
+func (m *Manager[C]) SetTraffic(pluginKey string, percent int) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("invalid traffic percentage %v", percent)
+	}
+	m.mu.RLock()
+	d, ok := m.deploys[pluginKey]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("plugin %v has no deployment in progress", pluginKey)
+	}
+	d.mu.Lock()
+	d.opts.Percent = percent
+	d.mu.Unlock()
+	return nil
+}
+
+// SetRouteRules replaces the routing rules of a deployment in progress.
+func (m *Manager[C]) SetRouteRules(pluginKey string, rules []RouteRule) error {
+	if err := validateRouteRules(rules); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	d, ok := m.deploys[pluginKey]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("plugin %v has no deployment in progress", pluginKey)
+	}
+	d.mu.Lock()
+	d.opts.Rules = append([]RouteRule(nil), rules...)
+	d.mu.Unlock()
+	return nil
+}
+
+// Promote makes the deployed instance the primary one and stops the old one.
+func (m *Manager[C]) Promote(pluginKey string) error {
+	m.mu.Lock()
+	d, ok := m.deploys[pluginKey]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("plugin %v has no deployment in progress", pluginKey)
+	}
+	delete(m.deploys, pluginKey)
+	old := m.plugins[pluginKey]
+	m.plugins[pluginKey] = d.green
+	m.mu.Unlock()
+
+	if old != nil {
+		old.Stop()
+	}
+	m.stopStandby(pluginKey)
+	m.spawnStandby(d.green.Info)
+	m.events.publish(pluginEvent(EventPromoted, d.green.Info, ""))
+	return nil
+}
+
+// Rollback stops the deployed instance and keeps the old one.
+func (m *Manager[C]) Rollback(pluginKey string) error {
+	m.mu.Lock()
+	d, ok := m.deploys[pluginKey]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("plugin %v has no deployment in progress", pluginKey)
+	}
+	delete(m.deploys, pluginKey)
+	m.mu.Unlock()
+
+	d.green.Stop()
+	m.events.publish(pluginEvent(EventRolledBack, d.green.Info, ""))
+	return nil
+}
+
+func (m *Manager[C]) DeploymentStatus(pluginKey string) (DeploymentStatus, error) {
+	m.mu.RLock()
+	d, ok := m.deploys[pluginKey]
+	old := m.plugins[pluginKey]
+	m.mu.RUnlock()
+	if !ok {
+		return DeploymentStatus{}, fmt.Errorf("plugin %v has no deployment in progress", pluginKey)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := DeploymentStatus{
+		Key:      pluginKey,
+		New:      d.green.Info,
+		Percent:  d.opts.Percent,
+		Rules:    d.opts.Rules,
+		OldStats: d.blue,
+		NewStats: d.greenRun,
+	}
+	if old != nil {
+		status.Old = old.Info
+	}
+	return status, nil
+}
+
+// route picks the instance serving a call made with attrs, returning the
+// deployment it belongs to when one is in progress.
+func (m *Manager[C]) route(pluginKey string, attrs RouteAttributes) (*pluginInstance[C], *deployment[C], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.plugins[pluginKey]
+	if !ok {
+		return nil, nil, false
+	}
+	d, ok := m.deploys[pluginKey]
+	if !ok {
+		return p, nil, true
+	}
+	d.mu.Lock()
+	green := routeGreen(d.opts, attrs)
+	d.mu.Unlock()
+	if green {
+		return d.green, d, true
+	}
+	return p, d, true
+}
+
+func (m *Manager[C]) observeDeployment(pluginKey string, d *deployment[C], p *pluginInstance[C], err error) {
+	d.mu.Lock()
+	if p != d.green {
+		d.blue.observe(err)
+		d.blueWindow.observe(err)
+		d.mu.Unlock()
+		return
+	}
+	d.greenRun.observe(err)
+	d.greenWindow.observe(err)
+	stats, opts := d.greenRun, d.opts
+	d.mu.Unlock()
+
+	switch {
+	case opts.MaxErrorRate > 0 && stats.Calls >= opts.MinCalls && stats.ErrorRate > opts.MaxErrorRate:
+		m.Rollback(pluginKey)
+	case opts.PromoteAfter > 0 && stats.Calls >= opts.PromoteAfter:
+		m.Promote(pluginKey)
+	}
+}
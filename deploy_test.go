@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDeployRollsBackCrashedGreen(t *testing.T) {
+	m := newTestManager(t, ManagerConfig{
+		RestartConfig: RestartConfig{Managed: true, PingInterval: 20 * time.Millisecond},
+	})
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	pm := testPluginInfo(t, "p")
+	if _, err := m.StartPlugin(pm); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := m.Subscribe(16)
+	defer cancel()
+	if err := m.Deploy(pm, DeployOptions{Percent: 50}); err != nil {
+		t.Fatal(err)
+	}
+	m.mu.RLock()
+	green := m.deploys[pm.Key].green
+	m.mu.RUnlock()
+
+	if err := syscall.Kill(green.client.ReattachConfig().Pid, syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	timeout := time.After(5 * time.Second)
+	for rolledBack := false; !rolledBack; {
+		select {
+		case e := <-events:
+			rolledBack = e.Type == EventRolledBack
+		case <-timeout:
+			t.Fatal("the deployment was not rolled back")
+		}
+	}
+	// The crashed instance is stopped, releasing what it held.
+	if _, err := os.Stat(green.sockDir); !os.IsNotExist(err) {
+		t.Errorf("socket directory of the crashed instance: %v, want it removed", err)
+	}
+}
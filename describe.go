@@ -0,0 +1,59 @@
+package manager
+
+import "fmt"
+
+// PluginDescription is a detailed view of a running plugin for debugging
+// and audits.
+type PluginDescription struct {
+	Info       PluginInfo
+	Connection ConnectionInfo
+	Sandbox    AppliedSandbox
+	Pings      PingStats
+	Provenance *Provenance
+	// SystemdUnit is the scope the plugin runs in, if any.
+	SystemdUnit string
+	Quarantine  *Quarantine
+	// Resources is the usage of the plugin process, where supported.
+	Resources *ResourceUsage
+	// Scan is the vulnerability scan of the binary, with ScanPolicy.
+	Scan *ScanResult
+	// Standby is the warm standby of plugins with PluginInfo.Standby, once
+	// it is running.
+	Standby  *PluginInfo
+	Pressure Pressure
+}
+
+func (m *Manager[C]) DescribePlugin(pluginKey string) (PluginDescription, error) {
+	if m.isClosed() {
+		return PluginDescription{}, ErrManagerClosed
+	}
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return PluginDescription{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	d := PluginDescription{
+		Info:        p.Info,
+		Connection:  p.ConnectionInfo(),
+		Sandbox:     p.sandbox,
+		Pings:       p.pings.snapshot(),
+		Provenance:  p.prov,
+		SystemdUnit: p.unit,
+		Scan:        p.scan,
+		Pressure:    m.pressure(p),
+	}
+	m.mu.RLock()
+	if s, ok := m.standbys[pluginKey]; ok {
+		info := s.Info
+		d.Standby = &info
+	}
+	m.mu.RUnlock()
+	if q, ok := m.quarantine(pluginKey); ok {
+		d.Quarantine = &q
+	}
+	if d.Connection.Pid > 0 {
+		if usage, err := processUsage(d.Connection.Pid); err == nil {
+			d.Resources = &usage
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/rpc"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+)
+
+// Descriptor ties a plugin interface C to the handshake and goplugin.Plugin
+// implementing it, so hosts and plugins share one definition. Using
+// NetRPCPluginFor or GRPCPluginFor as Plugin makes the compiler check that
+// the client dispensed for the plugin implements C.
+type Descriptor[C any] struct {
+	// Name is the manager's name and the name the plugin is served under.
+	Name      string
+	Handshake goplugin.HandshakeConfig
+	Plugin    goplugin.Plugin
+}
+
+// Serve is used by plugins to serve impl as described by d.
+func (d Descriptor[C]) Serve(impl C) {
+	plugin := d.Plugin
+	switch p := plugin.(type) {
+	case *NetRPCPluginFor[C]:
+		served := *p
+		served.Impl = impl
+		plugin = &served
+	case *GRPCPluginFor[C]:
+		served := *p
+		served.Impl = impl
+		plugin = &served
+	}
+	config := &goplugin.ServeConfig{
+		HandshakeConfig: d.Handshake,
+		Plugins:         goplugin.PluginSet{d.Name: plugin},
+	}
+	if _, ok := plugin.(goplugin.GRPCPlugin); ok {
+		config.GRPCServer = goplugin.DefaultGRPCServer
+	}
+	goplugin.Serve(config)
+}
+
+func (d Descriptor[C]) validate() error {
+	var errs []error
+	if d.Name == "" {
+		errs = append(errs, errors.New("empty name"))
+	}
+	if d.Handshake.MagicCookieKey == "" || d.Handshake.MagicCookieValue == "" {
+		errs = append(errs, errors.New("handshake has no magic cookie"))
+	}
+	if d.Handshake.ProtocolVersion == 0 {
+		errs = append(errs, errors.New("handshake has no protocol version"))
+	}
+	if d.Plugin == nil {
+		errs = append(errs, errors.New("no plugin"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("descriptor %q: %w", d.Name, err)
+	}
+	return nil
+}
+
+// NewManagerFor creates a manager for the plugins described by d. config
+// may be nil; its handshake and plugin are set from d.
+func NewManagerFor[C any](d Descriptor[C], config *ManagerConfig) (*Manager[C], error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &ManagerConfig{}
+	}
+	config.HandshakeConfig = d.Handshake
+	config.Plugin = d.Plugin
+	if _, ok := d.Plugin.(*GRPCPluginFor[C]); ok && len(config.AllowedProtocols) == 0 {
+		config.AllowedProtocols = []goplugin.Protocol{goplugin.ProtocolGRPC}
+	}
+	return NewManager[C](d.Name, config), nil
+}
+
+// NetRPCPluginFor is a netrpc goplugin.Plugin for the interface C. Impl is
+// only set in the plugin.
+type NetRPCPluginFor[C any] struct {
+	Impl C
+	// NewServer returns the RPC server serving impl in the plugin and
+	// NewClient the implementation of C calling it in the host.
+	NewServer func(impl C) any
+	NewClient func(c *rpc.Client) C
+}
+
+func (p *NetRPCPluginFor[C]) Server(*goplugin.MuxBroker) (any, error) {
+	return p.NewServer(p.Impl), nil
+}
+
+func (p *NetRPCPluginFor[C]) Client(_ *goplugin.MuxBroker, c *rpc.Client) (any, error) {
+	return p.NewClient(c), nil
+}
+
+// GRPCPluginFor is a gRPC goplugin.Plugin for the interface C. Impl is only
+// set in the plugin.
+type GRPCPluginFor[C any] struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl C
+	// Register registers impl with the plugin's gRPC server and NewClient
+	// returns the implementation of C calling it in the host.
+	Register  func(s *grpc.Server, impl C)
+	NewClient func(conn *grpc.ClientConn) C
+}
+
+func (p *GRPCPluginFor[C]) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	p.Register(s, p.Impl)
+	return nil
+}
+
+func (p *GRPCPluginFor[C]) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, conn *grpc.ClientConn) (any, error) {
+	return p.NewClient(conn), nil
+}
@@ -0,0 +1,261 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+type DoctorStatus string
+
+const (
+	DoctorOK   DoctorStatus = "ok"
+	DoctorWarn DoctorStatus = "warn"
+	DoctorFail DoctorStatus = "fail"
+)
+
+// DoctorCheck is the outcome of one check of Doctor. Guidance tells how to
+// fix the host when the check did not pass.
+type DoctorCheck struct {
+	Name     string       `json:"name"`
+	Status   DoctorStatus `json:"status"`
+	Detail   string       `json:"detail"`
+	Guidance string       `json:"guidance,omitempty"`
+}
+
+type DoctorReport struct {
+	Manager   string        `json:"manager"`
+	Platform  string        `json:"platform"`
+	GoVersion string        `json:"goVersion"`
+	GoPlugin  string        `json:"goPlugin"`
+	Checks    []DoctorCheck `json:"checks"`
+}
+
+// Err returns an error listing the failed checks with their guidance, or
+// nil.
+func (r DoctorReport) Err() error {
+	var errs []error
+	for _, c := range r.Checks {
+		if c.Status == DoctorFail {
+			errs = append(errs, fmt.Errorf("%v: %v; %v", c.Name, c.Detail, c.Guidance))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// minGoPlugin is the oldest go-plugin release the manager works with; it
+// relies on EnvUnixSocketDir, added in v1.6.0.
+const minGoPlugin = "v1.6.0"
+
+// minOpenFiles is the open file limit below which hosts running many
+// plugins run out of descriptors: each plugin holds its socket, its stdio
+// pipes and the connections multiplexed over them.
+const minOpenFiles = 1024
+
+// maxSocketPath is the shortest limit on unix socket paths among the
+// supported platforms, from sockaddr_un on Darwin and the BSDs.
+const maxSocketPath = 104
+
+// Doctor checks that the host has what the manager needs to run plugins, so
+// that a misconfigured host is reported up front rather than through plugins
+// failing to start later. It starts and changes nothing, besides creating
+// and removing probe files.
+func (m *Manager[C]) Doctor() DoctorReport {
+	r := DoctorReport{
+		Manager:   m.Name,
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		GoVersion: runtime.Version(),
+		GoPlugin:  goPluginVersion(),
+	}
+	r.Checks = append(r.Checks,
+		checkWritableDir("tmpdir", os.TempDir(), "set TMPDIR to a writable directory"),
+		m.checkSocketDir(),
+		checkFileLimit(),
+		m.checkCgroups(),
+		checkGoPlugin(r.GoPlugin),
+	)
+	if runtime.GOOS == "linux" {
+		c := checkTool("setpriv", "dropping plugin privileges", "install util-linux or set ManagerConfig.AllowUnsandboxed")
+		if c.Status == DoctorFail && m.config.AllowUnsandboxed {
+			c.Status = DoctorWarn
+		}
+		r.Checks = append(r.Checks, c)
+	}
+	if m.config.CrashDumps != nil && m.config.CrashDumps.CoreDumps {
+		r.Checks = append(r.Checks, checkTool("prlimit", "core dumps", "install util-linux or disable CrashDumps.CoreDumps"))
+	}
+	return r
+}
+
+// selfCheck logs the startup banner and the checks of Doctor that did not
+// pass, and fails with SelfCheck when one failed.
+func (m *Manager[C]) selfCheck() error {
+	r := m.Doctor()
+	log := m.config.Logger
+	log.Info("starting plugin manager", "manager", r.Manager, "platform", r.Platform, "go", r.GoVersion, "go_plugin", r.GoPlugin)
+	for _, c := range r.Checks {
+		switch c.Status {
+		case DoctorWarn:
+			log.Warn("host check", "check", c.Name, "detail", c.Detail, "guidance", c.Guidance)
+		case DoctorFail:
+			log.Error("host check failed", "check", c.Name, "detail", c.Detail, "guidance", c.Guidance)
+		}
+	}
+	if !m.config.SelfCheck {
+		return nil
+	}
+	return r.Err()
+}
+
+func checkWritableDir(name, dir, guidance string) DoctorCheck {
+	c := DoctorCheck{Name: name, Status: DoctorOK, Detail: dir + " is writable"}
+	f, err := os.CreateTemp(dir, ".plugin-manager-doctor-")
+	if err != nil {
+		c.Status, c.Detail, c.Guidance = DoctorFail, err.Error(), guidance
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return c
+}
+
+func (m *Manager[C]) checkSocketDir() DoctorCheck {
+	const guidance = "set ManagerConfig.SocketDir to a short directory only the host user can write to"
+	parent := m.config.SocketDir
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	existing := parent
+	if _, err := os.Stat(parent); errors.Is(err, os.ErrNotExist) {
+		// socketRoot creates it.
+		existing = filepath.Dir(filepath.Clean(parent))
+	}
+	c := checkWritableDir("socket dir", existing, guidance)
+	if c.Status != DoctorOK {
+		return c
+	}
+	// The sockets are in the socket root, in a directory per plugin; the
+	// longest is that of the socket transport.
+	longest := len(filepath.Join(parent, "plugin-manager-0000000000", "plugin-0000000000", "plugin-sock-0000000000", "plugin.sock"))
+	if longest > maxSocketPath {
+		c.Status, c.Guidance = DoctorFail, guidance
+		c.Detail = fmt.Sprintf("socket paths in %v reach %v bytes, over the %v byte limit", parent, longest, maxSocketPath)
+		return c
+	}
+	fi, err := os.Stat(existing)
+	if err == nil && runtime.GOOS != "windows" && fi.Mode().Perm()&0o002 != 0 && fi.Mode()&os.ModeSticky == 0 {
+		c.Status, c.Guidance = DoctorWarn, guidance
+		c.Detail = existing + " is world-writable without the sticky bit"
+	}
+	return c
+}
+
+func checkFileLimit() DoctorCheck {
+	c := DoctorCheck{Name: "open files", Status: DoctorOK}
+	cur, max, err := openFileLimit()
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	c.Detail = fmt.Sprintf("limit %v, hard limit %v", cur, max)
+	if cur < minOpenFiles {
+		c.Status = DoctorWarn
+		c.Guidance = fmt.Sprintf("raise the open file limit (ulimit -n, LimitNOFILE) to at least %v", minOpenFiles)
+	}
+	return c
+}
+
+// checkCgroups checks the cgroup filesystem, without which systemd cannot
+// apply the resource limits of ManagerConfig.Systemd.
+func (m *Manager[C]) checkCgroups() DoctorCheck {
+	c := DoctorCheck{Name: "cgroups", Status: DoctorOK}
+	if runtime.GOOS != "linux" {
+		c.Detail = "not applicable on " + runtime.GOOS
+		return c
+	}
+	switch {
+	case fileExists("/sys/fs/cgroup/cgroup.controllers"):
+		b, _ := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+		c.Detail = "cgroup v2, controllers: " + strings.TrimSpace(string(b))
+	case fileExists("/sys/fs/cgroup/memory"):
+		c.Detail = "cgroup v1"
+	default:
+		c.Detail = "no cgroup filesystem at /sys/fs/cgroup"
+		c.Status = DoctorWarn
+		if m.config.Systemd != nil {
+			c.Status = DoctorFail
+		}
+		c.Guidance = "mount the cgroup filesystem or run without ManagerConfig.Systemd"
+		return c
+	}
+	if m.config.Systemd != nil {
+		if t := checkTool("systemd-run", "systemd scopes", "install systemd or unset ManagerConfig.Systemd"); t.Status != DoctorOK {
+			t.Name = c.Name
+			return t
+		}
+	}
+	return c
+}
+
+func checkTool(name, feature, guidance string) DoctorCheck {
+	c := DoctorCheck{Name: name, Status: DoctorOK}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		c.Status, c.Guidance = DoctorFail, guidance
+		c.Detail = fmt.Sprintf("needed for %v: %v", feature, err)
+		return c
+	}
+	c.Detail = path
+	return c
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// goPluginVersion returns the version of go-plugin the host was built with,
+// or "unknown" without build information.
+func goPluginVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/hashicorp/go-plugin" {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+func checkGoPlugin(version string) DoctorCheck {
+	c := DoctorCheck{Name: "go-plugin", Status: DoctorOK, Detail: version}
+	v, err := parseVersion(version)
+	if err != nil {
+		// Local replacements and tests have no version to check.
+		c.Detail = "version " + version + " cannot be checked"
+		return c
+	}
+	min, _ := parseVersion(minGoPlugin)
+	switch {
+	case v[0] != min[0]:
+		c.Status = DoctorFail
+		c.Detail = fmt.Sprintf("version %v has an incompatible major version", version)
+	case compareVersions(v, min) < 0:
+		c.Status = DoctorFail
+		c.Detail = fmt.Sprintf("version %v is older than %v", version, minGoPlugin)
+	}
+	if c.Status == DoctorFail {
+		c.Guidance = fmt.Sprintf("require github.com/hashicorp/go-plugin %v or a later v1 release", minGoPlugin)
+	}
+	return c
+}
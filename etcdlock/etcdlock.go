@@ -0,0 +1,59 @@
+// Package etcdlock implements manager.Locker on top of etcd.
+package etcdlock
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// Locker takes locks under Prefix. Each lock has its own session, so a
+// manager that dies loses its locks once the session TTL expires.
+type Locker struct {
+	Client *clientv3.Client
+	Prefix string
+	// TTL of the sessions in seconds. Defaults to 10.
+	TTL int
+}
+
+func (l *Locker) Lock(ctx context.Context, name string) (manager.Lease, error) {
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = 10
+	}
+	session, err := concurrency.NewSession(l.Client, concurrency.WithTTL(ttl))
+	if err != nil {
+		return nil, err
+	}
+	mu := concurrency.NewMutex(session, path.Join(l.Prefix, name))
+	if err := mu.Lock(ctx); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return &lease{session: session, mu: mu}, nil
+}
+
+type lease struct {
+	session *concurrency.Session
+	mu      *concurrency.Mutex
+	once    sync.Once
+}
+
+func (l *lease) Lost() <-chan struct{} {
+	return l.session.Done()
+}
+
+func (l *lease) Unlock() error {
+	var err error
+	l.once.Do(func() {
+		err = l.mu.Unlock(context.Background())
+		if cerr := l.session.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
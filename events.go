@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventDegraded  EventType = "degraded"
+	EventRecovered EventType = "recovered"
+)
+
+type Event struct {
+	Type       EventType
+	Key        string
+	Generation uint64
+	Labels     map[string]string
+	Time       time.Time
+	Message    string
+	Data       any
+}
+
+// pluginEvent returns an event about the plugin with its sensitive values
+// masked in message. Data set afterwards must be masked by the caller.
+func pluginEvent(t EventType, info PluginInfo, message string) Event {
+	message = info.redact(message)
+	info = info.Redacted()
+	return Event{
+		Type:       t,
+		Key:        info.Key,
+		Generation: info.Generation,
+		Labels:     info.Labels,
+		Message:    message,
+	}
+}
+
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+	// store, when set, records every event before it is delivered.
+	store   EventStore
+	onError func(error)
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan Event]struct{})}
+}
+
+func (b *eventBus) subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, b.unsubscribe(ch)
+}
+
+// subscribeSince queues the stored events since the given time on a new
+// subscription. Publishing waits meanwhile, so none is missed or repeated.
+func (b *eventBus) subscribeSince(since time.Time, buffer int) (<-chan Event, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	past, err := b.store.Since(since)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan Event, len(past)+buffer)
+	for _, e := range past {
+		ch <- e
+	}
+	b.subs[ch] = struct{}{}
+	return ch, b.unsubscribe(ch), nil
+}
+
+func (b *eventBus) unsubscribe(ch chan Event) func() {
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return cancel
+}
+
+func (b *eventBus) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *eventBus) publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.store != nil {
+		if err := b.store.Append(e); err != nil && b.onError != nil {
+			b.onError(err)
+		}
+	}
+	for ch := range b.subs {
+		// Non-blocking send or discard
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (m *Manager[C]) Subscribe(buffer int) (<-chan Event, func()) {
+	return m.events.subscribe(buffer)
+}
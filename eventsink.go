@@ -0,0 +1,51 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// EventSink receives every event published by the manager, for instance
+// to forward it to a message broker. Each sink has its own queue, so a slow
+// sink does not hold up the others; events are dropped while it is full.
+type EventSink interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// MarshalEvent encodes an event as JSON, leaving out Data that cannot be
+// encoded.
+func MarshalEvent(e Event) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		e.Data = nil
+		return json.Marshal(e)
+	}
+	return b, nil
+}
+
+// forwardEvents calls fn with the events published until ctx is done, in
+// a goroutine of its own.
+func (m *Manager[C]) forwardEvents(ctx context.Context, buffer int, fn func(Event)) {
+	events, cancel := m.events.subscribe(buffer)
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-events:
+				fn(e)
+			}
+		}
+	}()
+}
+
+func (m *Manager[C]) startEventSinks(ctx context.Context, sinks []EventSink) {
+	for _, sink := range sinks {
+		m.forwardEvents(ctx, 256, func(e Event) {
+			if err := sink.Publish(ctx, e); err != nil && ctx.Err() == nil {
+				m.config.Logger.Warn("failed to publish event", "event", e.Type, LogKeyReason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var ErrNoEventStore = errors.New("no event store is configured")
+
+// EventStore persists published events so they can be replayed to
+// subscribers that attach later. Data of replayed events may be a
+// json.RawMessage rather than the value that was published.
+type EventStore interface {
+	Append(e Event) error
+	// Since returns the events published at or after since, oldest first.
+	Since(since time.Time) ([]Event, error)
+}
+
+// FileEventStore appends events as JSON lines to a file.
+type FileEventStore struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+func NewFileEventStore(path string) *FileEventStore {
+	return &FileEventStore{path: path}
+}
+
+func (s *FileEventStore) Append(e Event) error {
+	b, err := MarshalEvent(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+			return err
+		}
+		if s.f, err = os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600); err != nil {
+			return err
+		}
+	}
+	_, err = s.f.Write(append(b, '\n'))
+	return err
+}
+
+func (s *FileEventStore) Since(since time.Time) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var stored struct {
+			Event
+			Data json.RawMessage
+		}
+		if err := json.Unmarshal(sc.Bytes(), &stored); err != nil {
+			// A line cut short by a crash.
+			continue
+		}
+		if stored.Time.Before(since) {
+			continue
+		}
+		e := stored.Event
+		if len(stored.Data) > 0 && string(stored.Data) != "null" {
+			e.Data = stored.Data
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
+
+func (s *FileEventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// MemoryEventStore keeps the last capacity events in memory, 1000 by
+// default.
+type MemoryEventStore struct {
+	mu       sync.Mutex
+	capacity int
+	events   []Event
+}
+
+func NewMemoryEventStore(capacity int) *MemoryEventStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryEventStore{capacity: capacity}
+}
+
+func (s *MemoryEventStore) Append(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == s.capacity {
+		s.events = s.events[1:]
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *MemoryEventStore) Since(since time.Time) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []Event
+	for _, e := range s.events {
+		if !e.Time.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// ReplayEvents returns the events published since the given time, as
+// recorded by ManagerConfig.EventStore.
+func (m *Manager[C]) ReplayEvents(since time.Time) ([]Event, error) {
+	if m.events.store == nil {
+		return nil, ErrNoEventStore
+	}
+	return m.events.store.Since(since)
+}
+
+// SubscribeSince is Subscribe preceded by the events published since the
+// given time, without missing or repeating any published in between.
+func (m *Manager[C]) SubscribeSince(since time.Time, buffer int) (<-chan Event, func(), error) {
+	if m.events.store == nil {
+		return nil, nil, ErrNoEventStore
+	}
+	return m.events.subscribeSince(since, buffer)
+}
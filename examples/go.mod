@@ -0,0 +1,28 @@
+module github.com/joshwizzy/go-plugin-manager/examples
+
+go 1.22.0
+
+replace github.com/joshwizzy/go-plugin-manager => ../
+
+require (
+	github.com/hashicorp/go-plugin v1.6.0
+	github.com/joshwizzy/go-plugin-manager v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.28.2-0.20230222093303-bc1253ad3743
+)
+
+require (
+	github.com/fatih/color v1.13.0 // indirect
+	github.com/golang/protobuf v1.5.0 // indirect
+	github.com/hashicorp/go-hclog v1.6.3 // indirect
+	github.com/hashicorp/yamux v0.1.1 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/mitchellh/go-testing-interface v0.0.0-20171004221916-a61a99592b77 // indirect
+	github.com/oklog/run v1.0.0 // indirect
+	go.etcd.io/bbolt v1.3.11 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+)
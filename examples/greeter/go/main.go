@@ -0,0 +1,23 @@
+// Command go is the Go implementation of the example Greeter plugin.
+package main
+
+import (
+	"context"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/joshwizzy/go-plugin-manager/examples/greeter"
+)
+
+type impl struct{}
+
+func (impl) Greet(_ context.Context, name string) (string, error) {
+	return "Hello " + name + " from Go", nil
+}
+
+func main() {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: greeter.Handshake,
+		Plugins:         goplugin.PluginSet{"greeter": &greeter.Plugin{Impl: impl{}}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
@@ -0,0 +1,67 @@
+// Package greeter is the host side of the example plugins, which serve the
+// Greeter service of greeterpb/greeter.proto over gRPC with the go-plugin
+// handshake. The plugins in go, python and rust are interchangeable: the
+// manager starts, health checks and restarts them the same way.
+package greeter
+
+import (
+	"context"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/joshwizzy/go-plugin-manager/examples/greeter/greeterpb"
+	"google.golang.org/grpc"
+)
+
+// Greeter is the interface the host uses the plugins through.
+type Greeter interface {
+	Greet(ctx context.Context, name string) (string, error)
+}
+
+// Handshake must match the magic cookie the plugins check and the protocol
+// version they print in their handshake line.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "GREETER_PLUGIN",
+	MagicCookieValue: "greeter",
+}
+
+// Plugin serves Impl in Go plugins and dispenses a Greeter calling the
+// plugin in the host.
+type Plugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl Greeter
+}
+
+func (p *Plugin) GRPCServer(_ *goplugin.GRPCBroker, s *grpc.Server) error {
+	greeterpb.RegisterGreeterServer(s, &server{impl: p.Impl})
+	return nil
+}
+
+func (p *Plugin) GRPCClient(_ context.Context, _ *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &client{c: greeterpb.NewGreeterClient(c)}, nil
+}
+
+type client struct {
+	c greeterpb.GreeterClient
+}
+
+func (c *client) Greet(ctx context.Context, name string) (string, error) {
+	resp, err := c.c.Greet(ctx, &greeterpb.GreetRequest{Name: name})
+	if err != nil {
+		return "", err
+	}
+	return resp.Message, nil
+}
+
+type server struct {
+	greeterpb.UnimplementedGreeterServer
+	impl Greeter
+}
+
+func (s *server) Greet(ctx context.Context, req *greeterpb.GreetRequest) (*greeterpb.GreetResponse, error) {
+	msg, err := s.impl.Greet(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &greeterpb.GreetResponse{Message: msg}, nil
+}
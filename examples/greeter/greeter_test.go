@@ -0,0 +1,113 @@
+package greeter_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/joshwizzy/go-plugin-manager/examples/greeter"
+)
+
+// TestPlugins builds every example plugin and runs it through the manager.
+// Plugins whose toolchain or dependencies are missing are skipped.
+func TestPlugins(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t *testing.T, dir string) manager.PluginInfo
+		want  string
+	}{
+		{name: "go", build: buildGo, want: "Hello host from Go"},
+		{name: "python", build: buildPython, want: "Hello host from Python"},
+		{name: "rust", build: buildRust, want: "Hello host from Rust"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := tt.build(t, t.TempDir())
+			pm.Key = tt.name
+
+			m := manager.NewManager[greeter.Greeter]("greeter", &manager.ManagerConfig{
+				HandshakeConfig:  greeter.Handshake,
+				Plugin:           &greeter.Plugin{},
+				AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+				AllowUnsandboxed: true,
+			})
+			defer m.Shutdown()
+			if err := m.LoadPlugins([]manager.PluginInfo{pm}); err != nil {
+				t.Fatalf("LoadPlugins: %v", err)
+			}
+			greet(t, m, pm.Key, tt.want)
+			if err := m.RestartPlugin(pm); err != nil {
+				t.Fatalf("RestartPlugin: %v", err)
+			}
+			greet(t, m, pm.Key, tt.want)
+		})
+	}
+}
+
+func greet(t *testing.T, m *manager.Manager[greeter.Greeter], key, want string) {
+	t.Helper()
+	g, err := m.GetPlugin(key)
+	if err != nil {
+		t.Fatalf("GetPlugin: %v", err)
+	}
+	got, err := g.Greet(context.Background(), "host")
+	if err != nil {
+		t.Fatalf("Greet: %v", err)
+	}
+	if got != want {
+		t.Errorf("Greet = %q, want %q", got, want)
+	}
+}
+
+func buildGo(t *testing.T, dir string) manager.PluginInfo {
+	bin := filepath.Join(dir, "greeter")
+	run(t, exec.Command("go", "build", "-o", bin, "./go"))
+	return manager.PluginInfo{BinPath: bin}
+}
+
+func buildPython(t *testing.T, dir string) manager.PluginInfo {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip(err)
+	}
+	if out, err := exec.Command(python, "-c", "import grpc, grpc_health, grpc_tools").CombinedOutput(); err != nil {
+		t.Skipf("python dependencies from python/requirements.txt are missing: %s", out)
+	}
+	run(t, exec.Command(python, "-m", "grpc_tools.protoc", "-Igreeterpb", "--python_out="+dir, "--grpc_python_out="+dir, "greeterpb/greeter.proto"))
+	// The script imports the stubs from its own directory.
+	src, err := os.ReadFile("python/server.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := filepath.Join(dir, "server.py")
+	if err := os.WriteFile(server, src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return manager.PluginInfo{BinPath: server}
+}
+
+func buildRust(t *testing.T, dir string) manager.PluginInfo {
+	cargo, err := exec.LookPath("cargo")
+	if err != nil {
+		t.Skip(err)
+	}
+	manifest := filepath.Join("rust", "Cargo.toml")
+	if out, err := exec.Command(cargo, "fetch", "--manifest-path", manifest).CombinedOutput(); err != nil {
+		t.Skipf("fetching crates: %s", out)
+	}
+	target := filepath.Join(dir, "target")
+	run(t, exec.Command(cargo, "build", "--release", "--manifest-path", manifest, "--target-dir", target))
+	return manager.PluginInfo{BinPath: filepath.Join(target, "release", "greeter-plugin")}
+}
+
+func run(t *testing.T, cmd *exec.Cmd) {
+	t.Helper()
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %v", cmd, err)
+	}
+}
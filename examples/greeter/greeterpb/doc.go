@@ -0,0 +1,5 @@
+// Package greeterpb contains the protobuf definitions of the Greeter service
+// served by the example plugins.
+package greeterpb
+
+//go:generate buf generate --template buf.gen.yaml
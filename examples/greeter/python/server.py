@@ -0,0 +1,55 @@
+#!/usr/bin/env python3
+"""Python implementation of the example Greeter plugin.
+
+Generate the stubs next to this file before running it:
+
+    python -m grpc_tools.protoc -I../greeterpb --python_out=. \
+        --grpc_python_out=. ../greeterpb/greeter.proto
+
+and load the executable script with PluginInfo{BinPath: ".../server.py"}.
+"""
+
+import os
+import sys
+import tempfile
+from concurrent import futures
+
+import grpc
+from grpc_health.v1 import health, health_pb2, health_pb2_grpc
+
+import greeter_pb2
+import greeter_pb2_grpc
+
+
+class Greeter(greeter_pb2_grpc.GreeterServicer):
+    def Greet(self, request, context):
+        return greeter_pb2.GreetResponse(message=f"Hello {request.name} from Python")
+
+
+def main():
+    if os.environ.get("GREETER_PLUGIN") != "greeter":
+        print("This program is a plugin, it is not meant to be run directly.", file=sys.stderr)
+        sys.exit(1)
+
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=4))
+    greeter_pb2_grpc.add_GreeterServicer_to_server(Greeter(), server)
+    # go-plugin health checks plugins through the "plugin" service.
+    checker = health.HealthServicer()
+    checker.set("plugin", health_pb2.HealthCheckResponse.SERVING)
+    health_pb2_grpc.add_HealthServicer_to_server(checker, server)
+
+    # The manager gives every plugin its own socket directory.
+    sock_dir = os.environ.get("PLUGIN_UNIX_SOCKET_DIR") or tempfile.mkdtemp()
+    sock = os.path.join(sock_dir, "greeter.sock")
+    server.add_insecure_port("unix:" + sock)
+    server.start()
+
+    # The handshake line tells go-plugin where to connect: core protocol
+    # version|app protocol version|network|address|protocol. Nothing else
+    # may be written to stdout before it.
+    print(f"1|1|unix|{sock}|grpc", flush=True)
+    server.wait_for_termination()
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,8 @@
+fn main() -> Result<(), Box<dyn std::error::Error>> {
+    // Builds do not depend on a protoc installed on the host.
+    std::env::set_var("PROTOC", protoc_bin_vendored::protoc_bin_path()?);
+    tonic_build::configure()
+        .build_client(false)
+        .compile_protos(&["../greeterpb/greeter.proto"], &["../greeterpb"])?;
+    Ok(())
+}
@@ -0,0 +1,61 @@
+//! Rust implementation of the example Greeter plugin.
+
+use std::env;
+use std::path::PathBuf;
+
+use tokio::net::UnixListener;
+use tokio_stream::wrappers::UnixListenerStream;
+use tonic::transport::Server;
+use tonic::{Request, Response, Status};
+
+pub mod pb {
+    tonic::include_proto!("greeter");
+}
+
+use pb::greeter_server::{Greeter, GreeterServer};
+use pb::{GreetRequest, GreetResponse};
+
+struct Impl;
+
+#[tonic::async_trait]
+impl Greeter for Impl {
+    async fn greet(&self, req: Request<GreetRequest>) -> Result<Response<GreetResponse>, Status> {
+        let name = req.into_inner().name;
+        Ok(Response::new(GreetResponse {
+            message: format!("Hello {name} from Rust"),
+        }))
+    }
+}
+
+#[tokio::main]
+async fn main() -> Result<(), Box<dyn std::error::Error>> {
+    if env::var("GREETER_PLUGIN").as_deref() != Ok("greeter") {
+        eprintln!("This program is a plugin, it is not meant to be run directly.");
+        std::process::exit(1);
+    }
+
+    // go-plugin health checks plugins through the "plugin" service.
+    let (mut reporter, health) = tonic_health::server::health_reporter();
+    reporter
+        .set_service_status("plugin", tonic_health::ServingStatus::Serving)
+        .await;
+
+    // The manager gives every plugin its own socket directory.
+    let dir = env::var_os("PLUGIN_UNIX_SOCKET_DIR")
+        .map(PathBuf::from)
+        .unwrap_or_else(env::temp_dir);
+    let sock = dir.join(format!("greeter-{}.sock", std::process::id()));
+    let listener = UnixListener::bind(&sock)?;
+
+    // The handshake line tells go-plugin where to connect: core protocol
+    // version|app protocol version|network|address|protocol. Nothing else
+    // may be written to stdout before it.
+    println!("1|1|unix|{}|grpc", sock.display());
+
+    Server::builder()
+        .add_service(health)
+        .add_service(GreeterServer::new(Impl))
+        .serve_with_incoming(UnixListenerStream::new(listener))
+        .await?;
+    Ok(())
+}
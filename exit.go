@@ -0,0 +1,131 @@
+package manager
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+const (
+	EventExited           EventType = "exited"
+	EventRestartPrevented EventType = "restart_prevented"
+)
+
+type ExitKind string
+
+const (
+	// ExitClean means the plugin exited on its own with status 0.
+	ExitClean ExitKind = "clean"
+	// ExitCrash means the plugin exited with a non-zero status or was
+	// killed by a signal the host did not send.
+	ExitCrash ExitKind = "crash"
+	// ExitUnresponsive means the plugin stopped answering health checks and
+	// was killed by the host.
+	ExitUnresponsive ExitKind = "unresponsive"
+)
+
+// ExitConfigError is the exit code, EX_CONFIG from sysexits.h, plugins
+// conventionally use to report that their configuration is broken and
+// restarting them will not help.
+const ExitConfigError = 78
+
+const (
+	InitiatedByPlugin = "plugin"
+	InitiatedByHost   = "host"
+)
+
+// ExitReason describes why a plugin process went away. Plugins stopped
+// through the manager are not reported.
+type ExitReason struct {
+	Kind ExitKind `json:"kind"`
+	// ExitCode is -1 when the process was killed by a signal or its status
+	// is unknown.
+	ExitCode    int       `json:"exitCode"`
+	Signal      string    `json:"signal,omitempty"`
+	InitiatedBy string    `json:"initiatedBy"`
+	Time        time.Time `json:"time"`
+	// Err is the health check error that revealed the exit.
+	Err string `json:"err,omitempty"`
+	// Uptime is how long the plugin ran before the exit was noticed.
+	Uptime time.Duration `json:"uptime"`
+}
+
+// Failed reports whether the exit was a failure rather than the plugin
+// finishing its work.
+func (r ExitReason) Failed() bool {
+	return r.Kind != ExitClean
+}
+
+func (r ExitReason) String() string {
+	switch {
+	case r.Kind == ExitUnresponsive:
+		return fmt.Sprintf("unresponsive, killed by host: %v", r.Err)
+	case r.Signal != "":
+		return fmt.Sprintf("%v: killed by signal %v", r.Kind, r.Signal)
+	default:
+		return fmt.Sprintf("%v: exit code %v", r.Kind, r.ExitCode)
+	}
+}
+
+// waitExited reports whether the plugin process exits within timeout on the
+// plugin's clock. The process exits in real time, so it is polled in real
+// time.
+func (p *pluginInstance[T]) waitExited(timeout time.Duration) bool {
+	if p.client.Exited() || timeout <= 0 {
+		return p.client.Exited()
+	}
+	deadline := p.clock.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(10 * time.Millisecond)
+	defer poll.Stop()
+	for !p.client.Exited() {
+		select {
+		case <-deadline.C():
+			return p.client.Exited()
+		case <-poll.C:
+		}
+	}
+	return true
+}
+
+// exitReason classifies the exit of a plugin whose health check failed. A
+// plugin that is still running after wait is considered hung and killed.
+func (p *pluginInstance[T]) exitReason(pingErr error, wait time.Duration) ExitReason {
+	reason := ExitReason{
+		ExitCode:    -1,
+		InitiatedBy: InitiatedByPlugin,
+		Time:        p.clock.Now(),
+		Uptime:      p.clock.Now().Sub(p.started),
+	}
+	if pingErr != nil {
+		reason.Err = p.Info.redact(pingErr.Error())
+	}
+
+	if !p.waitExited(wait) {
+		if !p.quit() {
+			p.client.Kill()
+		}
+		reason.Kind = ExitUnresponsive
+		reason.InitiatedBy = InitiatedByHost
+		return reason
+	}
+
+	ps := p.cmd.ProcessState
+	if ps == nil {
+		reason.Kind = ExitCrash
+		return reason
+	}
+	reason.ExitCode = ps.ExitCode()
+	if ws, ok := ps.Sys().(interface {
+		Signaled() bool
+		Signal() syscall.Signal
+	}); ok && ws.Signaled() {
+		reason.Signal = ws.Signal().String()
+	}
+	if reason.ExitCode == 0 && reason.Signal == "" {
+		reason.Kind = ExitClean
+	} else {
+		reason.Kind = ExitCrash
+	}
+	return reason
+}
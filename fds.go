@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// EventFDExhausted is published when a plugin fails to start because the
+// host ran out of file descriptors. Its Data is the FDUsage at the time.
+// It is sent to alert routes by default.
+const EventFDExhausted EventType = "fd_exhausted"
+
+// ErrFDExhausted is returned by starts failing because the host is out of
+// file descriptors, and by the starts attempted while they are paused
+// afterwards.
+var ErrFDExhausted = errors.New("host is out of file descriptors")
+
+// FDUsage is the file descriptor usage of the host and its plugins. Counts
+// are only available on Linux.
+type FDUsage struct {
+	Open  int    `json:"open"`
+	Limit uint64 `json:"limit,omitempty"`
+	// Plugins maps the keys of the running plugins to the number of file
+	// descriptors they hold.
+	Plugins map[string]int `json:"plugins,omitempty"`
+}
+
+// FDUsage returns the file descriptor usage of the host and its plugins.
+func (m *Manager[C]) FDUsage() (FDUsage, error) {
+	if m.isClosed() {
+		return FDUsage{}, ErrManagerClosed
+	}
+	return m.fdUsage()
+}
+
+func (m *Manager[C]) fdUsage() (FDUsage, error) {
+	usage, err := hostFDUsage()
+	if err != nil {
+		return usage, err
+	}
+	pids := make(map[string]int)
+	m.mu.RLock()
+	for key, p := range m.plugins {
+		if pid := p.ConnectionInfo().Pid; pid > 0 {
+			pids[key] = pid
+		}
+	}
+	m.mu.RUnlock()
+	usage.Plugins = make(map[string]int, len(pids))
+	for key, pid := range pids {
+		if n, err := processFDs(pid); err == nil {
+			usage.Plugins[key] = n
+		}
+	}
+	return usage, nil
+}
+
+func hostFDUsage() (FDUsage, error) {
+	var usage FDUsage
+	usage.Limit, _, _ = openFileLimit()
+	open, err := processFDs(os.Getpid())
+	usage.Open = open
+	return usage, err
+}
+
+func (u FDUsage) String() string {
+	s := fmt.Sprintf("%v open", u.Open)
+	if u.Limit > 0 {
+		s += fmt.Sprintf(" of %v", u.Limit)
+	}
+	return s
+}
+
+// isFDExhausted reports whether err comes from running out of file
+// descriptors. go-plugin does not always wrap the errors of the system
+// calls it makes, so their messages are matched too.
+func isFDExhausted(err error) bool {
+	if isFDErrno(err) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "too many open files") || strings.Contains(msg, "file table overflow")
+}
+
+// fdPaused fails starts while they are paused after the host ran out of
+// file descriptors.
+func (m *Manager[C]) fdPaused() error {
+	m.fdMu.Lock()
+	until := m.fdPausedUntil
+	m.fdMu.Unlock()
+	if m.config.Clock.Now().Before(until) {
+		return fmt.Errorf("%w: plugin starts paused until %v", ErrFDExhausted, until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// checkFDExhaustion turns a failure to start pm caused by running out of
+// file descriptors into ErrFDExhausted, pausing further starts for
+// FDExhaustionPause so that they do not fail in turn with obscure errors
+// and hold on to more descriptors.
+func (m *Manager[C]) checkFDExhaustion(pm PluginInfo, err error) error {
+	if err == nil || errors.Is(err, ErrFDExhausted) || !isFDExhausted(err) {
+		return err
+	}
+	until := m.config.Clock.Now().Add(m.config.FDExhaustionPause)
+	m.fdMu.Lock()
+	m.fdPausedUntil = until
+	m.fdMu.Unlock()
+
+	usage, _ := hostFDUsage()
+	m.logFor(pm).Error("host is out of file descriptors, pausing plugin starts", "usage", usage.String(), "until", until, LogKeyReason, err)
+	// Counting the descriptors of the plugins takes m.mu, which
+	// LoadPlugins holds while starting them.
+	go m.alertFDExhaustion(pm, until)
+	return fmt.Errorf("%w (%v): %w", ErrFDExhausted, usage, err)
+}
+
+func (m *Manager[C]) alertFDExhaustion(pm PluginInfo, until time.Time) {
+	usage, err := m.fdUsage()
+	if err != nil {
+		m.logFor(pm).Debug("failed to count file descriptors", LogKeyReason, err)
+	}
+	e := pluginEvent(EventFDExhausted, pm, fmt.Sprintf("%v, plugin starts paused until %v", usage, until.Format(time.RFC3339)))
+	e.Data = usage
+	m.events.publish(e)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package manager
+
+import "errors"
+
+func openFileLimit() (uint64, uint64, error) {
+	return 0, 0, errors.New("no open file limit on this platform")
+}
+
+func isFDErrno(err error) bool {
+	return false
+}
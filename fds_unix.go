@@ -0,0 +1,20 @@
+//go:build unix
+
+package manager
+
+import (
+	"errors"
+	"syscall"
+)
+
+func openFileLimit() (uint64, uint64, error) {
+	var lim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &lim); err != nil {
+		return 0, 0, err
+	}
+	return uint64(lim.Cur), uint64(lim.Max), nil
+}
+
+func isFDErrno(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
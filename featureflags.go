@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// EventFeatureFlagChanged is published when the flag of a plugin passed to
+// Reconcile flips.
+const EventFeatureFlagChanged EventType = "feature_flag_changed"
+
+// FeatureFlagProvider evaluates the flags plugins are tied to with
+// PluginInfo.FeatureFlag. The openfeature module adapts OpenFeature
+// clients.
+type FeatureFlagProvider interface {
+	Enabled(ctx context.Context, flag string, pm PluginInfo) (bool, error)
+}
+
+type FeatureFlagProviderFunc func(ctx context.Context, flag string, pm PluginInfo) (bool, error)
+
+func (f FeatureFlagProviderFunc) Enabled(ctx context.Context, flag string, pm PluginInfo) (bool, error) {
+	return f(ctx, flag, pm)
+}
+
+// FeatureFlagConfig makes Reconcile only run plugins whose flag is on, and
+// reconcile the last desired plugins again whenever a flag flips once the
+// manager is started.
+type FeatureFlagConfig struct {
+	Provider FeatureFlagProvider
+	// Interval is how often flags are evaluated, 30 seconds by default.
+	Interval time.Duration
+	// Timeout bounds each evaluation, 5 seconds by default.
+	Timeout time.Duration
+}
+
+func featureFlagDefaults(c *FeatureFlagConfig) {
+	if c.Interval == 0 {
+		c.Interval = 30 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+}
+
+// evaluateFlags returns whether the flag of each plugin in desired that has
+// one is on. Plugins whose flag cannot be evaluated keep their last known
+// state, or stay off.
+func (m *Manager[C]) evaluateFlags(desired []PluginInfo) map[string]bool {
+	c := m.config.FeatureFlags
+	m.mu.RLock()
+	last := m.flagStates
+	m.mu.RUnlock()
+
+	states := make(map[string]bool)
+	for _, pm := range desired {
+		if pm.FeatureFlag == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+		on, err := c.Provider.Enabled(ctx, pm.FeatureFlag, pm)
+		cancel()
+		if err != nil {
+			m.logFor(pm).Warn("failed to evaluate feature flag", "flag", pm.FeatureFlag, LogKeyReason, err)
+			on = last[pm.Key]
+		}
+		states[pm.Key] = on
+	}
+	return states
+}
+
+// gateFeatureFlags records desired for watchFeatureFlags and returns the
+// plugins in it whose flag is on or that have none.
+func (m *Manager[C]) gateFeatureFlags(desired []PluginInfo) []PluginInfo {
+	states := m.evaluateFlags(desired)
+	m.mu.Lock()
+	m.desired = desired
+	m.flagStates = states
+	m.mu.Unlock()
+
+	gated := make([]PluginInfo, 0, len(desired))
+	for _, pm := range desired {
+		if on, ok := states[pm.Key]; ok && !on {
+			continue
+		}
+		gated = append(gated, pm)
+	}
+	return gated
+}
+
+// watchFeatureFlags reconciles the plugins last passed to Reconcile again
+// when one of their flags flips.
+func (m *Manager[C]) watchFeatureFlags(ctx context.Context, interval time.Duration) {
+	ticker := m.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		m.mu.RLock()
+		desired, last := m.desired, m.flagStates
+		m.mu.RUnlock()
+		if desired == nil {
+			continue
+		}
+		var flipped bool
+		states := m.evaluateFlags(desired)
+		for _, pm := range desired {
+			on, ok := states[pm.Key]
+			if !ok || on == last[pm.Key] {
+				continue
+			}
+			flipped = true
+			state := "off"
+			if on {
+				state = "on"
+			}
+			m.events.publish(pluginEvent(EventFeatureFlagChanged, pm, fmt.Sprintf("flag %v is %v", pm.FeatureFlag, state)))
+		}
+		if !flipped {
+			continue
+		}
+		result, err := m.Reconcile(desired)
+		if err != nil {
+			m.config.Logger.Warn("failed to reconcile plugins after a feature flag changed", LogKeyReason, err)
+		}
+		m.config.Logger.Info("reconciled plugins after a feature flag changed",
+			"started", result.Started, "stopped", result.Stopped, "restarted", result.Restarted)
+	}
+}
@@ -0,0 +1,236 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type MirrorHealth struct {
+	Successes           int
+	Failures            int
+	ConsecutiveFailures int
+	LastError           string
+	LastFailure         time.Time
+}
+
+// Fetcher downloads plugin artifacts into an ArtifactCache. Interrupted
+// downloads are resumed with HTTP range requests, from the same or the next
+// mirror, and mirrors that keep failing are tried last.
+type Fetcher struct {
+	Cache  *ArtifactCache
+	Client *http.Client
+
+	mu       sync.Mutex
+	health   map[string]*MirrorHealth
+	inflight map[string]*fetchCall
+}
+
+// fetchCall is a download shared by concurrent fetches of one artifact.
+type fetchCall struct {
+	done chan struct{}
+	path string
+	err  error
+}
+
+func NewFetcher(cache *ArtifactCache, client *http.Client) *Fetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Fetcher{
+		Cache:  cache,
+		Client: client,
+		health: make(map[string]*MirrorHealth),
+	}
+}
+
+// Fetch returns the cached path of the artifact with the given digest,
+// downloading it from mirrors if needed.
+func (f *Fetcher) Fetch(ctx context.Context, digest string, mirrors []string) (string, error) {
+	digest, err := normalizeChecksum(digest)
+	if err != nil {
+		return "", err
+	}
+	if digest == "" {
+		return "", errors.New("fetching an artifact requires a checksum")
+	}
+	if path, ok := f.Cache.Get(digest); ok {
+		return path, nil
+	}
+	if len(mirrors) == 0 {
+		return "", fmt.Errorf("artifact %v is not cached and has no mirrors", digest)
+	}
+
+	f.mu.Lock()
+	if call, ok := f.inflight[digest]; ok {
+		f.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.path, call.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if f.inflight == nil {
+		f.inflight = make(map[string]*fetchCall)
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	f.inflight[digest] = call
+	f.mu.Unlock()
+
+	call.path, call.err = f.fetch(ctx, digest, mirrors)
+	f.mu.Lock()
+	delete(f.inflight, digest)
+	f.mu.Unlock()
+	close(call.done)
+	return call.path, call.err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, digest string, mirrors []string) (string, error) {
+	partial := filepath.Join(f.Cache.dir, ".partial-"+digest)
+	var errs []error
+	for _, mirror := range f.order(mirrors) {
+		path, err := f.fetchFrom(ctx, mirror, digest, partial)
+		f.record(mirror, err)
+		if err == nil {
+			return path, nil
+		}
+		errs = append(errs, fmt.Errorf("%v: %w", mirror, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", fmt.Errorf("fetching artifact %v: %w", digest, errors.Join(errs...))
+}
+
+// fetchFrom downloads the artifact from mirror and adds it to the cache.
+// An interrupted download is left in partial for the next mirror to
+// resume; one that does not match digest is discarded.
+func (f *Fetcher) fetchFrom(ctx context.Context, mirror, digest, partial string) (string, error) {
+	if err := f.download(ctx, mirror, partial); err != nil {
+		return "", err
+	}
+	file, err := os.Open(partial)
+	if err != nil {
+		return "", err
+	}
+	_, path, err := f.Cache.Put(file, digest)
+	file.Close()
+	os.Remove(partial)
+	return path, err
+}
+
+func (f *Fetcher) download(ctx context.Context, mirror, partial string) error {
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mirror, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server ignored the range, start over.
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file already holds the whole artifact.
+		return nil
+	default:
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	_, err = io.Copy(file, resp.Body)
+	return err
+}
+
+// order returns mirrors sorted by consecutive failures, keeping the
+// configured order among equally healthy mirrors.
+func (f *Fetcher) order(mirrors []string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ordered := append([]string(nil), mirrors...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return f.failures(ordered[i]) < f.failures(ordered[j])
+	})
+	return ordered
+}
+
+func (f *Fetcher) failures(mirror string) int {
+	if h, ok := f.health[mirrorHost(mirror)]; ok {
+		return h.ConsecutiveFailures
+	}
+	return 0
+}
+
+func (f *Fetcher) record(mirror string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	host := mirrorHost(mirror)
+	h, ok := f.health[host]
+	if !ok {
+		h = &MirrorHealth{}
+		f.health[host] = h
+	}
+	if err == nil {
+		h.Successes++
+		h.ConsecutiveFailures = 0
+		return
+	}
+	h.Failures++
+	h.ConsecutiveFailures++
+	h.LastError = err.Error()
+	h.LastFailure = time.Now()
+}
+
+// MirrorHealth returns the download history of every mirror host used so
+// far.
+func (f *Fetcher) MirrorHealth() map[string]MirrorHealth {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]MirrorHealth, len(f.health))
+	for host, h := range f.health {
+		out[host] = *h
+	}
+	return out
+}
+
+func mirrorHost(mirror string) string {
+	u, err := url.Parse(mirror)
+	if err != nil || u.Host == "" {
+		return mirror
+	}
+	return u.Host
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFetchSkipsMirrorWithBadArtifact(t *testing.T) {
+	artifact := []byte("plugin binary")
+	sum := sha256.Sum256(artifact)
+	digest := hex.EncodeToString(sum[:])
+	serve := func(body []byte) *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(body)
+		}))
+		t.Cleanup(s.Close)
+		return s
+	}
+	bad, good := serve([]byte("tampered binary")), serve(artifact)
+
+	cache, err := NewArtifactCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFetcher(cache, nil)
+	path, err := f.Fetch(context.Background(), digest, []string{bad.URL, good.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(artifact) {
+		t.Errorf("fetched %q, %v, want %q", got, err, artifact)
+	}
+
+	health := f.MirrorHealth()
+	if h := health[mirrorHost(bad.URL)]; h.Failures != 1 || h.Successes != 0 {
+		t.Errorf("bad mirror health = %+v, want one failure", h)
+	}
+	if h := health[mirrorHost(good.URL)]; h.Failures != 0 || h.Successes != 1 {
+		t.Errorf("good mirror health = %+v, want one success", h)
+	}
+}
@@ -0,0 +1,26 @@
+// Package fxmanager provides plugin managers to uber/fx applications.
+package fxmanager
+
+import (
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"go.uber.org/fx"
+)
+
+// Module provides a *manager.Manager[C] built from the *manager.ManagerConfig
+// in the application, along with it as a manager.Plugins[C]. plugins are
+// loaded when the application starts and the manager shuts down with it.
+func Module[C any](name string, plugins ...manager.PluginInfo) fx.Option {
+	return fx.Module("plugin-manager."+name,
+		fx.Provide(
+			func(lc fx.Lifecycle, config *manager.ManagerConfig) *manager.Manager[C] {
+				m := manager.NewManager[C](name, config)
+				hooks := m.Hooks(plugins)
+				lc.Append(fx.Hook{OnStart: hooks.OnStart, OnStop: hooks.OnStop})
+				return m
+			},
+			func(m *manager.Manager[C]) manager.Plugins[C] {
+				return m
+			},
+		),
+	)
+}
@@ -0,0 +1,152 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// EventGateWaiting is published when a plugin waits for a readiness gate
+	// that was not ready at first, and EventGateReady once it is.
+	EventGateWaiting EventType = "gate_waiting"
+	EventGateReady   EventType = "gate_ready"
+	EventGateTimeout EventType = "gate_timeout"
+)
+
+var ErrGateTimeout = errors.New("readiness gate timed out")
+
+// ReadinessGate is an external resource a plugin needs, such as its
+// database, which must be available before the plugin is launched. Exactly
+// one of TCP, File and HTTP is set.
+type ReadinessGate struct {
+	// TCP is a host:port that must accept connections.
+	TCP string `json:"tcp,omitempty" yaml:"tcp,omitempty"`
+	// File is a path that must exist.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// HTTP is a URL that must answer GET with 200 OK.
+	HTTP string `json:"http,omitempty" yaml:"http,omitempty"`
+	// Timeout overrides ReadinessGateConfig.Timeout.
+	Timeout Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+func (g ReadinessGate) String() string {
+	switch {
+	case g.TCP != "":
+		return "tcp " + g.TCP
+	case g.File != "":
+		return "file " + g.File
+	default:
+		return "http " + g.HTTP
+	}
+}
+
+func (g ReadinessGate) validate() error {
+	n := 0
+	for _, target := range []string{g.TCP, g.File, g.HTTP} {
+		if target != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("exactly one of tcp, file and http must be set")
+	}
+	if g.Timeout < 0 {
+		return fmt.Errorf("%v: negative timeout", g)
+	}
+	return nil
+}
+
+// ReadinessGateConfig sets how plugins wait for their
+// PluginInfo.ReadinessGates.
+type ReadinessGateConfig struct {
+	// Timeout is how long to wait for each gate, 5 minutes by default.
+	Timeout time.Duration
+	// Interval is how often gates are checked, 2 seconds by default. It
+	// also bounds each check.
+	Interval time.Duration
+}
+
+func (g ReadinessGate) check(ctx context.Context) error {
+	switch {
+	case g.TCP != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", g.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case g.File != "":
+		_, err := os.Stat(g.File)
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.HTTP, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %v", resp.Status)
+	}
+	return nil
+}
+
+// awaitGates blocks until every readiness gate of pm is ready, so plugins
+// whose dependencies are down wait for them instead of crash looping. It
+// gives up when the manager shuts down.
+func (m *Manager[C]) awaitGates(pm PluginInfo) error {
+	c := m.config.ReadinessGates
+	for _, g := range pm.ReadinessGates {
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("plugin %v: readiness gate: %w", pm.Key, err)
+		}
+		timeout := c.Timeout
+		if g.Timeout > 0 {
+			timeout = time.Duration(g.Timeout)
+		}
+		clock := m.config.Clock
+		start := clock.Now()
+		deadline := start.Add(timeout)
+		waited := false
+		for {
+			ctx, cancel := context.WithTimeout(m.ctx, c.Interval)
+			err := g.check(ctx)
+			cancel()
+			if err == nil {
+				break
+			}
+			if m.ctx.Err() != nil {
+				return ErrManagerClosed
+			}
+			if !clock.Now().Add(c.Interval).Before(deadline) {
+				msg := fmt.Sprintf("%v not ready after %v: %v", g, timeout, err)
+				m.events.publish(pluginEvent(EventGateTimeout, pm, msg))
+				return fmt.Errorf("%w: plugin %v: %v", ErrGateTimeout, pm.Key, msg)
+			}
+			if !waited {
+				waited = true
+				m.logFor(pm).Info("waiting for readiness gate", "gate", g.String(), LogKeyReason, err)
+				m.events.publish(pluginEvent(EventGateWaiting, pm, fmt.Sprintf("%v: %v", g, err)))
+			}
+			if err := sleep(m.ctx, clock, c.Interval); err != nil {
+				return ErrManagerClosed
+			}
+		}
+		if waited {
+			m.events.publish(pluginEvent(
+				EventGateReady,
+				pm,
+				fmt.Sprintf("%v ready after %v", g, clock.Now().Sub(start).Round(time.Millisecond)),
+			))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ObservabilityConfig configures GrafanaDashboard and PrometheusRules.
+type ObservabilityConfig struct {
+	// Title defaults to "Plugins".
+	Title string
+	// Datasource is the uid of the Prometheus data source. By default the
+	// dashboard lets users pick one.
+	Datasource string
+	// Namespaces restricts the dashboard and rules to the managers with
+	// these names.
+	Namespaces []string
+	// MaxErrorRatio is the ratio of failed calls that fires
+	// PluginHighErrorRate, 0.05 by default.
+	MaxErrorRatio float64
+	// MaxP99Latency is the call latency that fires PluginSlowCalls, one
+	// second by default.
+	MaxP99Latency time.Duration
+	// MaxRestarts is the number of restarts in 15 minutes that fires
+	// PluginRestarting, 3 by default.
+	MaxRestarts int
+}
+
+func (c ObservabilityConfig) withDefaults() ObservabilityConfig {
+	if c.Title == "" {
+		c.Title = "Plugins"
+	}
+	if c.MaxErrorRatio == 0 {
+		c.MaxErrorRatio = 0.05
+	}
+	if c.MaxP99Latency == 0 {
+		c.MaxP99Latency = time.Second
+	}
+	if c.MaxRestarts == 0 {
+		c.MaxRestarts = 3
+	}
+	return c
+}
+
+// namespaceMatcher returns the label matcher selecting c.Namespaces.
+func (c ObservabilityConfig) namespaceMatcher() string {
+	if len(c.Namespaces) == 0 {
+		return `namespace=~".+"`
+	}
+	quoted := make([]string, len(c.Namespaces))
+	for i, ns := range c.Namespaces {
+		quoted[i] = regexp.QuoteMeta(ns)
+	}
+	return `namespace=~"` + labelEscaper.Replace(strings.Join(quoted, "|")) + `"`
+}
+
+type grafanaPanel struct {
+	Title       string           `json:"title"`
+	Type        string           `json:"type"`
+	GridPos     map[string]int   `json:"gridPos"`
+	Datasource  map[string]any   `json:"datasource"`
+	Targets     []map[string]any `json:"targets"`
+	FieldConfig map[string]any   `json:"fieldConfig"`
+}
+
+// GrafanaDashboard returns the JSON model of a Grafana dashboard for the
+// metrics of MetricsHandler, with variables to pick namespaces and plugins.
+func GrafanaDashboard(config ObservabilityConfig) ([]byte, error) {
+	c := config.withDefaults()
+	ds := map[string]any{"type": "prometheus", "uid": "${datasource}"}
+	if c.Datasource != "" {
+		ds["uid"] = c.Datasource
+	}
+	sel := `namespace=~"$namespace",plugin=~"$plugin"`
+
+	var panels []grafanaPanel
+	add := func(title, kind, unit string, width int, exprs ...string) {
+		x, y := 0, 0
+		if n := len(panels); n > 0 {
+			last := panels[n-1].GridPos
+			x, y = last["x"]+last["w"], last["y"]
+			if x+width > 24 {
+				x, y = 0, y+last["h"]
+			}
+		}
+		p := grafanaPanel{
+			Title:       title,
+			Type:        kind,
+			GridPos:     map[string]int{"x": x, "y": y, "w": width, "h": 8},
+			Datasource:  ds,
+			FieldConfig: map[string]any{"defaults": map[string]any{"unit": unit}, "overrides": []any{}},
+		}
+		for i, expr := range exprs {
+			p.Targets = append(p.Targets, map[string]any{
+				"refId":        string(rune('A' + i)),
+				"expr":         expr,
+				"legendFormat": "{{namespace}}/{{plugin}}",
+				"datasource":   ds,
+			})
+		}
+		panels = append(panels, p)
+	}
+	add("Running plugins", "stat", "none", 6, fmt.Sprintf("sum(%v{%v})", MetricPluginUp, sel))
+	add("Quarantined", "stat", "none", 6, fmt.Sprintf("sum(%v{%v}) or vector(0)", MetricPluginQuarantined, sel))
+	add("Degraded", "stat", "none", 6, fmt.Sprintf("sum(%v{%v}) or vector(0)", MetricPluginDegraded, sel))
+	add("Restarts (15m)", "stat", "none", 6, fmt.Sprintf("sum(increase(%v{%v}[15m]))", MetricPluginRestarts, sel))
+	add("Call rate", "timeseries", "reqps", 12, fmt.Sprintf("sum by (namespace, plugin) (rate(%v{%v}[$__rate_interval]))", MetricCalls, sel))
+	add("Error ratio", "timeseries", "percentunit", 12, fmt.Sprintf(
+		"sum by (namespace, plugin) (rate(%v{%v}[$__rate_interval])) / sum by (namespace, plugin) (rate(%v{%v}[$__rate_interval]))",
+		MetricCallErrors, sel, MetricCalls, sel))
+	add("Call latency p99", "timeseries", "s", 12, fmt.Sprintf(`max by (namespace, plugin) (%v{%v,quantile="0.99"})`, MetricCallLatency, sel))
+	add("Health check latency", "timeseries", "s", 12, fmt.Sprintf("%v{%v}", MetricPingSeconds, sel))
+	add("CPU", "timeseries", "percentunit", 12, fmt.Sprintf("rate(%v{%v}[$__rate_interval])", MetricCPUSeconds, sel))
+	add("Resident memory", "timeseries", "bytes", 12, fmt.Sprintf("%v{%v}", MetricRSSBytes, sel))
+	add("Restarts", "timeseries", "none", 24, fmt.Sprintf("increase(%v{%v}[$__rate_interval])", MetricPluginRestarts, sel))
+
+	variable := func(name, query string) map[string]any {
+		return map[string]any{
+			"name":       name,
+			"type":       "query",
+			"datasource": ds,
+			"query":      map[string]any{"query": query, "refId": name},
+			"refresh":    2,
+			"multi":      true,
+			"includeAll": true,
+			"current":    map[string]any{"text": "All", "value": "$__all"},
+		}
+	}
+	vars := []map[string]any{
+		variable("namespace", fmt.Sprintf("label_values(%v{%v}, namespace)", MetricPluginUp, c.namespaceMatcher())),
+		variable("plugin", fmt.Sprintf(`label_values(%v{namespace=~"$namespace"}, plugin)`, MetricPluginUp)),
+	}
+	if c.Datasource == "" {
+		vars = append([]map[string]any{{"name": "datasource", "type": "datasource", "query": "prometheus"}}, vars...)
+	}
+
+	return indentJSON(map[string]any{
+		"title":         c.Title,
+		"uid":           "plugin-manager",
+		"tags":          []string{"plugins"},
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]string{"from": "now-6h", "to": "now"},
+		"templating":    map[string]any{"list": vars},
+		"panels":        panels,
+	})
+}
+
+type alertRule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// PrometheusRules returns a Prometheus rule file alerting on quarantined,
+// degraded, restarting, failing and slow plugins. It is JSON, which
+// Prometheus reads as YAML.
+func PrometheusRules(config ObservabilityConfig) ([]byte, error) {
+	c := config.withDefaults()
+	sel := c.namespaceMatcher()
+	rule := func(name, expr, forDuration, severity, summary string) alertRule {
+		return alertRule{
+			Alert:  name,
+			Expr:   expr,
+			For:    forDuration,
+			Labels: map[string]string{"severity": severity},
+			Annotations: map[string]string{
+				"summary": "Plugin {{ $labels.plugin }} in {{ $labels.namespace }} " + summary,
+			},
+		}
+	}
+	rules := []alertRule{
+		rule("PluginQuarantined",
+			fmt.Sprintf("%v{%v} == 1", MetricPluginQuarantined, sel),
+			"", "critical", "is quarantined after crash looping"),
+		rule("PluginRestarting",
+			fmt.Sprintf("increase(%v{%v}[15m]) > %v", MetricPluginRestarts, sel, c.MaxRestarts),
+			"", "warning", "restarted more than "+strconv.Itoa(c.MaxRestarts)+" times in 15 minutes"),
+		rule("PluginDegraded",
+			fmt.Sprintf("%v{%v} == 1", MetricPluginDegraded, sel),
+			"5m", "warning", "answers health checks slowly"),
+		rule("PluginHighErrorRate",
+			fmt.Sprintf("sum by (namespace, plugin) (rate(%v{%v}[5m])) / sum by (namespace, plugin) (rate(%v{%v}[5m])) > %v",
+				MetricCallErrors, sel, MetricCalls, sel, c.MaxErrorRatio),
+			"10m", "warning", fmt.Sprintf("fails more than %v%% of calls", c.MaxErrorRatio*100)),
+		rule("PluginSlowCalls",
+			fmt.Sprintf(`max by (namespace, plugin) (%v{%v,quantile="0.99"}) > %v`, MetricCallLatency, sel, c.MaxP99Latency.Seconds()),
+			"10m", "warning", "has a p99 call latency above "+c.MaxP99Latency.String()),
+	}
+	return indentJSON(map[string]any{
+		"groups": []map[string]any{{"name": "plugin-manager", "rules": rules}},
+	})
+}
+
+// indentJSON is json.MarshalIndent without escaping <, > and &, which are
+// common in PromQL.
+func indentJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
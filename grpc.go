@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+// GRPCOptions tune the connection to a gRPC plugin.
+type GRPCOptions struct {
+	// MaxSendMsgSize and MaxRecvMsgSize limit the size of messages, in
+	// bytes. go-plugin allows up to 2GB by default.
+	MaxSendMsgSize int `json:"maxSendMsgSize,omitempty" yaml:"maxSendMsgSize,omitempty"`
+	MaxRecvMsgSize int `json:"maxRecvMsgSize,omitempty" yaml:"maxRecvMsgSize,omitempty"`
+	// Compression names the compressor requests are sent with, such as
+	// "gzip". Other compressors, like zstd, must be registered with
+	// encoding.RegisterCompressor by both the host and the plugin.
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+	// Keepalive pings the plugin after KeepaliveTime without activity and
+	// closes the connection if no answer arrives within KeepaliveTimeout.
+	KeepaliveTime                Duration `json:"keepaliveTime,omitempty" yaml:"keepaliveTime,omitempty"`
+	KeepaliveTimeout             Duration `json:"keepaliveTimeout,omitempty" yaml:"keepaliveTimeout,omitempty"`
+	KeepalivePermitWithoutStream bool     `json:"keepalivePermitWithoutStream,omitempty" yaml:"keepalivePermitWithoutStream,omitempty"`
+}
+
+func (o *GRPCOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.Compression != "" && encoding.GetCompressor(o.Compression) == nil {
+		return fmt.Errorf("compressor %q is not registered", o.Compression)
+	}
+	if o.MaxSendMsgSize < 0 || o.MaxRecvMsgSize < 0 {
+		return errors.New("negative message size")
+	}
+	return nil
+}
+
+// grpcDialOptions returns the options applied to the connection of gRPC
+// plugins. Deadlines and cancellation of the context passed to a gRPC stub
+// are propagated to the plugin by gRPC itself; the interceptors bound calls
+// made without a deadline by DefaultCallTimeout.
+func (m *Manager[C]) grpcDialOptions(pm PluginInfo) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if m.config.DefaultCallTimeout > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(m.unaryTimeout))
+	}
+	o := pm.GRPC
+	if o == nil {
+		return opts
+	}
+	// go-plugin sets its default message sizes before these options.
+	if o.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(o.MaxSendMsgSize)))
+	}
+	if o.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(o.MaxRecvMsgSize)))
+	}
+	if o.Compression != "" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(o.Compression)))
+	}
+	if o.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                time.Duration(o.KeepaliveTime),
+			Timeout:             time.Duration(o.KeepaliveTimeout),
+			PermitWithoutStream: o.KeepalivePermitWithoutStream,
+		}))
+	}
+	return opts
+}
+
+func (m *Manager[C]) unaryTimeout(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.config.DefaultCallTimeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+)
+
+// Handle is a long lived reference to a plugin that always resolves to the
+// live instance, so it keeps working after the plugin is restarted. Go
+// cannot implement C at runtime through reflection, so callers resolve the
+// implementation per use with Impl or Call instead of holding on to it, or
+// hold on to a proxy implementing C through Call, generated with
+// cmd/plugingen -kind proxy.
+type Handle[C any] struct {
+	m   *Manager[C]
+	key string
+}
+
+func (m *Manager[C]) Handle(pluginKey string) *Handle[C] {
+	return &Handle[C]{m: m, key: pluginKey}
+}
+
+func (h *Handle[C]) Key() string {
+	return h.key
+}
+
+// Generation returns the generation of the live instance. Callers caching
+// anything tied to an instance can compare it to detect restarts.
+func (h *Handle[C]) Generation() (uint64, error) {
+	if h.m.isClosed() {
+		return 0, ErrManagerClosed
+	}
+	p, ok := h.m.getPlugin(h.key)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrPluginNotFound, h.key)
+	}
+	return p.Info.Generation, nil
+}
+
+// Impl returns the implementation of the live instance.
+func (h *Handle[C]) Impl() (C, error) {
+	return h.m.GetPlugin(h.key)
+}
+
+func (h *Handle[C]) Call(ctx context.Context, method string, fn func(context.Context, C) error) error {
+	return h.m.Call(ctx, h.key, method, fn)
+}
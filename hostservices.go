@@ -0,0 +1,237 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Permission grants a plugin access to host services. Permissions are
+// namespaced with colons and may be granted with wildcards, e.g. "host:kv:*".
+type Permission string
+
+const (
+	PermNetOutbound Permission = "net:outbound"
+	PermKVRead      Permission = "host:kv:read"
+	PermKVWrite     Permission = "host:kv:write"
+	PermSecretsRead Permission = "secrets:read"
+)
+
+// HostServicesEnv holds the address of the host services broker in the
+// environment of plugins started while host services are registered.
+const HostServicesEnv = "PLUGIN_HOST_SERVICES"
+
+const EventPermissionDenied EventType = "permission_denied"
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// HostServiceFunc handles a call made by a plugin to a host service. caller
+// describes the plugin instance making the call.
+type HostServiceFunc func(ctx context.Context, caller PluginInfo, req []byte) ([]byte, error)
+
+type hostService struct {
+	perm Permission
+	fn   HostServiceFunc
+}
+
+// RegisterHostService makes a service available to plugins started after
+// the call. Only plugins granted perm may call it; perm may be empty for
+// services every plugin can use.
+func (m *Manager[C]) RegisterHostService(name string, perm Permission, fn HostServiceFunc) {
+	m.hostMu.Lock()
+	defer m.hostMu.Unlock()
+	if m.hostServices == nil {
+		m.hostServices = make(map[string]hostService)
+	}
+	m.hostServices[name] = hostService{perm: perm, fn: fn}
+}
+
+func (m *Manager[C]) hostService(name string) (hostService, bool) {
+	m.hostMu.RLock()
+	defer m.hostMu.RUnlock()
+	s, ok := m.hostServices[name]
+	return s, ok
+}
+
+func (m *Manager[C]) hasHostServices() bool {
+	m.hostMu.RLock()
+	defer m.hostMu.RUnlock()
+	return len(m.hostServices) > 0
+}
+
+// Granted reports whether the plugin was granted perm.
+func (pm PluginInfo) Granted(perm Permission) bool {
+	for _, p := range pm.Permissions {
+		if ok, _ := path.Match(string(p), string(perm)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hostBroker serves host services to a single plugin instance over a unix
+// socket, so every call is attributed to the instance the socket was
+// created for.
+type hostBroker[C any] struct {
+	m      *Manager[C]
+	dir    string
+	addr   string
+	server *http.Server
+	// instance and token identify the plugin instance when
+	// ManagerConfig.IdentityTokens is set.
+	instance string
+	token    string
+
+	mu   sync.RWMutex
+	info PluginInfo
+}
+
+func (m *Manager[C]) startHostBroker(root string, info PluginInfo) (*hostBroker[C], error) {
+	dir, err := os.MkdirTemp(root, "plugin-host-")
+	if err != nil {
+		return nil, err
+	}
+	addr := filepath.Join(dir, "host.sock")
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	b := &hostBroker[C]{m: m, dir: dir, addr: addr, info: info}
+	if config := m.config.IdentityTokens; config != nil {
+		if b.instance, err = newInstanceID(); err == nil {
+			b.token, err = config.sign(info.Key, b.instance)
+		}
+		if err != nil {
+			l.Close()
+			os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	b.server = &http.Server{Handler: b}
+	go b.server.Serve(l)
+	return b, nil
+}
+
+func (b *hostBroker[C]) setInfo(info PluginInfo) {
+	b.mu.Lock()
+	b.info = info
+	b.mu.Unlock()
+}
+
+func (b *hostBroker[C]) caller() PluginInfo {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.info
+}
+
+func (b *hostBroker[C]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	caller := b.caller()
+	if err := b.authenticate(r, caller); err != nil {
+		b.m.logFor(caller).Warn("rejected host service call", "service", name, LogKeyReason, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if name == identityRefresh {
+		b.refreshIdentity(w, caller)
+		return
+	}
+
+	s, ok := b.m.hostService(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("host service %v not found", name), http.StatusNotFound)
+		return
+	}
+	if s.perm != "" && !caller.Granted(s.perm) {
+		msg := fmt.Sprintf("host service %v requires permission %v", name, s.perm)
+		b.m.logFor(caller).Warn("rejected host service call", "service", name, "permission", s.perm)
+		e := pluginEvent(EventPermissionDenied, caller, msg)
+		e.Data = s.perm
+		b.m.events.publish(e)
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+
+	req, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp, err := s.fn(r.Context(), caller, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(resp)
+}
+
+func (b *hostBroker[C]) Close() {
+	b.server.Close()
+	os.RemoveAll(b.dir)
+}
+
+// CallHostService is used by plugins to call a service registered with
+// RegisterHostService on the host that started them.
+func CallHostService(ctx context.Context, name string, req []byte) ([]byte, error) {
+	token := identityToken(ctx, func(ctx context.Context, token string) ([]byte, error) {
+		return callHostService(ctx, identityRefresh, token, nil)
+	})
+	return callHostService(ctx, name, token, req)
+}
+
+func callHostService(ctx context.Context, name, token string, req []byte) ([]byte, error) {
+	addr := os.Getenv(HostServicesEnv)
+	if addr == "" {
+		return nil, fmt.Errorf("host services are not available")
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		},
+	}}
+	defer client.CloseIdleConnections()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://host/"+name, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, strings.TrimSpace(string(body)))
+	case http.StatusUnauthorized:
+		msg := strings.TrimPrefix(strings.TrimSpace(string(body)), ErrInvalidIdentity.Error())
+		return nil, fmt.Errorf("%w%s", ErrInvalidIdentity, msg)
+	default:
+		return nil, fmt.Errorf("host service %v: %s", name, strings.TrimSpace(string(body)))
+	}
+}
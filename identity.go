@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdentityTokenEnv holds the identity token of plugins started while
+// ManagerConfig.IdentityTokens is set.
+const IdentityTokenEnv = "PLUGIN_IDENTITY_TOKEN"
+
+// identityRefresh is handled by the host service broker itself to renew a
+// plugin's token before it expires.
+const identityRefresh = "identity.refresh"
+
+var ErrInvalidIdentity = errors.New("invalid plugin identity token")
+
+// IdentityTokenConfig makes every plugin present a token signed by the
+// manager when it calls host services. Tokens are bound to the plugin key
+// and instance, so a plugin cannot call in the name of another, and expire
+// after TTL unless the plugin renews them, which CallHostService does.
+type IdentityTokenConfig struct {
+	// Key signs the tokens with HMAC-SHA256. Defaults to a random key.
+	Key []byte
+	// TTL defaults to 15 minutes.
+	TTL time.Duration
+}
+
+type identityClaims struct {
+	Key      string `json:"key"`
+	Instance string `json:"instance"`
+	Issued   int64  `json:"iat"`
+	Expires  int64  `json:"exp"`
+}
+
+func newInstanceID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (c *IdentityTokenConfig) sign(key, instance string) (string, error) {
+	now := time.Now()
+	payload, err := json.Marshal(identityClaims{Key: key, Instance: instance, Issued: now.Unix(), Expires: now.Add(c.TTL).Unix()})
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write(payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verify checks the token was signed by the manager for the instance and
+// has not expired.
+func (c *IdentityTokenConfig) verify(token, key, instance string) error {
+	enc := base64.RawURLEncoding
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidIdentity
+	}
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return ErrInvalidIdentity
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil {
+		return ErrInvalidIdentity
+	}
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrInvalidIdentity
+	}
+	var claims identityClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ErrInvalidIdentity
+	}
+	if claims.Key != key || claims.Instance != instance {
+		return fmt.Errorf("%w: issued to another plugin instance", ErrInvalidIdentity)
+	}
+	if time.Now().Unix() >= claims.Expires {
+		return fmt.Errorf("%w: expired", ErrInvalidIdentity)
+	}
+	return nil
+}
+
+// authenticate checks the token presented with a host service call.
+func (b *hostBroker[C]) authenticate(r *http.Request, caller PluginInfo) error {
+	config := b.m.config.IdentityTokens
+	if config == nil {
+		return nil
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return fmt.Errorf("%w: missing", ErrInvalidIdentity)
+	}
+	return config.verify(token, caller.Key, b.instance)
+}
+
+// refreshIdentity handles identityRefresh, issuing a new token to a caller
+// that authenticated with its current one.
+func (b *hostBroker[C]) refreshIdentity(w http.ResponseWriter, caller PluginInfo) {
+	config := b.m.config.IdentityTokens
+	if config == nil {
+		http.Error(w, "identity tokens are not enabled", http.StatusNotFound)
+		return
+	}
+	token, err := config.sign(caller.Key, b.instance)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(token))
+}
+
+// pluginToken is the identity token of the plugin process.
+var pluginToken struct {
+	mu     sync.Mutex
+	loaded bool
+	token  string
+	claims identityClaims
+}
+
+func parseClaims(token string) identityClaims {
+	var claims identityClaims
+	payloadPart, _, _ := strings.Cut(token, ".")
+	if payload, err := base64.RawURLEncoding.DecodeString(payloadPart); err == nil {
+		json.Unmarshal(payload, &claims)
+	}
+	return claims
+}
+
+// identityToken returns the plugin's token, renewing it with refresh once
+// two thirds of its lifetime have passed.
+func identityToken(ctx context.Context, refresh func(ctx context.Context, token string) ([]byte, error)) string {
+	pluginToken.mu.Lock()
+	defer pluginToken.mu.Unlock()
+	if !pluginToken.loaded {
+		pluginToken.token = os.Getenv(IdentityTokenEnv)
+		pluginToken.claims = parseClaims(pluginToken.token)
+		pluginToken.loaded = true
+	}
+	c := pluginToken.claims
+	if pluginToken.token == "" || time.Now().Unix() < c.Issued+(c.Expires-c.Issued)*2/3 {
+		return pluginToken.token
+	}
+	// On failure the current token is used until it expires.
+	if token, err := refresh(ctx, pluginToken.token); err == nil {
+		pluginToken.token = string(token)
+		pluginToken.claims = parseClaims(pluginToken.token)
+	}
+	return pluginToken.token
+}
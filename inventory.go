@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"crypto/rand"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InventoryFormat is the document format of InventoryReport.
+type InventoryFormat string
+
+const (
+	// InventoryCycloneDX is a CycloneDX 1.5 JSON BOM.
+	InventoryCycloneDX InventoryFormat = "cyclonedx"
+	// InventorySPDX is an SPDX 2.3 JSON document.
+	InventorySPDX InventoryFormat = "spdx"
+)
+
+// Signature statuses of InventoryItem.
+const (
+	SignatureVerified = "verified"
+	// SignatureUnsigned plugins have provenance whose envelope was not
+	// verified.
+	SignatureUnsigned = "unsigned"
+	// SignatureNone plugins have no provenance.
+	SignatureNone = "none"
+)
+
+// InventoryItem describes the binary a running plugin was started from.
+type InventoryItem struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// Checksum is the hex sha256 of the binary.
+	Checksum  string `json:"checksum"`
+	Signature string `json:"signature"`
+	// SourceURL is the source of the provenance, or the first mirror.
+	SourceURL string `json:"sourceUrl,omitempty"`
+	Builder   string `json:"builder,omitempty"`
+}
+
+// Inventory lists the running plugins sorted by key.
+func (m *Manager[C]) Inventory() ([]InventoryItem, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrManagerClosed
+	}
+	items := make([]InventoryItem, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		item := InventoryItem{
+			Key:       p.Info.Key,
+			Name:      filepath.Base(p.Info.BinPath),
+			Version:   p.Info.Version,
+			Checksum:  p.digest,
+			Signature: SignatureNone,
+		}
+		if len(p.Info.Mirrors) > 0 {
+			item.SourceURL = p.Info.Mirrors[0]
+		}
+		if p.prov != nil {
+			item.Signature = SignatureUnsigned
+			if p.prov.Signed {
+				item.Signature = SignatureVerified
+			}
+			if p.prov.Subject != "" {
+				item.Name = p.prov.Subject
+			}
+			if p.prov.SourceURI != "" {
+				item.SourceURL = p.prov.SourceURI
+			}
+			item.Builder = p.prov.BuilderID
+		}
+		items = append(items, item)
+	}
+	m.mu.Unlock()
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items, nil
+}
+
+// InventoryReport returns the Inventory as a CycloneDX or SPDX JSON
+// document for compliance and vulnerability scanning tools. The plugin key,
+// signature status and builder are recorded as properties in CycloneDX and
+// as comments in SPDX.
+func (m *Manager[C]) InventoryReport(format InventoryFormat) ([]byte, error) {
+	items, err := m.Inventory()
+	if err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	switch format {
+	case InventoryCycloneDX, "":
+		return indentJSON(m.cycloneDX(items, id, now))
+	case InventorySPDX:
+		return indentJSON(m.spdx(items, id, now))
+	}
+	return nil, fmt.Errorf("unsupported inventory format %q", format)
+}
+
+func (m *Manager[C]) cycloneDX(items []InventoryItem, id, now string) map[string]any {
+	components := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		c := map[string]any{
+			"type":    "application",
+			"bom-ref": item.Key,
+			"name":    item.Name,
+			"hashes":  []map[string]string{{"alg": "SHA-256", "content": item.Checksum}},
+			"properties": []map[string]string{
+				{"name": "plugin-manager:key", "value": item.Key},
+				{"name": "plugin-manager:signature", "value": item.Signature},
+			},
+		}
+		if item.Version != "" {
+			c["version"] = item.Version
+		}
+		if item.SourceURL != "" {
+			c["externalReferences"] = []map[string]string{{"type": "distribution", "url": item.SourceURL}}
+		}
+		if item.Builder != "" {
+			c["properties"] = append(c["properties"].([]map[string]string), map[string]string{"name": "plugin-manager:builder", "value": item.Builder})
+		}
+		components = append(components, c)
+	}
+	return map[string]any{
+		"bomFormat":    "CycloneDX",
+		"specVersion":  "1.5",
+		"serialNumber": "urn:uuid:" + id,
+		"version":      1,
+		"metadata": map[string]any{
+			"timestamp": now,
+			"tools":     map[string]any{"components": []map[string]string{{"type": "application", "name": "go-plugin-manager"}}},
+			"component": map[string]string{"type": "application", "bom-ref": m.Name, "name": m.Name},
+		},
+		"components": components,
+	}
+}
+
+func (m *Manager[C]) spdx(items []InventoryItem, id, now string) map[string]any {
+	packages := make([]map[string]any, 0, len(items))
+	relationships := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		ref := "SPDXRef-Plugin-" + spdxID(item.Key)
+		download := item.SourceURL
+		if download == "" {
+			download = "NOASSERTION"
+		}
+		comment := "plugin key: " + item.Key + "; signature: " + item.Signature
+		if item.Builder != "" {
+			comment += "; builder: " + item.Builder
+		}
+		p := map[string]any{
+			"name":             item.Name,
+			"SPDXID":           ref,
+			"downloadLocation": download,
+			"filesAnalyzed":    false,
+			"checksums":        []map[string]string{{"algorithm": "SHA256", "checksumValue": item.Checksum}},
+			"comment":          comment,
+		}
+		if item.Version != "" {
+			p["versionInfo"] = item.Version
+		}
+		packages = append(packages, p)
+		relationships = append(relationships, map[string]string{
+			"spdxElementId":      "SPDXRef-DOCUMENT",
+			"relationshipType":   "DESCRIBES",
+			"relatedSpdxElement": ref,
+		})
+	}
+	return map[string]any{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              m.Name + " plugins",
+		"documentNamespace": "https://spdx.org/spdxdocs/plugin-manager-" + m.Name + "-" + id,
+		"creationInfo": map[string]any{
+			"created":  now,
+			"creators": []string{"Tool: go-plugin-manager"},
+		},
+		"packages":      packages,
+		"relationships": relationships,
+	}
+}
+
+// spdxID replaces the characters SPDX identifiers do not allow.
+func spdxID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,188 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socketRootOwner holds the pid of the manager owning a socket root, so
+// the janitor of another manager can tell whether it is still in use.
+const socketRootOwner = "manager.pid"
+
+// JanitorConfig removes files left behind by plugins that no longer exist
+// and by managers that exited without shutting down: socket directories,
+// staged binaries, payload files and old crash dumps. It runs when the
+// manager starts and then every Interval, if set.
+type JanitorConfig struct {
+	Interval time.Duration
+	// CrashRetention is how long crash dumps are kept. Zero keeps them.
+	CrashRetention time.Duration
+}
+
+// CleanupReport lists the paths removed by CollectGarbage.
+type CleanupReport struct {
+	Removed []string `json:"removed"`
+}
+
+// CollectGarbage runs the janitor once. Files it fails to remove are
+// reported in the error and retried on the next run.
+func (m *Manager[C]) CollectGarbage() (CleanupReport, error) {
+	if m.isClosed() {
+		return CleanupReport{}, ErrManagerClosed
+	}
+	var r CleanupReport
+	var errs []error
+	remove := func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		r.Removed = append(r.Removed, path)
+	}
+
+	m.collectSocketRoots(remove)
+	m.collectStaged(remove)
+	m.collectPayloads(remove)
+	if m.config.CrashDumps != nil && m.config.Janitor != nil && m.config.Janitor.CrashRetention > 0 {
+		m.collectCrashDumps(m.config.Janitor.CrashRetention, remove)
+	}
+	if len(r.Removed) > 0 {
+		m.config.Logger.Info("removed stale plugin files", "count", len(r.Removed))
+	}
+	return r, errors.Join(errs...)
+}
+
+func (m *Manager[C]) janitor(ctx context.Context, interval time.Duration) {
+	if _, err := m.CollectGarbage(); err != nil {
+		m.config.Logger.Warn("failed to remove stale plugin files", LogKeyReason, err)
+	}
+	if interval <= 0 {
+		return
+	}
+	ticker := m.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := m.CollectGarbage(); err != nil {
+				m.config.Logger.Warn("failed to remove stale plugin files", LogKeyReason, err)
+			}
+		}
+	}
+}
+
+// loadedKeys returns the keys of the plugins currently loaded or being
+// deployed.
+func (m *Manager[C]) loadedKeys() map[string]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make(map[string]bool, len(m.plugins)+len(m.deploys))
+	for key := range m.plugins {
+		keys[key] = true
+	}
+	for key := range m.deploys {
+		keys[key] = true
+	}
+	return keys
+}
+
+// collectSocketRoots removes the socket roots of managers that are no
+// longer running.
+func (m *Manager[C]) collectSocketRoots(remove func(string)) {
+	parent := m.config.SocketDir
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	dirs, _ := filepath.Glob(filepath.Join(parent, "plugin-manager-*"))
+	for _, dir := range dirs {
+		b, err := os.ReadFile(filepath.Join(dir, socketRootOwner))
+		if err != nil {
+			// Not ours to judge.
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err == nil && pid != os.Getpid() && !processAlive(pid) {
+			remove(dir)
+		}
+	}
+}
+
+// collectStaged removes staged binaries no longer in use and forgets those
+// of plugins that are not loaded. Binaries run by instances, including
+// those of tasks and standbys, are kept until the instances stop.
+func (m *Manager[C]) collectStaged(remove func(string)) {
+	loaded := m.loadedKeys()
+	m.stageMu.Lock()
+	defer m.stageMu.Unlock()
+	keep := make(map[string]bool, len(m.staged)+len(m.stagedUse))
+	for key, s := range m.staged {
+		if !loaded[key] {
+			delete(m.staged, key)
+			continue
+		}
+		keep[s.path] = true
+	}
+	for path := range m.stagedUse {
+		keep[path] = true
+	}
+
+	root := filepath.Join(m.config.DataDir, "run")
+	dirs, _ := os.ReadDir(root)
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.Name())
+		files, _ := os.ReadDir(path)
+		kept := 0
+		for _, f := range files {
+			if file := filepath.Join(path, f.Name()); keep[file] {
+				kept++
+			} else {
+				remove(file)
+			}
+		}
+		if kept == 0 {
+			remove(path)
+		}
+	}
+}
+
+// collectPayloads removes payload files left by calls of previous runs.
+func (m *Manager[C]) collectPayloads(remove func(string)) {
+	root := filepath.Join(m.config.DataDir, "payloads")
+	files, _ := filepath.Glob(filepath.Join(root, "*", "*"))
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil && fi.ModTime().Before(m.created) {
+			remove(file)
+		}
+	}
+}
+
+// collectCrashDumps removes crash dumps older than retention, and the dump
+// directories left empty of plugins that are not loaded.
+func (m *Manager[C]) collectCrashDumps(retention time.Duration, remove func(string)) {
+	loaded := m.loadedKeys()
+	root := m.config.CrashDumps.Dir
+	dirs, _ := os.ReadDir(root)
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.Name())
+		files, _ := os.ReadDir(path)
+		left := len(files)
+		for _, f := range files {
+			if fi, err := f.Info(); err == nil && time.Since(fi.ModTime()) > retention {
+				remove(filepath.Join(path, f.Name()))
+				left--
+			}
+		}
+		key, err := url.PathUnescape(dir.Name())
+		if left == 0 && err == nil && !loaded[key] {
+			remove(path)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollectGarbageKeepsStagedBinariesInUse(t *testing.T) {
+	m := newTestManager(t, ManagerConfig{DataDir: t.TempDir(), StageBinaries: true})
+
+	var staged string
+	_, err := RunPluginTask(context.Background(), m, testPluginInfo(t, "task"), func(_ context.Context, g testGreeter) (string, error) {
+		m.stageMu.Lock()
+		staged = m.staged["task"].path
+		m.stageMu.Unlock()
+		if _, err := m.CollectGarbage(); err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(staged); err != nil {
+			t.Errorf("staged binary of a running task was collected: %v", err)
+		}
+		return g.Greet()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.CollectGarbage(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(staged); !os.IsNotExist(err) {
+		t.Errorf("staged binary of a finished task was kept: %v", err)
+	}
+}
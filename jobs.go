@@ -0,0 +1,235 @@
+package manager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const EventJobDeadLettered EventType = "job_dead_lettered"
+
+var ErrQueueFull = errors.New("job queue is full")
+
+type Job struct {
+	ID         string
+	PluginKey  string
+	Payload    []byte
+	Attempts   int
+	LastError  string
+	EnqueuedAt time.Time
+}
+
+// JobStore persists jobs so they survive host restarts. Save is called when
+// a job is enqueued and after every failed attempt, Delete once it
+// succeeded and DeadLetter when it ran out of attempts.
+type JobStore interface {
+	Save(job Job) error
+	Delete(id string) error
+	DeadLetter(job Job) error
+	// Pending returns the jobs to resume when the queue starts.
+	Pending() ([]Job, error)
+}
+
+type JobQueueOptions struct {
+	// Workers is the number of jobs processed at the same time. Defaults
+	// to 1.
+	Workers int
+	// QueueSize bounds the number of jobs waiting for a worker. Defaults
+	// to 1024.
+	QueueSize int
+	// MaxAttempts is the number of times a job is tried before it is
+	// dead-lettered. Defaults to 5.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on every
+	// further attempt. Defaults to one second.
+	Backoff time.Duration
+	Store   JobStore
+}
+
+// JobQueue dispatches jobs to plugins with at-least-once semantics: a job is
+// retried until its handler succeeds or it runs out of attempts.
+type JobQueue[C any] struct {
+	m       *Manager[C]
+	handler func(context.Context, C, Job) error
+	opts    JobQueueOptions
+	jobs    chan Job
+
+	mu     sync.Mutex
+	dead   []Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewJobQueue[C any](
+	m *Manager[C],
+	handler func(context.Context, C, Job) error,
+	opts JobQueueOptions,
+) *JobQueue[C] {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JobQueue[C]{
+		m:       m,
+		handler: handler,
+		opts:    opts,
+		jobs:    make(chan Job, opts.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start resumes pending jobs from the store and launches the workers.
+func (q *JobQueue[C]) Start() error {
+	if q.opts.Store != nil {
+		pending, err := q.opts.Store.Pending()
+		if err != nil {
+			return err
+		}
+		for _, job := range pending {
+			if err := q.push(job); err != nil {
+				return err
+			}
+		}
+	}
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return nil
+}
+
+// Stop stops the workers after their current job. Jobs not yet processed
+// stay in the store.
+func (q *JobQueue[C]) Stop() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *JobQueue[C]) Enqueue(pluginKey string, payload []byte) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	job := Job{
+		ID:         hex.EncodeToString(id),
+		PluginKey:  pluginKey,
+		Payload:    payload,
+		EnqueuedAt: time.Now(),
+	}
+	// The job is saved before it is pushed, since a worker may finish and
+	// delete it before a later save, and deleted again when it cannot be
+	// queued so that it is not resumed after being refused.
+	if q.opts.Store != nil {
+		if err := q.opts.Store.Save(job); err != nil {
+			return "", err
+		}
+	}
+	if err := q.push(job); err != nil {
+		if q.opts.Store != nil {
+			if derr := q.opts.Store.Delete(job.ID); derr != nil {
+				return "", errors.Join(err, derr)
+			}
+		}
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// DeadLetters returns the jobs that ran out of attempts.
+func (q *JobQueue[C]) DeadLetters() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Job(nil), q.dead...)
+}
+
+func (q *JobQueue[C]) push(job Job) error {
+	select {
+	case q.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *JobQueue[C]) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case job := <-q.jobs:
+			q.process(job)
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+func (q *JobQueue[C]) process(job Job) {
+	job.Attempts++
+	err := q.dispatch(job)
+	if err == nil {
+		if q.opts.Store != nil {
+			q.opts.Store.Delete(job.ID)
+		}
+		return
+	}
+
+	job.LastError = err.Error()
+	if job.Attempts >= q.opts.MaxAttempts {
+		q.deadLetter(job)
+		return
+	}
+	if q.opts.Store != nil {
+		q.opts.Store.Save(job)
+	}
+
+	delay := q.opts.Backoff << (job.Attempts - 1)
+	q.m.config.Clock.AfterFunc(delay, func() {
+		if q.ctx.Err() != nil {
+			return
+		}
+		if err := q.push(job); err != nil {
+			job.LastError = err.Error()
+			q.deadLetter(job)
+		}
+	})
+}
+
+// dispatch hands the job to the plugin, refusing plugins that are degraded
+// so the job is retried once they are healthy again.
+func (q *JobQueue[C]) dispatch(job Job) error {
+	if stats, err := q.m.PingStats(job.PluginKey); err == nil && stats.Degraded {
+		return fmt.Errorf("plugin %v is degraded", job.PluginKey)
+	}
+	return q.m.Call(q.ctx, job.PluginKey, "job", func(ctx context.Context, impl C) error {
+		return q.handler(ctx, impl, job)
+	})
+}
+
+func (q *JobQueue[C]) deadLetter(job Job) {
+	q.mu.Lock()
+	q.dead = append(q.dead, job)
+	q.mu.Unlock()
+	if q.opts.Store != nil {
+		q.opts.Store.DeadLetter(job)
+	}
+	q.m.events.publish(Event{
+		Type:    EventJobDeadLettered,
+		Key:     job.PluginKey,
+		Message: fmt.Sprintf("job %v failed %v times: %v", job.ID, job.Attempts, job.LastError),
+	})
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type memJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+	dead []Job
+}
+
+func newMemJobStore() *memJobStore {
+	return &memJobStore{jobs: make(map[string]Job)}
+}
+
+func (s *memJobStore) Save(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *memJobStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *memJobStore) DeadLetter(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, job.ID)
+	s.dead = append(s.dead, job)
+	return nil
+}
+
+func (s *memJobStore) Pending() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []Job
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func TestJobQueueEnqueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueSize int
+		enqueue   int
+		accepted  int
+	}{
+		{name: "room", queueSize: 4, enqueue: 3, accepted: 3},
+		{name: "full", queueSize: 2, enqueue: 5, accepted: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager[any]("test", &ManagerConfig{})
+			defer m.Shutdown()
+			store := newMemJobStore()
+			// The queue is not started, so jobs stay queued.
+			q := NewJobQueue(m, func(context.Context, any, Job) error { return nil }, JobQueueOptions{QueueSize: tt.queueSize, Store: store})
+
+			accepted := 0
+			for i := 0; i < tt.enqueue; i++ {
+				_, err := q.Enqueue("p", nil)
+				switch {
+				case err == nil:
+					accepted++
+				case !errors.Is(err, ErrQueueFull):
+					t.Fatalf("Enqueue: %v", err)
+				}
+			}
+			if accepted != tt.accepted {
+				t.Errorf("accepted %v jobs, want %v", accepted, tt.accepted)
+			}
+			// Refused jobs must not be resumed from the store.
+			pending, _ := store.Pending()
+			if len(pending) != tt.accepted {
+				t.Errorf("store holds %v jobs, want %v", len(pending), tt.accepted)
+			}
+		})
+	}
+}
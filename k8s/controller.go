@@ -0,0 +1,203 @@
+// Package k8s drives a plugin manager from Kubernetes objects, either
+// Plugin custom resources (see crd.yaml) or labeled ConfigMaps whose entries
+// each hold a PluginInfo in JSON or YAML.
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	manager "github.com/joshwizzy/go-plugin-manager"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	PluginResource    = schema.GroupVersionResource{Group: "pluginmanager.io", Version: "v1alpha1", Resource: "plugins"}
+	ConfigMapResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+// DefaultConfigMapSelector selects the ConfigMaps read in ConfigMap mode.
+const DefaultConfigMapSelector = "pluginmanager.io/plugins=true"
+
+// Reconciler is implemented by *manager.Manager.
+type Reconciler interface {
+	Reconcile(desired []manager.PluginInfo) (manager.ReconcileResult, error)
+}
+
+type Controller struct {
+	Client    dynamic.Interface
+	Manager   Reconciler
+	Namespace string
+	// Resource is PluginResource or ConfigMapResource. Defaults to
+	// PluginResource.
+	Resource schema.GroupVersionResource
+	// Selector filters the watched objects by label. ConfigMaps default to
+	// DefaultConfigMapSelector.
+	Selector string
+	// Resync reconciles periodically even without changes. Defaults to 5m.
+	Resync time.Duration
+	Logger hclog.Logger
+}
+
+// Run watches the desired plugins and reconciles the manager with them
+// until ctx is done.
+func (c *Controller) Run(ctx context.Context) error {
+	if c.Resource.Resource == "" {
+		c.Resource = PluginResource
+	}
+	if c.Selector == "" && c.Resource == ConfigMapResource {
+		c.Selector = DefaultConfigMapSelector
+	}
+	if c.Resync == 0 {
+		c.Resync = 5 * time.Minute
+	}
+	if c.Logger == nil {
+		c.Logger = hclog.NewNullLogger()
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(
+		c.Client,
+		c.Resync,
+		c.Namespace,
+		func(o *metav1.ListOptions) { o.LabelSelector = c.Selector },
+	)
+	informer := factory.ForResource(c.Resource).Informer()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { notify() },
+		UpdateFunc: func(any, any) { notify() },
+		DeleteFunc: func(any) { notify() },
+	})
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return ctx.Err()
+	}
+	notify()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+		desired, err := c.desired(informer.GetStore().List())
+		if err != nil {
+			c.Logger.Error("invalid desired plugins", "error", err)
+			continue
+		}
+		result, err := c.Manager.Reconcile(desired)
+		if err != nil {
+			c.Logger.Error("reconciling plugins failed", "error", err)
+		}
+		if len(result.Started)+len(result.Stopped)+len(result.Restarted) > 0 {
+			c.Logger.Info(
+				"reconciled plugins",
+				"started", result.Started,
+				"stopped", result.Stopped,
+				"restarted", result.Restarted,
+			)
+		}
+	}
+}
+
+func (c *Controller) desired(objs []any) ([]manager.PluginInfo, error) {
+	var desired []manager.PluginInfo
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var plugins []manager.PluginInfo
+		var err error
+		if c.Resource == ConfigMapResource {
+			plugins, err = fromConfigMap(u)
+		} else {
+			plugins, err = fromPlugin(u)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v %v/%v: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+		}
+		desired = append(desired, plugins...)
+	}
+	sort.Slice(desired, func(i, j int) bool { return desired[i].Key < desired[j].Key })
+	return desired, nil
+}
+
+// fromPlugin reads a Plugin resource, whose spec is a PluginInfo. The key
+// defaults to the resource name.
+func fromPlugin(u *unstructured.Unstructured) ([]manager.PluginInfo, error) {
+	spec, ok, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil || !ok {
+		return nil, fmt.Errorf("missing spec")
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var pm manager.PluginInfo
+	if err := json.Unmarshal(data, &pm); err != nil {
+		return nil, err
+	}
+	if pm.Key == "" {
+		pm.Key = u.GetName()
+	}
+	return []manager.PluginInfo{pm}, nil
+}
+
+// fromConfigMap reads every entry of a ConfigMap as a PluginInfo. Keys
+// default to the entry names without extension.
+func fromConfigMap(u *unstructured.Unstructured) ([]manager.PluginInfo, error) {
+	data, _, err := unstructured.NestedStringMap(u.Object, "data")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plugins []manager.PluginInfo
+	for _, name := range names {
+		raw, err := yaml.YAMLToJSON([]byte(data[name]))
+		if err != nil {
+			return nil, fmt.Errorf("entry %v: %w", name, err)
+		}
+		var pm manager.PluginInfo
+		if err := json.Unmarshal(raw, &pm); err != nil {
+			return nil, fmt.Errorf("entry %v: %w", name, err)
+		}
+		if pm.Key == "" {
+			pm.Key = trimExt(name)
+		}
+		plugins = append(plugins, pm)
+	}
+	return plugins, nil
+}
+
+func trimExt(name string) string {
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] == '.' {
+			return name[:i]
+		}
+	}
+	return name
+}
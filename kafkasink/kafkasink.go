@@ -0,0 +1,43 @@
+// Package kafkasink implements manager.EventSink on top of Kafka.
+package kafkasink
+
+import (
+	"context"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/segmentio/kafka-go"
+)
+
+// Sink writes events as JSON to a topic, keyed by plugin key so that the
+// events of a plugin stay in order on one partition. The event type is in
+// the "event-type" header.
+type Sink struct {
+	Writer *kafka.Writer
+}
+
+// New returns a sink writing to topic on brokers. Close it once the
+// manager is shut down.
+func New(brokers []string, topic string) *Sink {
+	return &Sink{Writer: &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+	}}
+}
+
+func (s *Sink) Publish(ctx context.Context, e manager.Event) error {
+	data, err := manager.MarshalEvent(e)
+	if err != nil {
+		return err
+	}
+	return s.Writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(e.Key),
+		Value:   data,
+		Headers: []kafka.Header{{Key: "event-type", Value: []byte(e.Type)}},
+		Time:    e.Time,
+	})
+}
+
+func (s *Sink) Close() error {
+	return s.Writer.Close()
+}
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"fmt"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// PluginKind describes a kind of plugin with its own handshake and
+// goplugin.Plugin, so plugins of different kinds can run under one
+// manager. The clients of every kind must implement the manager's C, which
+// may be a small common interface or any.
+type PluginKind struct {
+	Handshake goplugin.HandshakeConfig
+	Plugin    goplugin.Plugin
+	// VersionedPlugins takes precedence over Plugin, as in ManagerConfig.
+	VersionedPlugins map[int]goplugin.Plugin
+	// Name is the name plugins of this kind serve the plugin under.
+	// Defaults to the manager's name.
+	Name string
+}
+
+// RegisterKind makes kind available to plugins whose PluginInfo.Kind is
+// name, replacing ManagerConfig.HandshakeConfig and Plugin for them.
+func (m *Manager[C]) RegisterKind(name string, kind PluginKind) {
+	m.kindMu.Lock()
+	defer m.kindMu.Unlock()
+	m.kinds[name] = kind
+}
+
+func (m *Manager[C]) kind(name string) (PluginKind, bool) {
+	m.kindMu.RLock()
+	defer m.kindMu.RUnlock()
+	kind, ok := m.kinds[name]
+	return kind, ok
+}
+
+// applyKind configures the client of a plugin of the given kind and
+// returns the name to dispense.
+func (m *Manager[C]) applyKind(config *goplugin.ClientConfig, pm PluginInfo) (string, error) {
+	if pm.Kind == "" {
+		return m.Name, nil
+	}
+	kind, ok := m.kind(pm.Kind)
+	if !ok {
+		return "", fmt.Errorf("plugin %v has unknown kind %q", pm.Key, pm.Kind)
+	}
+	name := kind.Name
+	if name == "" {
+		name = m.Name
+	}
+	config.HandshakeConfig = kind.Handshake
+	config.Plugins = map[string]goplugin.Plugin{name: kind.Plugin}
+	config.VersionedPlugins = nil
+	if len(kind.VersionedPlugins) > 0 {
+		config.Plugins = nil
+		config.VersionedPlugins = make(map[int]goplugin.PluginSet, len(kind.VersionedPlugins))
+		for version, plugin := range kind.VersionedPlugins {
+			config.VersionedPlugins[version] = goplugin.PluginSet{name: plugin}
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Names of the host services registered by EnableKV.
+const (
+	KVGetService    = "kv.get"
+	KVPutService    = "kv.put"
+	KVDeleteService = "kv.delete"
+	KVListService   = "kv.list"
+)
+
+// KVStore persists the state plugins keep through the KV host services.
+// Every plugin key has its own namespace.
+type KVStore interface {
+	Get(ctx context.Context, pluginKey, key string) (value []byte, ok bool, err error)
+	Put(ctx context.Context, pluginKey, key string, value []byte) error
+	Delete(ctx context.Context, pluginKey, key string) error
+	// List returns the keys starting with prefix, sorted.
+	List(ctx context.Context, pluginKey, prefix string) ([]string, error)
+}
+
+// BoltKVStore is a KVStore keeping each plugin's entries in a bbolt bucket.
+type BoltKVStore struct {
+	db *bolt.DB
+}
+
+func OpenBoltKVStore(path string) (*BoltKVStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltKVStore{db: db}, nil
+}
+
+func (s *BoltKVStore) Get(_ context.Context, pluginKey, key string) ([]byte, bool, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(pluginKey)); b != nil {
+			if v := b.Get([]byte(key)); v != nil {
+				value = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	return value, value != nil, err
+}
+
+func (s *BoltKVStore) Put(_ context.Context, pluginKey, key string, value []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(pluginKey))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+func (s *BoltKVStore) Delete(_ context.Context, pluginKey, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(pluginKey)); b != nil {
+			return b.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+func (s *BoltKVStore) List(_ context.Context, pluginKey, prefix string) ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(pluginKey))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+func (s *BoltKVStore) Close() error {
+	return s.db.Close()
+}
+
+type kvRequest struct {
+	Key    string `json:"key,omitempty"`
+	Value  []byte `json:"value,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+type kvResponse struct {
+	Value []byte   `json:"value,omitempty"`
+	Found bool     `json:"found,omitempty"`
+	Keys  []string `json:"keys,omitempty"`
+}
+
+// EnableKV registers the KV host services, letting plugins granted
+// PermKVRead and PermKVWrite keep state across restarts under their key.
+// A nil store opens a bbolt database in DataDir, closed by Shutdown.
+func (m *Manager[C]) EnableKV(store KVStore) error {
+	if store == nil {
+		db, err := OpenBoltKVStore(filepath.Join(m.config.DataDir, "kv.db"))
+		if err != nil {
+			return err
+		}
+		m.hostMu.Lock()
+		m.kvCloser = db
+		m.hostMu.Unlock()
+		store = db
+	}
+
+	handle := func(fn func(ctx context.Context, caller PluginInfo, req kvRequest) (kvResponse, error)) HostServiceFunc {
+		return func(ctx context.Context, caller PluginInfo, body []byte) ([]byte, error) {
+			var req kvRequest
+			if err := json.Unmarshal(body, &req); err != nil {
+				return nil, err
+			}
+			resp, err := fn(ctx, caller, req)
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(resp)
+		}
+	}
+	m.RegisterHostService(KVGetService, PermKVRead, handle(func(ctx context.Context, caller PluginInfo, req kvRequest) (kvResponse, error) {
+		value, ok, err := store.Get(ctx, caller.Key, req.Key)
+		return kvResponse{Value: value, Found: ok}, err
+	}))
+	m.RegisterHostService(KVListService, PermKVRead, handle(func(ctx context.Context, caller PluginInfo, req kvRequest) (kvResponse, error) {
+		keys, err := store.List(ctx, caller.Key, req.Prefix)
+		return kvResponse{Keys: keys}, err
+	}))
+	m.RegisterHostService(KVPutService, PermKVWrite, handle(func(ctx context.Context, caller PluginInfo, req kvRequest) (kvResponse, error) {
+		if req.Key == "" {
+			return kvResponse{}, errors.New("empty key")
+		}
+		if req.Value == nil {
+			req.Value = []byte{}
+		}
+		return kvResponse{}, store.Put(ctx, caller.Key, req.Key, req.Value)
+	}))
+	m.RegisterHostService(KVDeleteService, PermKVWrite, handle(func(ctx context.Context, caller PluginInfo, req kvRequest) (kvResponse, error) {
+		return kvResponse{}, store.Delete(ctx, caller.Key, req.Key)
+	}))
+	return nil
+}
+
+func (m *Manager[C]) closeKV() error {
+	m.hostMu.Lock()
+	defer m.hostMu.Unlock()
+	if m.kvCloser == nil {
+		return nil
+	}
+	err := m.kvCloser.Close()
+	m.kvCloser = nil
+	return err
+}
+
+func callKV(ctx context.Context, service string, req kvRequest) (kvResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return kvResponse{}, err
+	}
+	body, err = CallHostService(ctx, service, body)
+	if err != nil {
+		return kvResponse{}, err
+	}
+	var resp kvResponse
+	err = json.Unmarshal(body, &resp)
+	return resp, err
+}
+
+// KVGet is used by plugins to read a value stored with KVPut.
+func KVGet(ctx context.Context, key string) ([]byte, bool, error) {
+	resp, err := callKV(ctx, KVGetService, kvRequest{Key: key})
+	return resp.Value, resp.Found, err
+}
+
+// KVPut is used by plugins to store a value that outlives the plugin
+// process.
+func KVPut(ctx context.Context, key string, value []byte) error {
+	_, err := callKV(ctx, KVPutService, kvRequest{Key: key, Value: value})
+	return err
+}
+
+func KVDelete(ctx context.Context, key string) error {
+	_, err := callKV(ctx, KVDeleteService, kvRequest{Key: key})
+	return err
+}
+
+// KVList is used by plugins to list their keys starting with prefix.
+func KVList(ctx context.Context, prefix string) ([]string, error) {
+	resp, err := callKV(ctx, KVListService, kvRequest{Prefix: prefix})
+	return resp.Keys, err
+}
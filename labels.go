@@ -0,0 +1,25 @@
+package manager
+
+// Filter selects plugins in ListPlugins.
+type Filter func(PluginInfo) bool
+
+// MatchLabels selects plugins carrying every label in selector.
+func MatchLabels(selector map[string]string) Filter {
+	return func(info PluginInfo) bool {
+		for k, v := range selector {
+			if got, ok := info.Labels[k]; !ok || got != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func matchAll(info PluginInfo, filters []Filter) bool {
+	for _, f := range filters {
+		if !f(info) {
+			return false
+		}
+	}
+	return true
+}
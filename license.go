@@ -0,0 +1,160 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	EventLicenseExpiring EventType = "license_expiring"
+	EventLicenseExpired  EventType = "license_expired"
+)
+
+var (
+	ErrLicenseExpired = errors.New("plugin license expired")
+	// ErrLicenseInvalid is returned by LicenseValidators that reject a
+	// license, as opposed to failing to check it.
+	ErrLicenseInvalid = errors.New("plugin license invalid")
+)
+
+type PluginLicense struct {
+	// ID identifies the license to the LicenseValidator.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+	// Expires is when the plugin may no longer run. The zero time never
+	// expires.
+	Expires time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+// LicenseValidator checks plugin licenses, for instance against a license
+// server. It returns the license as currently granted, which may extend
+// the expiry, or an error wrapping ErrLicenseInvalid.
+type LicenseValidator interface {
+	ValidateLicense(ctx context.Context, pm PluginInfo) (PluginLicense, error)
+}
+
+type LicenseValidatorFunc func(ctx context.Context, pm PluginInfo) (PluginLicense, error)
+
+func (f LicenseValidatorFunc) ValidateLicense(ctx context.Context, pm PluginInfo) (PluginLicense, error) {
+	return f(ctx, pm)
+}
+
+// LicenseConfig revalidates the licenses of running plugins every Interval
+// once the manager is started, stopping the ones that expired or were
+// revoked. Expired plugins are refused at start whether or not it is set.
+type LicenseConfig struct {
+	Validator LicenseValidator
+	// WarnBefore is how long before expiry EventLicenseExpiring is
+	// published, 7 days by default.
+	WarnBefore time.Duration
+	// Interval defaults to an hour.
+	Interval time.Duration
+	// Timeout bounds each validation, 30 seconds by default.
+	Timeout time.Duration
+	// AllowOnError keeps using the last known expiry when the validator
+	// fails without rejecting the license. Otherwise such plugins are
+	// refused at start; running ones are never stopped for it.
+	AllowOnError bool
+}
+
+func licenseDefaults(c *LicenseConfig) {
+	if c.WarnBefore == 0 {
+		c.WarnBefore = 7 * 24 * time.Hour
+	}
+	if c.Interval == 0 {
+		c.Interval = time.Hour
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+}
+
+// checkLicense validates the license of pm, updating it with the one the
+// validator granted, and refuses expired plugins. Running plugins are
+// checked every interval and only warned about once, when their license
+// enters the warning period; interval is zero for plugins being started.
+func (m *Manager[C]) checkLicense(pm *PluginInfo, interval time.Duration) error {
+	if pm.License == nil {
+		return nil
+	}
+	warnBefore := 7 * 24 * time.Hour
+	if c := m.config.Licenses; c != nil {
+		warnBefore = c.WarnBefore
+		if c.Validator != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+			license, err := c.Validator.ValidateLicense(ctx, *pm)
+			cancel()
+			switch {
+			case errors.Is(err, ErrLicenseInvalid):
+				return fmt.Errorf("plugin %v: %w", pm.Key, err)
+			case err != nil && interval == 0 && !c.AllowOnError:
+				return fmt.Errorf("validating license of plugin %v: %w", pm.Key, err)
+			case err != nil:
+				m.logFor(*pm).Warn("failed to validate plugin license", LogKeyReason, err)
+			default:
+				pm.License = &license
+			}
+		}
+	}
+
+	expires := pm.License.Expires
+	if expires.IsZero() {
+		return nil
+	}
+	left := time.Until(expires)
+	if left <= 0 {
+		return fmt.Errorf("%w: plugin %v on %v", ErrLicenseExpired, pm.Key, expires.Format(time.RFC3339))
+	}
+	if left <= warnBefore && (interval == 0 || left > warnBefore-interval) {
+		m.logFor(*pm).Warn("plugin license expires soon", "expires", expires)
+		m.events.publish(pluginEvent(
+			EventLicenseExpiring,
+			*pm,
+			fmt.Sprintf("license expires on %v", expires.Format(time.RFC3339)),
+		))
+	}
+	return nil
+}
+
+// revalidateLicenses checks the licenses of the running plugins every
+// interval and stops the plugins whose license expired or was revoked.
+func (m *Manager[C]) revalidateLicenses(ctx context.Context, interval time.Duration) {
+	ticker := m.config.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+
+		m.mu.Lock()
+		running := make([]*pluginInstance[C], 0, len(m.plugins))
+		for _, p := range m.plugins {
+			if p.Info.License != nil {
+				running = append(running, p)
+			}
+		}
+		m.mu.Unlock()
+
+		for _, p := range running {
+			m.mu.Lock()
+			info := p.Info
+			m.mu.Unlock()
+			if err := m.checkLicense(&info, interval); err != nil {
+				m.logFor(info).Error("stopping plugin", LogKeyReason, err)
+				m.events.publish(pluginEvent(EventLicenseExpired, info, err.Error()))
+				if err := m.StopPlugin(PluginInfo{Key: info.Key}); err != nil && !errors.Is(err, ErrPluginNotFound) {
+					m.logFor(info).Warn("failed to stop plugin", LogKeyReason, err)
+				}
+				continue
+			}
+			m.mu.Lock()
+			if m.plugins[info.Key] == p {
+				p.Info.License = info.License
+			}
+			m.mu.Unlock()
+		}
+	}
+}
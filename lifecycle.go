@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Lifecycle string
+
+const (
+	LifecycleActive     Lifecycle = ""
+	LifecycleDeprecated Lifecycle = "deprecated"
+	LifecycleEOL        Lifecycle = "eol"
+)
+
+const EventDeprecated EventType = "deprecated"
+
+var ErrPluginEOL = errors.New("plugin version is end-of-life")
+
+// checkLifecycle warns about deprecated plugin versions and refuses
+// end-of-life ones unless the manager allows them.
+func (m *Manager[C]) checkLifecycle(pm PluginInfo) error {
+	switch pm.Lifecycle {
+	case LifecycleActive:
+		return nil
+	case LifecycleDeprecated:
+		m.logFor(pm).Warn("plugin version is deprecated", "version", pm.Version)
+		m.events.publish(pluginEvent(
+			EventDeprecated,
+			pm,
+			fmt.Sprintf("version %v is deprecated", pm.Version),
+		))
+		return nil
+	case LifecycleEOL:
+		if m.config.AllowEOL {
+			m.logFor(pm).Warn("starting end-of-life plugin version", "version", pm.Version)
+			return nil
+		}
+		return fmt.Errorf("plugin %v version %v: %w", pm.Key, pm.Version, ErrPluginEOL)
+	default:
+		return fmt.Errorf("plugin %v has unknown lifecycle %q", pm.Key, pm.Lifecycle)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix && !windows
+
+package manager
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errors.New("file locks are not supported on this platform")
+}
+
+// tryRLockFile always succeeds: without locks there are no writers to wait
+// for.
+func tryRLockFile(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
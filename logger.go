@@ -0,0 +1,201 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type LogLevel int
+
+const (
+	LevelTrace LogLevel = iota + 1
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Logger is the minimal interface the manager can log through instead of
+// hclog, set with ManagerConfig.LogSink. args alternate keys and values; the
+// name of the sub-logger, such as the plugin key, is passed under "logger".
+// Adapters for zap and zerolog live in the zaplogger and zerologger
+// modules.
+type Logger interface {
+	Log(level LogLevel, msg string, args ...any)
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// SlogLogger logs to l. Trace messages are logged at slog.LevelDebug-4.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+func (s slogLogger) Log(level LogLevel, msg string, args ...any) {
+	s.l.Log(context.Background(), slogLevel(level), msg, args...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LevelTrace:
+		return slog.LevelDebug - 4
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+type hclogLogger struct {
+	l hclog.Logger
+}
+
+// HCLogLogger logs to l.
+func HCLogLogger(l hclog.Logger) Logger {
+	return hclogLogger{l}
+}
+
+func (h hclogLogger) Log(level LogLevel, msg string, args ...any) {
+	h.l.Log(hclog.Level(level), msg, args...)
+}
+
+// logBridge implements hclog.Logger, which go-plugin requires, on top of a
+// Logger. Levels behave like hclog's SyncParentLevel: setting the level of
+// a logger applies to its sub-loggers until one of them is set.
+type logBridge struct {
+	sink    Logger
+	name    string
+	implied []any
+	level   *bridgeLevel
+	epochs  *atomic.Uint64
+}
+
+type bridgeLevel struct {
+	parent *bridgeLevel
+	mu     sync.Mutex
+	level  hclog.Level
+	epoch  uint64
+}
+
+func newLogBridge(sink Logger, name string, level hclog.Level) hclog.Logger {
+	return &logBridge{
+		sink:   sink,
+		name:   name,
+		level:  &bridgeLevel{level: level},
+		epochs: &atomic.Uint64{},
+	}
+}
+
+func (b *logBridge) GetLevel() hclog.Level {
+	level, epoch := hclog.NoLevel, uint64(0)
+	for l := b.level; l != nil; l = l.parent {
+		l.mu.Lock()
+		if level == hclog.NoLevel || l.epoch > epoch {
+			level, epoch = l.level, l.epoch
+		}
+		l.mu.Unlock()
+	}
+	return level
+}
+
+func (b *logBridge) SetLevel(level hclog.Level) {
+	b.level.mu.Lock()
+	b.level.level = level
+	b.level.epoch = b.epochs.Add(1)
+	b.level.mu.Unlock()
+}
+
+func (b *logBridge) Log(level hclog.Level, msg string, args ...interface{}) {
+	if level < b.GetLevel() || level == hclog.Off {
+		return
+	}
+	all := make([]any, 0, 2+len(b.implied)+len(args))
+	if b.name != "" {
+		all = append(all, "logger", b.name)
+	}
+	all = append(append(all, b.implied...), args...)
+	b.sink.Log(LogLevel(level), msg, all...)
+}
+
+func (b *logBridge) Trace(msg string, args ...interface{}) { b.Log(hclog.Trace, msg, args...) }
+func (b *logBridge) Debug(msg string, args ...interface{}) { b.Log(hclog.Debug, msg, args...) }
+func (b *logBridge) Info(msg string, args ...interface{})  { b.Log(hclog.Info, msg, args...) }
+func (b *logBridge) Warn(msg string, args ...interface{})  { b.Log(hclog.Warn, msg, args...) }
+func (b *logBridge) Error(msg string, args ...interface{}) { b.Log(hclog.Error, msg, args...) }
+
+func (b *logBridge) IsTrace() bool { return b.GetLevel() <= hclog.Trace }
+func (b *logBridge) IsDebug() bool { return b.GetLevel() <= hclog.Debug }
+func (b *logBridge) IsInfo() bool  { return b.GetLevel() <= hclog.Info }
+func (b *logBridge) IsWarn() bool  { return b.GetLevel() <= hclog.Warn }
+func (b *logBridge) IsError() bool { return b.GetLevel() <= hclog.Error }
+
+func (b *logBridge) ImpliedArgs() []interface{} { return b.implied }
+func (b *logBridge) Name() string               { return b.name }
+
+// sub returns a sub-logger with its own level, following b's until set.
+func (b *logBridge) sub(name string, implied []any) *logBridge {
+	return &logBridge{
+		sink:    b.sink,
+		name:    name,
+		implied: implied,
+		level:   &bridgeLevel{parent: b.level, level: hclog.NoLevel},
+		epochs:  b.epochs,
+	}
+}
+
+func (b *logBridge) With(args ...interface{}) hclog.Logger {
+	implied := append(append([]any(nil), b.implied...), args...)
+	return b.sub(b.name, implied)
+}
+
+func (b *logBridge) Named(name string) hclog.Logger {
+	if b.name != "" {
+		name = b.name + "." + name
+	}
+	return b.sub(name, b.implied)
+}
+
+func (b *logBridge) ResetNamed(name string) hclog.Logger {
+	return b.sub(name, b.implied)
+}
+
+func (b *logBridge) StandardLogger(opts *hclog.StandardLoggerOptions) *log.Logger {
+	return log.New(b.StandardWriter(opts), "", 0)
+}
+
+// StandardWriter logs every line written to it at Info, or at the level of
+// its [LEVEL] prefix when opts.InferLevels is set.
+func (b *logBridge) StandardWriter(opts *hclog.StandardLoggerOptions) io.Writer {
+	r, w := io.Pipe()
+	go func() {
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			line, level := s.Text(), hclog.Info
+			if opts != nil && opts.InferLevels {
+				for _, l := range []hclog.Level{hclog.Trace, hclog.Debug, hclog.Info, hclog.Warn, hclog.Error} {
+					prefix := "[" + strings.ToUpper(l.String()) + "]"
+					if strings.HasPrefix(line, prefix) {
+						line, level = strings.TrimSpace(strings.TrimPrefix(line, prefix)), l
+						break
+					}
+				}
+			}
+			b.Log(level, line)
+		}
+	}()
+	return w
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Keys of the fields logged by the manager. hclog takes alternating keys
+// and values, not format strings; use logFor for plugin related messages so
+// every line identifies the plugin the same way.
+const (
+	LogKeyPlugin     = "plugin_key"
+	LogKeyGeneration = "generation"
+	LogKeyReason     = "reason"
+)
+
+// logFor returns the plugin's logger with the fields identifying the
+// plugin instance.
+func (m *Manager[C]) logFor(pm PluginInfo) hclog.Logger {
+	args := []interface{}{LogKeyPlugin, pm.Key}
+	if pm.Generation > 0 {
+		args = append(args, LogKeyGeneration, pm.Generation)
+	}
+	return m.pluginLogger(pm.Key).With(args...)
+}
+
+// SetLogLevel changes the level of the manager's logger and of every plugin
+// logger, overriding levels set with SetPluginLogLevel. Loggers passed in
+// ManagerConfig.Logger need hclog.LoggerOptions.SyncParentLevel for plugin
+// levels to be set independently.
+func (m *Manager[C]) SetLogLevel(level hclog.Level) {
+	m.config.Logger.SetLevel(level)
+}
+
+// SetPluginLogLevel changes the level of the logger of one plugin, which
+// also logs what go-plugin reports about the plugin process and its
+// stderr.
+func (m *Manager[C]) SetPluginLogLevel(pluginKey string, level hclog.Level) {
+	m.pluginLogger(pluginKey).SetLevel(level)
+}
+
+// pluginLogger returns the sub-logger of a plugin, kept across restarts so
+// its level survives them.
+func (m *Manager[C]) pluginLogger(pluginKey string) hclog.Logger {
+	m.logMu.Lock()
+	defer m.logMu.Unlock()
+	l, ok := m.pluginLoggers[pluginKey]
+	if !ok {
+		l = m.config.Logger.Named(pluginKey)
+		m.pluginLoggers[pluginKey] = l
+	}
+	return l
+}
+
+// adminLogLevel handles PUT /log-level?level=debug[&plugin=key].
+func (m *Manager[C]) adminLogLevel(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	level := hclog.LevelFromString(q.Get("level"))
+	if level == hclog.NoLevel {
+		http.Error(w, fmt.Sprintf("unknown log level %q", q.Get("level")), http.StatusBadRequest)
+		return
+	}
+	if key := q.Get("plugin"); key != "" {
+		m.SetPluginLogLevel(key, level)
+	} else {
+		m.SetLogLevel(level)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
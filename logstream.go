@@ -0,0 +1,116 @@
+package manager
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLogLine bounds the lines published to log subscribers; longer ones
+// are split.
+const maxLogLine = 64 << 10
+
+// LogLine is a line a plugin wrote to its stderr, with secrets masked.
+type LogLine struct {
+	Key        string    `json:"key"`
+	Generation uint64    `json:"generation,omitempty"`
+	Time       time.Time `json:"time"`
+	Line       string    `json:"line"`
+}
+
+type logBus struct {
+	mu sync.Mutex
+	// subs maps subscriptions to the plugin key they follow, or "" for
+	// every plugin.
+	subs map[chan LogLine]string
+}
+
+func newLogBus() *logBus {
+	return &logBus{subs: make(map[chan LogLine]string)}
+}
+
+func (b *logBus) subscribe(pluginKey string, buffer int) (<-chan LogLine, func()) {
+	ch := make(chan LogLine, buffer)
+	b.mu.Lock()
+	b.subs[ch] = pluginKey
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *logBus) publish(l LogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch, key := range b.subs {
+		if key != "" && key != l.Key {
+			continue
+		}
+		select {
+		case ch <- l:
+		default:
+		}
+	}
+}
+
+// logTap is the stderr writer of a plugin instance, splitting what it
+// writes into lines for log subscribers.
+type logTap struct {
+	bus        *logBus
+	key        string
+	generation *atomic.Uint64
+	r          *strings.Replacer
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *logBus) tap(pm PluginInfo, generation *atomic.Uint64) *logTap {
+	t := &logTap{bus: b, key: pm.Key, generation: generation}
+	if secrets := pm.secrets(); len(secrets) > 0 {
+		pairs := make([]string, 0, 2*len(secrets))
+		for _, s := range secrets {
+			pairs = append(pairs, s, RedactedValue)
+		}
+		t.r = strings.NewReplacer(pairs...)
+	}
+	return t
+}
+
+func (t *logTap) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	for {
+		i := bytes.IndexByte(t.buf, '\n')
+		if i < 0 && len(t.buf) < maxLogLine {
+			break
+		}
+		if i < 0 {
+			i = len(t.buf)
+		}
+		line := string(bytes.TrimSuffix(t.buf[:i], []byte{'\r'}))
+		t.buf = t.buf[min(i+1, len(t.buf)):]
+		if t.r != nil {
+			line = t.r.Replace(line)
+		}
+		t.bus.publish(LogLine{Key: t.key, Generation: t.generation.Load(), Time: time.Now(), Line: line})
+	}
+	return len(p), nil
+}
+
+// SubscribeLogs streams the lines plugins write to stderr, of the plugin
+// with the given key or of every plugin if it is empty. Lines are dropped
+// while the channel is full.
+func (m *Manager[C]) SubscribeLogs(pluginKey string, buffer int) (<-chan LogLine, func()) {
+	return m.logs.subscribe(pluginKey, buffer)
+}
@@ -1,39 +1,315 @@
 package manager
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 	goplugin "github.com/hashicorp/go-plugin"
 )
 
+var ErrPluginNotFound = errors.New("plugin not found")
+
 type ManagerConfig struct {
 	HandshakeConfig goplugin.HandshakeConfig
 	Plugin          goplugin.Plugin
-	RestartConfig   RestartConfig
-	Logger          hclog.Logger
+	// VersionedPlugins maps protocol versions to plugin implementations. When
+	// set it takes precedence over Plugin, which lets plugins written in other
+	// languages negotiate the version they implement.
+	VersionedPlugins map[int]goplugin.Plugin
+	// AllowedProtocols restricts the protocols plugins may speak. Non-Go
+	// plugins must use goplugin.ProtocolGRPC.
+	AllowedProtocols []goplugin.Protocol
+	RestartConfig    RestartConfig
+	Logger           hclog.Logger
+	AutoMTLS         bool
+	TLSConfig        *tls.Config
+	Metrics          MetricsSink
+	// CertRotationInterval, when set with AutoMTLS or TLSConfig, rotates the
+	// certificates of running plugins at this interval, see
+	// Manager.RotateCertificates.
+	CertRotationInterval time.Duration
+	// BinaryCheckInterval, when set, checks the binaries of running plugins
+	// at this interval and reloads plugins whose binary was replaced. It
+	// also lets crashed plugins restart from a replaced binary, which is
+	// refused otherwise. Checksums still apply.
+	BinaryCheckInterval time.Duration
+	// BinaryLockTimeout bounds how long restarts wait for a tool holding
+	// LockBinary on the plugin binary. Defaults to 30s.
+	BinaryLockTimeout time.Duration
+	// StageBinaries copies plugin binaries into DataDir/run before they are
+	// launched, so changes to the original path do not affect running
+	// plugins and restarts relaunch the verified copy. Plugins are staged
+	// again when their BinPath or Checksum changes or BinaryCheckInterval
+	// reloads them.
+	StageBinaries bool
+	// Janitor, when set, removes stale files left by plugins and managers,
+	// see Manager.CollectGarbage.
+	Janitor *JanitorConfig
+	// EventStore, when set, records every event for Manager.ReplayEvents
+	// and Manager.SubscribeSince.
+	EventStore EventStore
+	// Webhooks, when set, posts events to URLs once the manager is
+	// started.
+	Webhooks *WebhookConfig
+	// Alerts, when set, sends events to alert sinks such as SlackSink and
+	// PagerDutySink once the manager is started.
+	Alerts *AlertConfig
+	// EventSinks receive every event once the manager is started. The
+	// natssink and kafkasink modules implement sinks for message brokers.
+	EventSinks []EventSink
+	// AdminAuth, when set, requires callers of the admin API and control
+	// service to authenticate, and limits what they can do by role.
+	AdminAuth *AdminAuth
+	// Kinds are registered as if with Manager.RegisterKind.
+	Kinds map[string]PluginKind
+	// ProtocolPlugins replaces Plugin for plugins whose PluginInfo.Protocol
+	// is set, for hosts with separate netrpc and gRPC implementations.
+	ProtocolPlugins map[goplugin.Protocol]goplugin.Plugin
+	// Fetcher downloads binaries of plugins that only declare mirrors.
+	Fetcher *Fetcher
+	// DataDir holds files the manager creates, such as imported bundles.
+	// Defaults to a directory named after the manager in os.TempDir.
+	DataDir string
+	// BundleSigningKey signs the bundles written by ExportBundle.
+	BundleSigningKey ed25519.PrivateKey
+	// BundleKeys are the keys ImportBundle accepts bundle signatures from.
+	// When set, bundles without a valid signature by one of them are
+	// refused.
+	BundleKeys []ed25519.PublicKey
+	// AllowEOL lets end-of-life plugin versions start with a warning instead
+	// of being refused.
+	AllowEOL bool
+	// AllowUnsandboxed starts plugins with the privileges of the host on
+	// Linux hosts without setpriv, which drops them, instead of failing to
+	// start them. Plugins opt out with SandboxConfig.KeepPrivileges.
+	AllowUnsandboxed bool
+	// ShutdownTimeout bounds how long Shutdown waits for plugins to exit.
+	ShutdownTimeout time.Duration
+	// RateLimit limits the rate of calls made through Call.
+	RateLimit RateLimitConfig
+	// MaxInFlight limits the number of concurrent calls made through Call to
+	// each plugin. PluginInfo.MaxInFlight overrides it per plugin.
+	MaxInFlight int
+	// Clock defaults to the system clock. Tests may set a SimulatedClock.
+	Clock Clock
+	// LoadShedding, when set, sheds calls to plugins under pressure, see
+	// Manager.Pressure.
+	LoadShedding *LoadShedConfig
+	// DefaultCallTimeout is applied to gRPC calls made without a deadline.
+	DefaultCallTimeout time.Duration
+	// ResultCache enables the result cache used by CallCached.
+	ResultCache *ResultCacheConfig
+	// Recording records or replays the calls made through CallRecorded.
+	Recording *RecordingConfig
+	// Tasks limits plugins run with RunTask and RunPluginTask.
+	Tasks TaskConfig
+	// HostVersion is checked against the host versions plugins accept.
+	HostVersion string
+	// Approver, when set, must approve every binary that was not approved
+	// before it is launched. Approvals are recorded in ApprovalStore, which
+	// defaults to a file in DataDir.
+	Approver      Approver
+	ApprovalStore ApprovalStore
+	// ApprovalTimeout bounds each call to Approver. Defaults to 15m.
+	ApprovalTimeout time.Duration
+	// Admission controllers are consulted, in order, before every plugin
+	// start.
+	Admission []AdmissionController
+	// Scan, when set, scans plugin binaries for vulnerabilities before
+	// they are launched.
+	Scan *ScanPolicy
+	// Licenses, when set, validates plugin licenses and revalidates them
+	// periodically once the manager is started.
+	Licenses *LicenseConfig
+	// FeatureFlags, when set, gates the plugins passed to Reconcile on
+	// their PluginInfo.FeatureFlag.
+	FeatureFlags *FeatureFlagConfig
+	// RequireProvenance refuses plugins without a provenance attestation.
+	// TrustedBuilders, when set, lists the builder IDs attestations must
+	// name, and EnvelopeVerifier checks attestation signatures.
+	RequireProvenance bool
+	TrustedBuilders   []string
+	EnvelopeVerifier  EnvelopeVerifier
+	// Locker elects the manager running each singleton plugin.
+	Locker Locker
+	// Systemd, when set, runs every plugin in its own systemd scope.
+	Systemd *SystemdConfig
+	// CrashDumps, when set, collects core and goroutine dumps of plugins
+	// that crash or hang.
+	CrashDumps *CrashDumpConfig
+	// Pprof gives every plugin a socket to serve net/http/pprof on with
+	// ServePprof. AdminHandler proxies it under /debug/pprof/{key}/.
+	Pprof bool
+	// LogJSON makes the default logger write JSON lines.
+	LogJSON bool
+	// IdentityTokens, when set, makes plugins authenticate their host
+	// service calls with a token issued when they start.
+	IdentityTokens *IdentityTokenConfig
+	// FDExhaustionPause is how long plugin starts are refused with
+	// ErrFDExhausted after one failed for lack of file descriptors.
+	// Defaults to 30s.
+	FDExhaustionPause time.Duration
+	// SelfCheck makes Start fail when a check of Manager.Doctor fails.
+	// Start logs the checks that did not pass either way.
+	SelfCheck bool
+	// SocketDir is where the private directory holding plugin sockets is
+	// created, with permissions 0700 and an unpredictable name. Defaults to
+	// os.TempDir.
+	SocketDir string
+	// SocketStartTimeout bounds how long plugins using TransportSocket may
+	// take to listen on their socket. Defaults to 30s.
+	SocketStartTimeout time.Duration
+	// StartupTimeouts bounds the phases of starting a plugin, see
+	// Manager.StartupReport.
+	StartupTimeouts StartupTimeouts
+	// SlowStart, when set, publishes EventSlowStart for starts much slower
+	// than the plugin's usual ones.
+	SlowStart *SlowStartConfig
+	// ReadinessGates sets how plugins wait for PluginInfo.ReadinessGates.
+	ReadinessGates ReadinessGateConfig
+	// PluginLogHandler, when set, receives what plugins log to stderr as
+	// slog records with the plugin key and generation as attributes, rather
+	// than the plugin's logger. Lines plugins log as JSON through hclog keep
+	// their level and fields.
+	PluginLogHandler slog.Handler
+	// StrictConformance asks every plugin, through its client's Describer
+	// implementation, which methods it serves before it is started, so a
+	// plugin built against an older interface is refused up front.
+	StrictConformance bool
+	// LogSink, when set and Logger is not, receives the logs of the manager
+	// and of go-plugin, for hosts logging through slog, zap or zerolog.
+	LogSink Logger
 }
 
 type RestartConfig struct {
 	Managed      bool
 	PingInterval time.Duration
 	MaxRestarts  int
+	// PingTimeout bounds each health ping. Defaults to half of PingInterval.
+	PingTimeout time.Duration
+	// SlowPing is the latency above which a ping counts as slow. Defaults to
+	// half of PingTimeout.
+	SlowPing time.Duration
+	// DegradedAfter is the number of consecutive slow pings after which the
+	// plugin is reported as degraded.
+	DegradedAfter int
+	// Strategy decides which exited plugins are restarted. Defaults to
+	// RestartOnFailure(MaxRestarts).
+	Strategy RestartStrategy
+	// PreventRestartExitCodes lists exit codes after which plugins are never
+	// restarted, whatever the strategy, such as ExitConfigError.
+	PreventRestartExitCodes []int
+	// CrashLoop quarantines plugins crashing repeatedly right after they
+	// start until Unquarantine is called.
+	CrashLoop CrashLoopConfig
 }
 
 type Manager[C any] struct {
-	mu      sync.RWMutex
-	Name    string
-	killed  chan PluginInfo
-	config  *ManagerConfig
-	plugins map[string]*pluginInstance[C]
-	stop    chan struct{}
-	done    chan struct{}
+	mu         sync.RWMutex
+	Name       string
+	killed     chan PluginInfo
+	due        chan PluginInfo
+	config     *ManagerConfig
+	plugins    map[string]*pluginInstance[C]
+	stats      *callStats
+	events     *eventBus
+	logs       *logBus
+	deploys    map[string]*deployment[C]
+	standbys   map[string]*pluginInstance[C]
+	limiter    *rateLimiter
+	bulkheads  *bulkheads
+	results    *resultCache
+	recorder   *recorder
+	tasks      chan struct{}
+	schedules  map[string]*scheduledTask[C]
+	singletons map[string]*singleton
+	superv     supervisorState
+	stop       chan struct{}
+	done       chan struct{}
+	// ctx is canceled when the manager shuts down, ending the waits of
+	// plugins being started.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	genMu       sync.Mutex
+	generations map[string]uint64
+
+	approveMu sync.Mutex
+
+	quarMu      sync.Mutex
+	crashLoops  map[string]int
+	quarantined map[string]Quarantine
+
+	logMu         sync.Mutex
+	pluginLoggers map[string]hclog.Logger
+
+	sockOnce sync.Once
+	sockDir  string
+	sockErr  error
+
+	startMu    sync.Mutex
+	startups   map[string]StartupReport
+	startTimes map[string][]time.Duration
+
+	digestMu sync.Mutex
+	digests  map[string]digestEntry
+
+	stageMu sync.Mutex
+	staged  map[string]stagedBinary
+	// stagedUse counts the running instances of each staged path.
+	stagedUse map[string]int
+
+	pressureMu sync.Mutex
+	pressures  map[string]PressureLevel
+
+	// fdPausedUntil is when plugin starts resume after the host ran out
+	// of file descriptors.
+	fdMu          sync.Mutex
+	fdPausedUntil time.Time
+
+	// desired and flagStates, guarded by mu, are the plugins last passed to
+	// Reconcile and the state of their feature flags.
+	desired    []PluginInfo
+	flagStates map[string]bool
+
+	// retrying, guarded by mu, holds the plugins the supervisor failed to
+	// restart and is still retrying.
+	retrying map[string]bool
+
+	kindMu sync.RWMutex
+	kinds  map[string]PluginKind
+
+	hostMu       sync.RWMutex
+	hostServices map[string]hostService
+	kvCloser     io.Closer
+
+	stopRotation    context.CancelFunc
+	stopBinaryWatch context.CancelFunc
+	stopJanitor     context.CancelFunc
+	stopWebhooks    context.CancelFunc
+	stopAlerts      context.CancelFunc
+	stopSinks       context.CancelFunc
+	stopLicenses    context.CancelFunc
+	stopFlags       context.CancelFunc
+	created         time.Time
+
+	started      bool
+	closed       bool
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
 func NewManager[C any](name string, config *ManagerConfig) *Manager[C] {
@@ -43,25 +319,145 @@ func NewManager[C any](name string, config *ManagerConfig) *Manager[C] {
 	if config.RestartConfig.PingInterval == 0 {
 		config.RestartConfig.PingInterval = 10 * time.Second
 	}
+	if config.RestartConfig.PingTimeout == 0 {
+		config.RestartConfig.PingTimeout = config.RestartConfig.PingInterval / 2
+	}
+	if config.RestartConfig.SlowPing == 0 {
+		config.RestartConfig.SlowPing = config.RestartConfig.PingTimeout / 2
+	}
+	if config.RestartConfig.DegradedAfter == 0 {
+		config.RestartConfig.DegradedAfter = 3
+	}
+	if config.RestartConfig.Strategy == nil {
+		config.RestartConfig.Strategy = RestartOnFailure(config.RestartConfig.MaxRestarts)
+	}
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	if config.ReadinessGates.Timeout == 0 {
+		config.ReadinessGates.Timeout = 5 * time.Minute
+	}
+	if config.ReadinessGates.Interval == 0 {
+		config.ReadinessGates.Interval = 2 * time.Second
+	}
+	if config.LoadShedding != nil {
+		loadShedDefaults(config.LoadShedding)
+	}
+	if config.SlowStart != nil && config.SlowStart.Factor == 0 {
+		config.SlowStart.Factor = 2
+	}
+	if config.SlowStart != nil && config.SlowStart.MinSamples == 0 {
+		config.SlowStart.MinSamples = 5
+	}
+	if config.IdentityTokens != nil && config.IdentityTokens.TTL == 0 {
+		config.IdentityTokens.TTL = 15 * time.Minute
+	}
+	if config.IdentityTokens != nil && len(config.IdentityTokens.Key) == 0 {
+		config.IdentityTokens.Key = make([]byte, 32)
+		rand.Read(config.IdentityTokens.Key)
+	}
+	if config.BinaryLockTimeout == 0 {
+		config.BinaryLockTimeout = 30 * time.Second
+	}
+	if config.Webhooks != nil {
+		webhookDefaults(config.Webhooks)
+	}
+	if config.Alerts != nil {
+		alertDefaults(config.Alerts)
+	}
+	if config.Scan != nil {
+		scanDefaults(config.Scan)
+	}
+	if config.Licenses != nil {
+		licenseDefaults(config.Licenses)
+	}
+	if config.FeatureFlags != nil {
+		featureFlagDefaults(config.FeatureFlags)
+	}
+	if config.SocketStartTimeout == 0 {
+		config.SocketStartTimeout = 30 * time.Second
+	}
+	if config.FDExhaustionPause == 0 {
+		config.FDExhaustionPause = 30 * time.Second
+	}
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout = 10 * time.Second
+	}
+	if config.DataDir == "" {
+		config.DataDir = filepath.Join(os.TempDir(), "plugin-manager", name)
+	}
+	if config.ApprovalTimeout == 0 {
+		config.ApprovalTimeout = 15 * time.Minute
+	}
+	if config.Approver != nil && config.ApprovalStore == nil {
+		config.ApprovalStore = NewFileApprovalStore(filepath.Join(config.DataDir, "approvals.json"))
+	}
+	if config.CrashDumps != nil && config.CrashDumps.Dir == "" {
+		config.CrashDumps.Dir = filepath.Join(config.DataDir, "crashes")
+	}
+	if config.Logger == nil && config.LogSink != nil {
+		config.Logger = newLogBridge(config.LogSink, "plugin-manager", hclog.Debug)
+	}
 	if config.Logger == nil {
 		config.Logger = hclog.New(&hclog.LoggerOptions{
-			Name:   "plugin-manager",
-			Output: os.Stdout,
-			Level:  hclog.Debug,
+			Name:            "plugin-manager",
+			Output:          os.Stdout,
+			Level:           hclog.Debug,
+			JSONFormat:      config.LogJSON,
+			SyncParentLevel: true,
 		})
 	}
 
 	killed := make(chan PluginInfo, 1)
 	m := &Manager[C]{
-		Name:    name,
-		config:  config,
-		plugins: make(map[string]*pluginInstance[C]),
-		killed:  killed,
-		done:    make(chan struct{}),
-		stop:    make(chan struct{}),
+		Name:          name,
+		config:        config,
+		plugins:       make(map[string]*pluginInstance[C]),
+		stats:         newCallStats(),
+		generations:   make(map[string]uint64),
+		schedules:     make(map[string]*scheduledTask[C]),
+		singletons:    make(map[string]*singleton),
+		retrying:      make(map[string]bool),
+		crashLoops:    make(map[string]int),
+		quarantined:   make(map[string]Quarantine),
+		pluginLoggers: make(map[string]hclog.Logger),
+		startups:      make(map[string]StartupReport),
+		kinds:         make(map[string]PluginKind),
+		digests:       make(map[string]digestEntry),
+		staged:        make(map[string]stagedBinary),
+		stagedUse:     make(map[string]int),
+		pressures:     make(map[string]PressureLevel),
+		startTimes:    make(map[string][]time.Duration),
+		limiter:       newRateLimiter(config.RateLimit),
+		bulkheads:     newBulkheads(),
+		deploys:       make(map[string]*deployment[C]),
+		standbys:      make(map[string]*pluginInstance[C]),
+		events:        newEventBus(),
+		logs:          newLogBus(),
+		killed:        killed,
+		due:           make(chan PluginInfo),
+		done:          make(chan struct{}),
+		stop:          make(chan struct{}),
+		created:       time.Now(),
 	}
-	if m.config.RestartConfig.Managed {
-		go m.supervisor()
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for name, kind := range config.Kinds {
+		m.kinds[name] = kind
+	}
+	if config.EventStore != nil {
+		m.events.store = config.EventStore
+		m.events.onError = func(err error) {
+			config.Logger.Warn("failed to record event", LogKeyReason, err)
+		}
+	}
+	if config.Tasks.MaxConcurrent > 0 {
+		m.tasks = make(chan struct{}, config.Tasks.MaxConcurrent)
+	}
+	if config.ResultCache != nil {
+		m.results = newResultCache(*config.ResultCache)
+	}
+	if config.Recording != nil {
+		m.recorder = newRecorder(*config.Recording)
 	}
 	return m
 }
@@ -70,122 +466,468 @@ func (m *Manager[C]) PluginKilled() <-chan PluginInfo {
 	return m.killed
 }
 
-func (m *Manager[C]) supervisor() {
-	defer close(m.done)
-
-	for {
-		select {
-		case pm := <-m.killed:
-			if pm.Restarts >= m.config.RestartConfig.MaxRestarts {
-				m.config.Logger.Error(
-					"plugin %v restarts %v exceeded max restarts %v",
-					pm.Key,
-					pm.Restarts,
-					m.config.RestartConfig.MaxRestarts,
-				)
-				continue
-			}
-			m.RestartPlugin(PluginInfo{Key: pm.Key, BinPath: pm.BinPath, Checksum: pm.Checksum})
-		case <-m.stop:
-			return
-		}
-	}
-
+// Shutdown stops the supervisor and every plugin. It is safe to call more
+// than once and from several goroutines; every call returns the result of
+// the first one. After Shutdown the manager's methods fail with
+// ErrManagerClosed.
+func (m *Manager[C]) Shutdown() error {
+	m.shutdownOnce.Do(func() {
+		m.shutdownErr = m.shutdown()
+	})
+	return m.shutdownErr
 }
 
-func (m *Manager[C]) Shutdown() error {
-	close(m.killed)
-	for _, p := range m.plugins {
-		p.Stop()
+func (m *Manager[C]) shutdown() error {
+	m.cancel()
+	m.mu.Lock()
+	m.closed = true
+	if m.stopRotation != nil {
+		m.stopRotation()
+	}
+	if m.stopBinaryWatch != nil {
+		m.stopBinaryWatch()
+	}
+	if m.stopJanitor != nil {
+		m.stopJanitor()
+	}
+	if m.stopWebhooks != nil {
+		m.stopWebhooks()
+	}
+	if m.stopAlerts != nil {
+		m.stopAlerts()
 	}
-	if m.config.RestartConfig.Managed {
+	if m.stopSinks != nil {
+		m.stopSinks()
+	}
+	if m.stopLicenses != nil {
+		m.stopLicenses()
+	}
+	if m.stopFlags != nil {
+		m.stopFlags()
+	}
+	for key, t := range m.schedules {
+		delete(m.schedules, key)
+		t.cancel()
+	}
+	singletons := make([]*singleton, 0, len(m.singletons))
+	for key, s := range m.singletons {
+		delete(m.singletons, key)
+		s.cancel()
+		singletons = append(singletons, s)
+	}
+	m.mu.Unlock()
+	for _, s := range singletons {
+		<-s.done
+	}
+
+	if m.started && m.config.RestartConfig.Managed {
 		close(m.stop)
 		<-m.done
 	}
 
-	return nil
+	m.mu.Lock()
+	instances := make([]*pluginInstance[C], 0, len(m.plugins)+len(m.deploys)+len(m.standbys))
+	for key, d := range m.deploys {
+		delete(m.deploys, key)
+		instances = append(instances, d.green)
+	}
+	for key, s := range m.standbys {
+		delete(m.standbys, key)
+		instances = append(instances, s)
+	}
+	for key, p := range m.plugins {
+		delete(m.plugins, key)
+		instances = append(instances, p)
+	}
+	m.mu.Unlock()
+
+	err := stopAll(instances, m.config.ShutdownTimeout)
+	m.removeSocketRoot()
+	if m.recorder != nil {
+		err = errors.Join(err, m.recorder.close())
+	}
+	return errors.Join(err, m.closeKV())
+}
+
+func (m *Manager[C]) isClosed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.closed
 }
 
-func (m *Manager[C]) loadPlugin(pm PluginInfo) (*pluginInstance[C], error) {
+func (m *Manager[C]) loadPlugin(pm PluginInfo, killed chan PluginInfo) (p *pluginInstance[C], err error) {
+	log := m.logFor(pm)
+	st := newStartupTimer(pm.Key, m.config.StartupTimeouts)
+	defer func() {
+		err = m.checkFDExhaustion(pm, err)
+		r := st.report(pm.Generation, err)
+		r.Error = pm.redact(r.Error)
+		m.recordStartup(pm, r, err)
+	}()
+	pm, pre, err := m.preflight(pm, st)
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			pre.release()
+		}
+	}()
+	// Waiting on approvals, scans and gates does not count towards the
+	// exec timeout.
+	st.next(PhaseExec)
+	execPath := pm.BinPath
+	var unstaged func()
+	if m.config.StageBinaries {
+		if execPath, unstaged, err = m.stageBinary(pm); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				unstaged()
+			}
+		}()
+		pm.StagedPath = execPath
+	}
+
+	sockRoot, err := m.socketRoot()
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		return nil, err
+	}
+	// The sockets of the plugin are all in its own directory, the only
+	// one under the socket root a sandboxed plugin can write to. go-plugin
+	// plugins create theirs in the directory it names rather than in
+	// os.TempDir.
+	sockDir, err := os.MkdirTemp(sockRoot, "plugin-")
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		return nil, err
+	}
+	// The command runs the staged copy; pm keeps the original path.
+	binPath := pm.BinPath
+	pm.BinPath = execPath
+	cmd, sandbox, err := command(context.Background(), &pm, m.config.AllowUnsandboxed, sockDir)
+	pm.BinPath = binPath
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		os.RemoveAll(sockDir)
+		return nil, err
+	}
+	if runtime.GOOS == "linux" && !sandbox.NoNewPrivs && (pm.Sandbox == nil || !pm.Sandbox.KeepPrivileges) {
+		log.Warn("starting plugin with the privileges of the host, setpriv is not installed")
+	}
+	var dumps *crashDumps
+	if m.config.CrashDumps != nil {
+		if dumps, err = m.prepareCrashDumps(cmd, pm); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			os.RemoveAll(sockDir)
+			return nil, err
+		}
+	}
+	var unit string
+	if m.config.Systemd != nil {
+		if unit, err = m.systemdScope(cmd, pm); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			os.RemoveAll(sockDir)
+			return nil, err
+		}
+	}
+	cmd.Env = append(cmd.Env, goplugin.EnvUnixSocketDir+"="+sockDir)
+	var host *hostBroker[C]
+	if m.hasHostServices() {
+		if host, err = m.startHostBroker(sockDir, pm); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			os.RemoveAll(sockDir)
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, HostServicesEnv+"="+host.addr)
+		if host.token != "" {
+			cmd.Env = append(cmd.Env, IdentityTokenEnv+"="+host.token)
+		}
+	}
+	var bin *os.File
+	var binInfo os.FileInfo
+	var pprofDir, pprofAddr string
+	if m.config.Pprof {
+		if pprofDir, pprofAddr, err = pprofSocket(sockDir); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			if host != nil {
+				host.Close()
+			}
+			os.RemoveAll(sockDir)
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, PprofEnv+"="+pprofAddr)
+	}
+	cleanup := func() {
+		if host != nil {
+			host.Close()
+		}
+		if pprofDir != "" {
+			os.RemoveAll(pprofDir)
+		}
+		os.RemoveAll(sockDir)
+		if bin != nil {
+			bin.Close()
+		}
+	}
 	config := &goplugin.ClientConfig{
 		HandshakeConfig: m.config.HandshakeConfig,
 		Plugins: map[string]goplugin.Plugin{
 			m.Name: m.config.Plugin,
 		},
-		Cmd: exec.Command(pm.BinPath),
+		Cmd:              cmd,
+		AutoMTLS:         m.config.AutoMTLS,
+		TLSConfig:        m.config.TLSConfig,
+		AllowedProtocols: m.config.AllowedProtocols,
+		GRPCDialOptions:  m.grpcDialOptions(pm),
+		// command already added the host environment, which go-plugin
+		// would otherwise append after PluginInfo.Env.
+		SkipHostEnv: true,
 	}
-	if pm.Checksum != "" {
-		src := []byte(pm.Checksum)
-		dst := make([]byte, hex.DecodedLen(len(src)))
-		_, err := hex.Decode(dst, src)
-		if err != nil {
-			m.config.Logger.Error(err.Error())
+	if err := pm.GRPC.validate(); err != nil {
+		err = fmt.Errorf("plugin %v: %w", pm.Key, err)
+		log.Error("failed to start plugin", LogKeyReason, err)
+		cleanup()
+		return nil, err
+	}
+	var generation atomic.Uint64
+	clientLogger := m.pluginLogger(pm.Key)
+	if m.config.PluginLogHandler != nil {
+		clientLogger = &pluginOutputLogger{
+			Logger: clientLogger,
+			binary: filepath.Base(cmd.Path),
+			output: m.pluginOutput(pm, &generation),
+		}
+	}
+	config.Logger = newRedactLogger(clientLogger, pm.secrets())
+	config.Stderr = m.logs.tap(pm, &generation)
+	if dumps != nil && dumps.stderr != nil {
+		config.Stderr = io.MultiWriter(dumps.stderr, config.Stderr)
+	}
+	switch pm.Protocol {
+	case "":
+	case goplugin.ProtocolNetRPC, goplugin.ProtocolGRPC:
+		config.AllowedProtocols = []goplugin.Protocol{pm.Protocol}
+		if plugin, ok := m.config.ProtocolPlugins[pm.Protocol]; ok {
+			config.Plugins = map[string]goplugin.Plugin{m.Name: plugin}
+		}
+	default:
+		err := fmt.Errorf("plugin %v has unknown protocol %q", pm.Key, pm.Protocol)
+		log.Error("failed to start plugin", LogKeyReason, err)
+		cleanup()
+		return nil, err
+	}
+	if len(m.config.VersionedPlugins) > 0 {
+		config.Plugins = nil
+		config.VersionedPlugins = make(map[int]goplugin.PluginSet, len(m.config.VersionedPlugins))
+		for version, plugin := range m.config.VersionedPlugins {
+			config.VersionedPlugins[version] = goplugin.PluginSet{m.Name: plugin}
+		}
+	}
+	dispenseName, err := m.applyKind(config, pm)
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		cleanup()
+		return nil, err
+	}
+	// The command may run the plugin through sandbox helpers, so the
+	// binary is verified here rather than through goplugin.SecureConfig,
+	// which would hash the helper.
+	digest, err := m.binaryDigest(execPath)
+	if err == nil {
+		err = matchChecksum(pm, digest)
+	}
+	if err == nil {
+		bin, binInfo, err = openBinary(execPath)
+	}
+	if err == nil && execPath != pm.BinPath {
+		// Replacements are noticed at the original path.
+		binInfo, err = os.Stat(pm.BinPath)
+	}
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		cleanup()
+		return nil, err
+	}
+	if err := st.expired(); err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		cleanup()
+		return nil, err
+	}
+
+	st.next(PhaseHandshake)
+	config.StartTimeout = m.config.StartupTimeouts.Handshake
+	var socketDir string
+	if pm.Transport == TransportSocket {
+		if socketDir, err = m.startSocketProcess(cmd, pm, config, sockDir, socketOutput(config.Logger, config.Stderr)); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			cleanup()
 			return nil, err
 		}
-		config.SecureConfig = &goplugin.SecureConfig{
-			Checksum: dst,
-			Hash:     sha256.New(),
+	}
+	closeSocket := func() {
+		if socketDir != "" {
+			os.RemoveAll(socketDir)
 		}
 	}
 	client := goplugin.NewClient(config)
 
 	rpcClient, err := client.Client()
 	if err != nil {
-		m.config.Logger.Error(err.Error())
+		if expired := st.expired(); expired != nil {
+			err = fmt.Errorf("%w: %v", expired, err)
+		}
+		log.Error("failed to start plugin", LogKeyReason, err)
+		client.Kill()
+		cleanup()
+		closeSocket()
 		return nil, err
 	}
 
-	raw, err := rpcClient.Dispense(m.Name)
+	st.next(PhaseDispense)
+	raw, err := st.dispense(rpcClient, dispenseName)
 	if err != nil {
-		m.config.Logger.Error(err.Error())
+		log.Error("failed to start plugin", LogKeyReason, err)
+		client.Kill()
+		cleanup()
+		closeSocket()
 		return nil, err
 	}
 
-	impl, ok := raw.(C)
-	if !ok {
-		return nil, fmt.Errorf("plugin does not implement interface")
+	impl, err := conform[C](pm.Key, raw)
+	if err == nil && m.config.StrictConformance {
+		err = checkDescribed(pm.Key, impl)
+	}
+	if err == nil {
+		err = st.expired()
+	}
+	if err != nil {
+		log.Error("failed to start plugin", LogKeyReason, err)
+		client.Kill()
+		cleanup()
+		closeSocket()
+		return nil, err
+	}
+
+	pm.Generation = m.nextGeneration(pm.Key)
+	generation.Store(pm.Generation)
+	if host != nil {
+		host.setInfo(pm)
 	}
 
 	stop, done := make(chan struct{}), make(chan struct{})
-	p := &pluginInstance[C]{
+	p = &pluginInstance[C]{
 		Impl:      impl,
 		client:    client,
 		rpcClient: rpcClient,
+		cmd:       cmd,
+		sandbox:   sandbox,
 		stop:      stop,
 		done:      done,
 		Info:      pm,
+		tls:       config.AutoMTLS || config.TLSConfig != nil,
+		pings:     &pingTracker{},
+		clock:     m.config.Clock,
+		started:   m.config.Clock.Now(),
+		digest:    digest,
+		killed:    killed,
+		host:      host,
+		prov:      pre.prov,
+		scan:      pre.scan,
+		unit:      unit,
+		dumps:     dumps,
+		pprof:     pprofAddr,
+		pprofDir:  pprofDir,
+		socketDir: socketDir,
+		sockDir:   sockDir,
+		bin:       bin,
+		binInfo:   binInfo,
+		unstaged:  unstaged,
+		unpin:     pre.unpin,
 	}
-	go p.Watch(m.config.Logger, m.config.RestartConfig.PingInterval, m.killed)
 
+	if m.config.StartupTimeouts.Readiness > 0 {
+		st.next(PhaseReadiness)
+		if err := st.awaitReady(p); err != nil {
+			log.Error("failed to start plugin", LogKeyReason, err)
+			p.Stop()
+			return nil, err
+		}
+	}
 	return p, nil
 }
 
 func (m *Manager[C]) LoadPlugins(plugins []PluginInfo) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if m.isClosed() {
+		return ErrManagerClosed
+	}
 
+	plugins, err := dependencyOrder(plugins)
+	if err != nil {
+		return err
+	}
 	for _, pm := range plugins {
-		p, err := m.loadPlugin(pm)
+		if pm.Schedule != nil {
+			if err := m.Schedule(pm); err != nil {
+				return err
+			}
+			continue
+		}
+		if pm.Singleton {
+			m.mu.Lock()
+			err := m.startSingletonLocked(pm)
+			m.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// m.mu is not held while loading, which may wait for readiness
+		// gates, approvals, scans and downloads.
+		p, err := m.loadPlugin(pm, m.killed)
 		if err != nil {
 			return err
 		}
-		m.plugins[pm.Key] = p
+		if err := m.insertPlugin(pm.Key, p); err != nil {
+			p.Stop()
+			return err
+		}
 	}
 
 	return nil
 }
 
+// StopPlugin stops a plugin, or removes its schedule when it was loaded
+// with one.
 func (m *Manager[c]) StopPlugin(pm PluginInfo) error {
+	if m.isClosed() {
+		return ErrManagerClosed
+	}
+
+	unscheduled := m.unschedule(pm.Key)
+	retrying := m.endRetry(pm.Key)
 	p, ok := m.getPlugin(pm.Key)
 	if !ok {
-		return fmt.Errorf("plugin %v not found", pm.Key)
+		if unscheduled || retrying {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrPluginNotFound, pm.Key)
+	}
+
+	if err := m.Rollback(pm.Key); err == nil {
+		m.logFor(pm).Debug("rolled back deployment of stopped plugin")
 	}
 
 	p.Stop()
+	m.stopStandby(pm.Key)
 
 	err := m.deletePlugin(pm.Key)
 	if err != nil {
-		m.config.Logger.Error("failed to delete plugin: %v", err)
+		m.logFor(p.Info).Error("failed to delete plugin", LogKeyReason, err)
 		return err
 	}
 
@@ -193,57 +935,101 @@ func (m *Manager[c]) StopPlugin(pm PluginInfo) error {
 }
 
 func (m *Manager[C]) StartPlugin(pm PluginInfo) (*pluginInstance[C], error) {
-	p, err := m.loadPlugin(pm)
+	if m.isClosed() {
+		return nil, ErrManagerClosed
+	}
+
+	p, err := m.loadPlugin(pm, m.killed)
 	if err != nil {
 		return nil, err
 	}
 
 	err = m.insertPlugin(pm.Key, p)
 	if err != nil {
+		p.Stop()
 		return nil, err
 	}
 
 	return p, nil
 }
 
+// RestartPlugin stops the plugin and starts it again from pm. A plugin the
+// supervisor failed to restart is only started, keeping the restart count
+// of pm.
 func (m *Manager[C]) RestartPlugin(pm PluginInfo) error {
-	restartCount := 0
 	p, ok := m.getPlugin(pm.Key)
+	if retrying := m.endRetry(pm.Key); !ok && retrying {
+		return m.startAgain(pm, pm.Restarts)
+	}
+	restartCount := 0
 	if ok {
 		restartCount = p.Info.Restarts
+		if pm.Labels == nil {
+			pm.Labels = p.Info.Labels
+		}
+		if pm.Annotations == nil {
+			pm.Annotations = p.Info.Annotations
+		}
 	}
 
-	err := m.StopPlugin(pm)
-	if err != nil {
+	if err := m.StopPlugin(pm); err != nil {
 		return err
 	}
+	return m.startAgain(pm, restartCount)
+}
 
-	p, err = m.StartPlugin(pm)
+func (m *Manager[C]) startAgain(pm PluginInfo, restartCount int) error {
+	// Set before the start, the health check reading Info as soon as the
+	// plugin runs.
+	pm.Restarts = restartCount + 1
+	p, err := m.StartPlugin(pm)
 	if err != nil {
 		return err
 	}
 
-	p.Info.Restarts = restartCount + 1
-
-	m.config.Logger.Debug("restarted plugin: %v", pm)
+	m.logFor(p.Info).Debug("restarted plugin", "restarts", p.Info.Restarts)
 	return nil
 }
 
-func (m *Manager[C]) ListPlugins() ([]PluginInfo, error) {
+// endRetry reports whether the supervisor was retrying the restart of a
+// plugin, and stops it.
+func (m *Manager[C]) endRetry(pluginKey string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	retrying := m.retrying[pluginKey]
+	delete(m.retrying, pluginKey)
+	return retrying
+}
 
-	metas := []PluginInfo{}
+func (m *Manager[C]) ListPlugins(filters ...Filter) ([]PluginInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+
+	all := make([]PluginInfo, 0, len(m.plugins))
 	for _, p := range m.plugins {
-		metas = append(metas, p.Info)
+		all = append(all, p.Info)
+	}
+	sharedBinaries(all)
+	metas := []PluginInfo{}
+	for _, info := range all {
+		if matchAll(info, filters) {
+			metas = append(metas, info)
+		}
 	}
 	return metas, nil
 }
 
 func (m *Manager[C]) GetPlugin(pluginKey string) (C, error) {
+	if m.isClosed() {
+		return *new(C), ErrManagerClosed
+	}
+
 	p, ok := m.getPlugin(pluginKey)
 	if !ok {
-		return *new(C), fmt.Errorf("plugin %v not found", pluginKey)
+		return *new(C), fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
 	}
 	return p.Impl, nil
 }
@@ -258,13 +1044,28 @@ func (m *Manager[C]) getPlugin(pluginKey string) (*pluginInstance[C], bool) {
 func (m *Manager[C]) insertPlugin(pluginKey string, p *pluginInstance[C]) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.closed {
+		return ErrManagerClosed
+	}
 	m.plugins[pluginKey] = p
+	m.watchLocked(p)
+	m.spawnStandby(p.Info)
 	return nil
 }
 
 func (m *Manager[C]) deletePlugin(pluginKey string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	delete(m.plugins, pluginKey)
+	m.mu.Unlock()
+	m.pressureMu.Lock()
+	delete(m.pressures, pluginKey)
+	m.pressureMu.Unlock()
 	return nil
 }
+
+func (m *Manager[C]) nextGeneration(pluginKey string) uint64 {
+	m.genMu.Lock()
+	defer m.genMu.Unlock()
+	m.generations[pluginKey]++
+	return m.generations[pluginKey]
+}
@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"net/rpc"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// The test binary serves as the plugin of the tests starting plugins,
+// launched with the testHandshake cookie.
+var testHandshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "MANAGER_TEST_PLUGIN",
+	MagicCookieValue: "greeter",
+}
+
+// testExitFileEnv names a file whose existence makes the test plugin exit
+// before serving, failing its start.
+const testExitFileEnv = "MANAGER_TEST_EXIT_FILE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testHandshake.MagicCookieKey) == testHandshake.MagicCookieValue {
+		if _, err := os.Stat(os.Getenv(testExitFileEnv)); err == nil {
+			os.Exit(1)
+		}
+		goplugin.Serve(&goplugin.ServeConfig{
+			HandshakeConfig: testHandshake,
+			Plugins:         goplugin.PluginSet{"test": testPlugin{}},
+		})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testGreeter interface {
+	Greet() (string, error)
+}
+
+type testPlugin struct{}
+
+func (testPlugin) Server(*goplugin.MuxBroker) (any, error) {
+	return &testServer{}, nil
+}
+
+func (testPlugin) Client(_ *goplugin.MuxBroker, c *rpc.Client) (any, error) {
+	return &testClient{c}, nil
+}
+
+type testServer struct{}
+
+// testGreetingEnv, when set, is the greeting of the test plugin.
+const testGreetingEnv = "MANAGER_TEST_GREETING"
+
+func (*testServer) Greet(_ struct{}, resp *string) error {
+	*resp = "hello"
+	if greeting := os.Getenv(testGreetingEnv); greeting != "" {
+		*resp = greeting
+	}
+	return nil
+}
+
+type testClient struct{ c *rpc.Client }
+
+func (c *testClient) Greet() (string, error) {
+	var resp string
+	err := c.c.Call("Plugin.Greet", struct{}{}, &resp)
+	return resp, err
+}
+
+// newTestManager returns a manager of plugins served by the test binary.
+func newTestManager(t *testing.T, config ManagerConfig) *Manager[testGreeter] {
+	t.Helper()
+	config.HandshakeConfig = testHandshake
+	config.Plugin = testPlugin{}
+	config.Logger = hclog.NewNullLogger()
+	config.AllowUnsandboxed = true
+	m := NewManager[testGreeter]("test", &config)
+	t.Cleanup(func() { m.Shutdown() })
+	return m
+}
+
+func testPluginInfo(t *testing.T, key string) PluginInfo {
+	t.Helper()
+	bin, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return PluginInfo{Key: key, BinPath: bin}
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
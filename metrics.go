@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metrics served by MetricsHandler. Every series has the labels namespace,
+// the name of the manager, and plugin, the plugin key; call metrics also
+// have method.
+const (
+	MetricPluginUp          = "plugin_manager_plugin_up"
+	MetricPluginDegraded    = "plugin_manager_plugin_degraded"
+	MetricPluginQuarantined = "plugin_manager_plugin_quarantined"
+	MetricPluginPressure    = "plugin_manager_plugin_pressure"
+	MetricPluginRestarts    = "plugin_manager_plugin_restarts_total"
+	MetricPluginGeneration  = "plugin_manager_plugin_generation"
+	MetricPingSeconds       = "plugin_manager_ping_seconds"
+	MetricCPUSeconds        = "plugin_manager_plugin_cpu_seconds_total"
+	MetricRSSBytes          = "plugin_manager_plugin_resident_memory_bytes"
+	MetricCalls             = "plugin_manager_calls_total"
+	MetricCallErrors        = "plugin_manager_call_errors_total"
+	MetricCallLatency       = "plugin_manager_call_latency_seconds"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type metricFamily struct {
+	name, kind, help string
+	samples          []string
+}
+
+func (f *metricFamily) add(value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(f.name)
+	b.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i] + `="` + labelEscaper.Replace(labels[i+1]) + `"`)
+	}
+	b.WriteString("} ")
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	f.samples = append(f.samples, b.String())
+}
+
+// MetricsHandler serves the state and call statistics of the plugins in
+// the Prometheus text format. AdminHandler also serves it on GET /metrics.
+func (m *Manager[C]) MetricsHandler() http.Handler {
+	return http.HandlerFunc(m.adminMetrics)
+}
+
+func (m *Manager[C]) adminMetrics(w http.ResponseWriter, r *http.Request) {
+	infos, err := m.ListPlugins()
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+
+	up := &metricFamily{name: MetricPluginUp, kind: "gauge", help: "Whether the plugin is running."}
+	degraded := &metricFamily{name: MetricPluginDegraded, kind: "gauge", help: "Whether the plugin's health checks are slow."}
+	quarantined := &metricFamily{name: MetricPluginQuarantined, kind: "gauge", help: "Whether the plugin is quarantined after crash looping."}
+	pressure := &metricFamily{name: MetricPluginPressure, kind: "gauge", help: "Pressure level of the plugin: 0 normal, 1 elevated, 2 critical."}
+	restarts := &metricFamily{name: MetricPluginRestarts, kind: "counter", help: "Restarts of the plugin."}
+	generation := &metricFamily{name: MetricPluginGeneration, kind: "gauge", help: "Times a plugin with the key was started."}
+	ping := &metricFamily{name: MetricPingSeconds, kind: "gauge", help: "Moving average of the health check latency."}
+	cpu := &metricFamily{name: MetricCPUSeconds, kind: "counter", help: "CPU time used by the plugin process."}
+	rss := &metricFamily{name: MetricRSSBytes, kind: "gauge", help: "Resident memory of the plugin process."}
+	calls := &metricFamily{name: MetricCalls, kind: "counter", help: "Calls to the plugin."}
+	callErrors := &metricFamily{name: MetricCallErrors, kind: "counter", help: "Calls to the plugin that failed."}
+	latency := &metricFamily{name: MetricCallLatency, kind: "gauge", help: "Latency quantiles of recent calls to the plugin."}
+
+	for _, info := range infos {
+		l := []string{"namespace", m.Name, "plugin", info.Key}
+		up.add(1, l...)
+		restarts.add(float64(info.Restarts), l...)
+		generation.add(float64(info.Generation), l...)
+		if d, err := m.DescribePlugin(info.Key); err == nil {
+			degraded.add(boolValue(d.Pings.Degraded), l...)
+			ping.add(d.Pings.EWMA.Seconds(), l...)
+			pressure.add(float64(d.Pressure.Level), l...)
+			if d.Resources != nil {
+				cpu.add(d.Resources.CPUTime.Seconds(), l...)
+				rss.add(float64(d.Resources.RSS), l...)
+			}
+		}
+		stats, _ := m.stats.snapshot(info.Key)
+		methods := make([]string, 0, len(stats))
+		for method := range stats {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			s := stats[method]
+			ml := append(l[:len(l):len(l)], "method", method)
+			calls.add(float64(s.Calls), ml...)
+			callErrors.add(float64(s.Errors), ml...)
+			latency.add(s.P50.Seconds(), append(ml, "quantile", "0.5")...)
+			latency.add(s.P90.Seconds(), append(ml, "quantile", "0.9")...)
+			latency.add(s.P99.Seconds(), append(ml, "quantile", "0.99")...)
+		}
+	}
+	for _, q := range m.Quarantined() {
+		quarantined.add(1, "namespace", m.Name, "plugin", q.Key)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	bw := bufio.NewWriter(w)
+	for _, f := range []*metricFamily{up, degraded, quarantined, pressure, restarts, generation, ping, cpu, rss, calls, callErrors, latency} {
+		fmt.Fprintf(bw, "# HELP %v %v\n# TYPE %v %v\n", f.name, f.help, f.name, f.kind)
+		for _, s := range f.samples {
+			bw.WriteString(s + "\n")
+		}
+	}
+	bw.Flush()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
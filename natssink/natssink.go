@@ -0,0 +1,33 @@
+// Package natssink implements manager.EventSink on top of NATS.
+package natssink
+
+import (
+	"context"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/nats-io/nats.go"
+)
+
+// Sink publishes events as JSON on the subject Prefix.<event type>, for
+// instance plugins.events.exited, so subscribers can pick the types they
+// want with wildcards.
+type Sink struct {
+	Conn *nats.Conn
+	// Prefix defaults to "plugins.events".
+	Prefix string
+}
+
+func (s *Sink) Publish(_ context.Context, e manager.Event) error {
+	data, err := manager.MarshalEvent(e)
+	if err != nil {
+		return err
+	}
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "plugins.events"
+	}
+	msg := nats.NewMsg(prefix + "." + string(e.Type))
+	msg.Data = data
+	msg.Header.Set("Plugin-Key", e.Key)
+	return s.Conn.PublishMsg(msg)
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/joshwizzy/go-plugin-manager/controlpb"
+)
+
+// Observer follows another manager through its observer service, or its
+// control service, without changing anything: it lists and describes
+// plugins and streams their events and logs.
+type Observer struct {
+	client controlpb.ControlClient
+}
+
+func NewObserver(conn grpc.ClientConnInterface) *Observer {
+	return &Observer{client: controlpb.NewControlClient(conn)}
+}
+
+func (o *Observer) ListPlugins(ctx context.Context, selector map[string]string) ([]PluginInfo, error) {
+	resp, err := o.client.ListPlugins(ctx, &controlpb.ListPluginsRequest{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]PluginInfo, 0, len(resp.Plugins))
+	for _, p := range resp.Plugins {
+		var info PluginInfo
+		if err := json.Unmarshal(p.InfoJson, &info); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (o *Observer) DescribePlugin(ctx context.Context, pluginKey string) (PluginDescription, error) {
+	resp, err := o.client.DescribePlugin(ctx, &controlpb.DescribePluginRequest{Key: pluginKey})
+	if err != nil {
+		return PluginDescription{}, err
+	}
+	var d PluginDescription
+	err = json.Unmarshal(resp.DescriptionJson, &d)
+	return d, err
+}
+
+// WatchEvents calls fn with the events of the observed manager, starting
+// with those recorded since the given time unless it is zero, until ctx is
+// done or fn fails. Data of the events is a json.RawMessage.
+func (o *Observer) WatchEvents(ctx context.Context, since time.Time, fn func(Event) error, types ...EventType) error {
+	req := &controlpb.StreamEventsRequest{}
+	for _, t := range types {
+		req.Types = append(req.Types, string(t))
+	}
+	if !since.IsZero() {
+		req.Since = timestamppb.New(since)
+	}
+	stream, err := o.client.StreamEvents(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		pe, err := stream.Recv()
+		if err != nil {
+			return streamEnd(ctx, err)
+		}
+		e := Event{
+			Type:       EventType(pe.Type),
+			Key:        pe.Key,
+			Generation: pe.Generation,
+			Labels:     pe.Labels,
+			Time:       pe.Time.AsTime(),
+			Message:    pe.Message,
+		}
+		if len(pe.DataJson) > 0 {
+			e.Data = json.RawMessage(pe.DataJson)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// WatchLogs calls fn with the lines the plugin with the given key, or
+// every plugin if it is empty, writes to stderr until ctx is done or fn
+// fails.
+func (o *Observer) WatchLogs(ctx context.Context, pluginKey string, fn func(LogLine) error) error {
+	stream, err := o.client.StreamLogs(ctx, &controlpb.StreamLogsRequest{Key: pluginKey})
+	if err != nil {
+		return err
+	}
+	for {
+		l, err := stream.Recv()
+		if err != nil {
+			return streamEnd(ctx, err)
+		}
+		if err := fn(LogLine{Key: l.Key, Generation: l.Generation, Time: l.Time.AsTime(), Line: l.Line}); err != nil {
+			return err
+		}
+	}
+}
+
+// streamEnd returns nil for streams ended by the server or by ctx.
+func streamEnd(ctx context.Context, err error) error {
+	if errors.Is(err, io.EOF) || ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
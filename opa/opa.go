@@ -0,0 +1,68 @@
+// Package opa evaluates plugin admission policies written in Rego.
+//
+// Policies define a deny set of messages, any of which refuses the plugin:
+//
+//	package plugins.admission
+//
+//	deny contains msg if {
+//		input.plugin.labels.env == "prod"
+//		not startswith(input.plugin.binPath, "/opt/registry/")
+//		msg := "prod plugins must come from the registry"
+//	}
+package opa
+
+import (
+	"context"
+	"fmt"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+const DefaultQuery = "data.plugins.admission.deny"
+
+// Evaluator is a manager.AdmissionController backed by a Rego policy.
+type Evaluator struct {
+	query rego.PreparedEvalQuery
+}
+
+// New compiles the given Rego modules, keyed by file name, and prepares
+// query, which defaults to DefaultQuery.
+func New(ctx context.Context, modules map[string]string, query string) (*Evaluator, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+	opts := []func(*rego.Rego){rego.Query(query)}
+	for name, src := range modules {
+		opts = append(opts, rego.Module(name, src))
+	}
+	q, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("preparing admission policy: %w", err)
+	}
+	return &Evaluator{query: q}, nil
+}
+
+func (e *Evaluator) Admit(ctx context.Context, req manager.AdmissionRequest) error {
+	rs, err := e.query.Eval(ctx, rego.EvalInput(req))
+	if err != nil {
+		return fmt.Errorf("evaluating admission policy: %w", err)
+	}
+
+	var reasons []string
+	for _, r := range rs {
+		for _, expr := range r.Expressions {
+			deny, ok := expr.Value.([]interface{})
+			if !ok {
+				return fmt.Errorf("admission policy returned %T, want a set of messages", expr.Value)
+			}
+			for _, msg := range deny {
+				reasons = append(reasons, fmt.Sprint(msg))
+			}
+		}
+	}
+	if len(reasons) > 0 {
+		return &manager.AdmissionDeniedError{Key: req.Plugin.Key, Reasons: reasons}
+	}
+	return nil
+}
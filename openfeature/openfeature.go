@@ -0,0 +1,36 @@
+// Package openfeature implements manager.FeatureFlagProvider with an
+// OpenFeature client, so plugins can be gated on flags served by any
+// OpenFeature provider.
+package openfeature
+
+import (
+	"context"
+
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/open-feature/go-sdk/openfeature"
+)
+
+// Provider evaluates flags as booleans with the plugin key as targeting
+// key, and its version, group and labels as attributes.
+type Provider struct {
+	Client *openfeature.Client
+	// Default is returned, with the error, when a flag cannot be evaluated.
+	Default bool
+}
+
+// New returns a Provider with a client of the registered OpenFeature
+// provider.
+func New(domain string) *Provider {
+	return &Provider{Client: openfeature.NewClient(domain)}
+}
+
+func (p *Provider) Enabled(ctx context.Context, flag string, pm manager.PluginInfo) (bool, error) {
+	attrs := map[string]any{
+		"version": pm.Version,
+		"group":   pm.Group,
+	}
+	for k, v := range pm.Labels {
+		attrs["label."+k] = v
+	}
+	return p.Client.BooleanValue(ctx, flag, p.Default, openfeature.NewEvaluationContext(pm.Key, attrs))
+}
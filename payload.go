@@ -0,0 +1,131 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// PayloadRef points to a payload exchanged through a file rather than in an
+// RPC message. It is small enough to pass in any request, e.g. as JSON.
+type PayloadRef struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// PayloadRefs are the files of one CallPayload: the plugin reads Request
+// with OpenPayload and writes its result to Response with WritePayload.
+type PayloadRefs struct {
+	Request  PayloadRef `json:"request"`
+	Response PayloadRef `json:"response"`
+}
+
+// CallPayload makes a call exchanging large payloads through temporary files
+// instead of multi-hundred-MB gRPC messages. req is written to a file in
+// DataDir, fn passes the references to the plugin, and handle receives the
+// response the plugin wrote, memory-mapped where possible. Both files are
+// removed when CallPayload returns, so handle must not keep the slice.
+//
+// The plugin must be able to open files in DataDir, which sandboxes
+// running it as another user or without the host's filesystem prevent.
+func (m *Manager[C]) CallPayload(
+	ctx context.Context,
+	pluginKey string,
+	method string,
+	req io.Reader,
+	fn func(context.Context, C, PayloadRefs) error,
+	handle func(resp []byte) error,
+) error {
+	dir := filepath.Join(m.config.DataDir, "payloads", url.PathEscape(pluginKey))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	reqFile, err := os.CreateTemp(dir, "req-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(reqFile.Name())
+	size, err := io.Copy(reqFile, req)
+	if cerr := reqFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	respFile, err := os.CreateTemp(dir, "resp-")
+	if err != nil {
+		return err
+	}
+	respFile.Close()
+	defer os.Remove(respFile.Name())
+
+	refs := PayloadRefs{
+		Request:  PayloadRef{Path: reqFile.Name(), Size: size},
+		Response: PayloadRef{Path: respFile.Name()},
+	}
+	err = m.Call(ctx, pluginKey, method, func(ctx context.Context, impl C) error {
+		return fn(ctx, impl, refs)
+	})
+	if err != nil || handle == nil {
+		return err
+	}
+
+	resp, err := OpenPayload(refs.Response)
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+	return handle(resp.Bytes())
+}
+
+// Payload is the content of a payload file, memory-mapped where possible.
+type Payload struct {
+	data  []byte
+	unmap func() error
+}
+
+func (p *Payload) Bytes() []byte {
+	return p.data
+}
+
+func (p *Payload) Close() error {
+	if p.unmap == nil {
+		return nil
+	}
+	err := p.unmap()
+	p.data, p.unmap = nil, nil
+	return err
+}
+
+// OpenPayload is used by plugins to read a payload passed by CallPayload.
+// The content must not be used after Close.
+func OpenPayload(ref PayloadRef) (*Payload, error) {
+	f, err := os.Open(ref.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return &Payload{}, nil
+	}
+	return mapPayload(f, fi.Size())
+}
+
+// WritePayload is used by plugins to write the response of a CallPayload.
+func WritePayload(ref PayloadRef, r io.Reader) error {
+	f, err := os.OpenFile(ref.Path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package manager
+
+import (
+	"io"
+	"os"
+)
+
+func mapPayload(f *os.File, size int64) (*Payload, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, err
+	}
+	return &Payload{data: data}, nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package manager
+
+import (
+	"os"
+	"syscall"
+)
+
+func mapPayload(f *os.File, size int64) (*Payload, error) {
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &Payload{data: data, unmap: func() error { return syscall.Munmap(data) }}, nil
+}
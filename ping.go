@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	pingSamples = 128
+	ewmaWeight  = 0.2
+)
+
+var errPingTimeout = errors.New("ping timed out")
+
+type PingStats struct {
+	Last     time.Duration
+	EWMA     time.Duration
+	P99      time.Duration
+	Degraded bool
+}
+
+type pingTracker struct {
+	mu       sync.Mutex
+	samples  []time.Duration
+	next     int
+	last     time.Duration
+	ewma     float64
+	slow     int
+	degraded bool
+}
+
+func (t *pingTracker) observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = d
+	if t.ewma == 0 {
+		t.ewma = float64(d)
+	} else {
+		t.ewma = ewmaWeight*float64(d) + (1-ewmaWeight)*t.ewma
+	}
+	if len(t.samples) < pingSamples {
+		t.samples = append(t.samples, d)
+	} else {
+		t.samples[t.next] = d
+		t.next = (t.next + 1) % pingSamples
+	}
+}
+
+// markSlow records whether the latest ping was slow and reports whether the
+// degraded state changed.
+func (t *pingTracker) markSlow(slow bool, threshold int) (degraded bool, changed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if slow {
+		t.slow++
+	} else {
+		t.slow = 0
+	}
+	was := t.degraded
+	t.degraded = t.slow >= threshold
+	return t.degraded, was != t.degraded
+}
+
+func (t *pingTracker) snapshot() PingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sorted := append([]time.Duration(nil), t.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return PingStats{
+		Last:     t.last,
+		EWMA:     time.Duration(t.ewma),
+		P99:      percentile(sorted, 0.99),
+		Degraded: t.degraded,
+	}
+}
+
+func (p *pluginInstance[T]) pingWithTimeout(timeout time.Duration) (time.Duration, error) {
+	errc := make(chan error, 1)
+	start := p.clock.Now()
+	go func() {
+		errc <- p.Ping()
+	}()
+
+	timer := p.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-errc:
+		return p.clock.Now().Sub(start), err
+	case <-timer.C():
+		return timeout, errPingTimeout
+	}
+}
+
+func (m *Manager[C]) PingStats(pluginKey string) (PingStats, error) {
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return PingStats{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	return p.pings.snapshot(), nil
+}
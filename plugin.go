@@ -1,7 +1,11 @@
 package manager
 
 import (
-	"log"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -9,19 +13,139 @@ import (
 )
 
 type PluginInfo struct {
-	BinPath  string
-	Key      string
-	Checksum string
-	Restarts int
+	BinPath  string `json:"binPath" yaml:"binPath"`
+	Key      string `json:"key" yaml:"key"`
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	// Protocol restricts the plugin to goplugin.ProtocolNetRPC or
+	// goplugin.ProtocolGRPC instead of ManagerConfig.AllowedProtocols, so
+	// legacy netrpc plugins and gRPC ones can run under the same manager.
+	// The negotiated protocol is reported in ConnectionInfo.
+	Protocol goplugin.Protocol `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	// Kind selects a PluginKind registered with the manager instead of
+	// ManagerConfig.HandshakeConfig and Plugin.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Transport is empty for go-plugin plugins or TransportSocket.
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	// GRPC tunes the connection of gRPC plugins.
+	GRPC *GRPCOptions `json:"grpc,omitempty" yaml:"grpc,omitempty"`
+	// Args and Env are passed to the plugin process.
+	Args []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Env  map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// Sensitive names the Env variables, Annotations and flags in Args,
+	// without dashes, whose values are masked in logs and the admin API.
+	Sensitive []string `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	// Mirrors lists URLs the binary can be downloaded from, in order of
+	// preference, when BinPath is empty.
+	Mirrors []string `json:"mirrors,omitempty" yaml:"mirrors,omitempty"`
+	Group   string   `json:"group,omitempty" yaml:"group,omitempty"`
+	// SharedBinary lists, in ListPlugins, the other running plugins started
+	// from the same BinPath. They run in their own processes.
+	SharedBinary []string `json:"sharedBinary,omitempty" yaml:"sharedBinary,omitempty"`
+	// StagedPath is the copy of the binary the plugin runs, with
+	// ManagerConfig.StageBinaries.
+	StagedPath string `json:"stagedPath,omitempty" yaml:"stagedPath,omitempty"`
+	// DependsOn lists the keys of plugins LoadPlugins starts before this
+	// one.
+	DependsOn []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	Version   string   `json:"version,omitempty" yaml:"version,omitempty"`
+	// MinHostVersion and MaxHostVersion bound the host versions the plugin
+	// works with.
+	MinHostVersion string `json:"minHostVersion,omitempty" yaml:"minHostVersion,omitempty"`
+	MaxHostVersion string `json:"maxHostVersion,omitempty" yaml:"maxHostVersion,omitempty"`
+	// HostCompatibility is set when the plugin is loaded.
+	HostCompatibility Compatibility `json:"hostCompatibility,omitempty" yaml:"hostCompatibility,omitempty"`
+	// Lifecycle marks the version as deprecated or end-of-life.
+	Lifecycle Lifecycle `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	// FeatureFlag ties the plugin to a flag of
+	// ManagerConfig.FeatureFlags: Reconcile only runs it while the flag is
+	// on.
+	FeatureFlag string `json:"featureFlag,omitempty" yaml:"featureFlag,omitempty"`
+	// License, when set, stops the plugin from starting after it expires,
+	// see ManagerConfig.Licenses.
+	License *PluginLicense `json:"license,omitempty" yaml:"license,omitempty"`
+	// Labels identify and select plugins; Annotations carry free-form
+	// metadata. Both are kept across restarts.
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	MaxInFlight int               `json:"maxInFlight,omitempty" yaml:"maxInFlight,omitempty"`
+	Restarts    int               `json:"restarts,omitempty" yaml:"restarts,omitempty"`
+	// Generation increases every time a plugin with this key is started.
+	Generation uint64 `json:"generation,omitempty" yaml:"generation,omitempty"`
+	// Schedule runs the plugin in task mode on a cron schedule, see
+	// Manager.Schedule. LoadPlugins and Reconcile schedule such plugins
+	// instead of starting them.
+	Schedule *TaskSchedule  `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Sandbox  *SandboxConfig `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+	// NetworkIsolated reports whether the plugin was started without
+	// network access.
+	NetworkIsolated bool `json:"networkIsolated,omitempty" yaml:"networkIsolated,omitempty"`
+	// Permissions grants access to host services, see
+	// Manager.RegisterHostService.
+	Permissions []Permission `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	// Provenance and SBOM are paths to an in-toto attestation, optionally
+	// in a DSSE envelope, and a CycloneDX or SPDX JSON SBOM for the binary.
+	Provenance string `json:"provenance,omitempty" yaml:"provenance,omitempty"`
+	SBOM       string `json:"sbom,omitempty" yaml:"sbom,omitempty"`
+	// Singleton plugins only run on the manager holding their lock, see
+	// Manager.StartSingleton.
+	Singleton bool `json:"singleton,omitempty" yaml:"singleton,omitempty"`
+	// Standby keeps a second, health-checked instance running that takes
+	// over as soon as the plugin crashes. Failover is done by the
+	// supervisor, see RestartConfig.Managed.
+	Standby bool `json:"standby,omitempty" yaml:"standby,omitempty"`
+	// ReadinessGates are waited for before the plugin is launched, on every
+	// start and restart.
+	ReadinessGates []ReadinessGate `json:"readinessGates,omitempty" yaml:"readinessGates,omitempty"`
+	// SystemdProperties override ManagerConfig.Systemd.Properties.
+	SystemdProperties map[string]string `json:"systemdProperties,omitempty" yaml:"systemdProperties,omitempty"`
+	// PprofAddr is the host:port on which the plugin serves net/http/pprof
+	// itself, for plugins not using ServePprof. The host must be localhost
+	// or a loopback address, so that the admin API cannot be made to reach
+	// other hosts.
+	PprofAddr string `json:"pprofAddr,omitempty" yaml:"pprofAddr,omitempty"`
+	// LastExit is set on restarted plugins to the reason the previous
+	// instance exited.
+	LastExit *ExitReason `json:"lastExit,omitempty" yaml:"lastExit,omitempty"`
 }
 
 type pluginInstance[T any] struct {
 	Impl      T
 	client    *goplugin.Client
+	cmd       *exec.Cmd
+	sandbox   AppliedSandbox
 	rpcClient goplugin.ClientProtocol
 	Info      PluginInfo
 	stop      chan struct{}
+	stopOnce  sync.Once
 	done      chan struct{}
+	tls       bool
+	pings     *pingTracker
+	killed    chan PluginInfo
+	host      *hostBroker[T]
+	prov      *Provenance
+	scan      *ScanResult
+	unit      string
+	dumps     *crashDumps
+	pprof     string
+	pprofDir  string
+	socketDir string
+	sockDir   string
+	bin       *os.File
+	binInfo   os.FileInfo
+	clock     Clock
+	started   time.Time
+	// digest is the sha256 of the binary the plugin was started from.
+	digest string
+	// unstaged, if set, ends the use of the staged binary.
+	unstaged func()
+	// unpin, if set, lets the binary be evicted from the artifact cache.
+	unpin func()
+	// inFlight counts the calls made through Call in progress.
+	inFlight atomic.Int64
+
+	watchMu  sync.Mutex
+	watching bool
+	stopped  bool
 }
 
 func (p *pluginInstance[T]) Kill() {
@@ -29,46 +153,143 @@ func (p *pluginInstance[T]) Kill() {
 }
 
 func (p *pluginInstance[T]) Ping() error {
+	if p.Info.Transport == TransportSocket {
+		return pingSocket(p.rpcClient)
+	}
 	return p.rpcClient.Ping()
 }
 
 func (p *pluginInstance[T]) Watch(
 	l hclog.Logger,
-	interval time.Duration,
+	config RestartConfig,
 	killed chan PluginInfo,
+	emit func(Event),
+	panicked func(info PluginInfo, r any),
 ) {
 	defer close(p.done)
 
-	ticker := time.NewTicker(interval)
+	for !p.watch(l, config, killed, emit, panicked) {
+	}
+}
+
+// watch runs the health check loop. It returns false if the loop panicked
+// and should be started again.
+func (p *pluginInstance[T]) watch(
+	l hclog.Logger,
+	config RestartConfig,
+	killed chan PluginInfo,
+	emit func(Event),
+	panicked func(info PluginInfo, r any),
+) (exited bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked(p.Info, r)
+			exited = false
+		}
+	}()
+
+	ticker := p.clock.NewTicker(config.PingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-p.stop:
-			log.Println("we done")
-			return
-		case <-ticker.C:
-			if err := p.Ping(); err != nil {
-				l.Debug("plugin %s exited will restart\n", p.Info.Key)
-				// if p, ok := m.GetPlugin(pm.Key); ok && p.Unloaded() {
-				// 	return nil
-				// }
+			l.Trace("stopped watching plugin")
+			return true
+		case <-ticker.C():
+			latency, err := p.pingWithTimeout(config.PingTimeout)
+			if err != nil {
+				l.Debug("plugin health check failed", LogKeyReason, err)
+				if p.isStopped() {
+					return true
+				}
+				reason := p.exitReason(err, config.PingTimeout)
+				if p.isStopped() {
+					// Stopped by the host while the exit was classified.
+					return true
+				}
+				info := p.Info
+				info.LastExit = &reason
+				e := pluginEvent(EventExited, info, reason.String())
+				e.Data = p.crashReport(reason)
+				emit(e)
 
 				// Non-blocking send or discard
 				select {
-				case killed <- p.Info:
+				case killed <- info:
 					// message sent
 				default:
 					// message dropped
 				}
-				return
+				return true
+			}
+
+			p.pings.observe(latency)
+			degraded, changed := p.pings.markSlow(latency > config.SlowPing, config.DegradedAfter)
+			if changed && degraded {
+				emit(pluginEvent(
+					EventDegraded,
+					p.Info,
+					fmt.Sprintf("ping latency %v exceeded %v", latency, config.SlowPing),
+				))
+			} else if changed {
+				emit(pluginEvent(EventRecovered, p.Info, ""))
 			}
 		}
 	}
 }
 
+// startWatch runs watch in a new goroutine unless the instance is already
+// watched or stopped.
+func (p *pluginInstance[T]) startWatch(watch func()) {
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+	if p.watching || p.stopped {
+		return
+	}
+	p.watching = true
+	go watch()
+}
+
+func (p *pluginInstance[T]) isStopped() bool {
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+	return p.stopped
+}
+
 func (p *pluginInstance[T]) Stop() {
-	close(p.stop)
+	p.stopOnce.Do(func() {
+		p.watchMu.Lock()
+		p.stopped = true
+		watching := p.watching
+		p.watchMu.Unlock()
+
+		close(p.stop)
+		if !watching {
+			close(p.done)
+		}
+	})
 	<-p.done
 	p.client.Kill()
+	if p.host != nil {
+		p.host.Close()
+	}
+	if p.pprofDir != "" {
+		os.RemoveAll(p.pprofDir)
+	}
+	if p.socketDir != "" {
+		os.RemoveAll(p.socketDir)
+	}
+	if p.sockDir != "" {
+		os.RemoveAll(p.sockDir)
+	}
+	if p.bin != nil {
+		p.bin.Close()
+	}
+	if p.unstaged != nil {
+		p.unstaged()
+	}
+	if p.unpin != nil {
+		p.unpin()
+	}
 }
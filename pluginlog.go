@@ -0,0 +1,73 @@
+package manager
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// pluginOutputLogger sends the log lines go-plugin reads from the stderr
+// of a plugin, which it logs through a sub-logger named after the binary,
+// to ManagerConfig.PluginLogHandler.
+type pluginOutputLogger struct {
+	hclog.Logger
+	binary string
+	output hclog.Logger
+}
+
+func (l *pluginOutputLogger) Named(name string) hclog.Logger {
+	if name == l.binary {
+		return l.output
+	}
+	return l.Logger.Named(name)
+}
+
+// slogHandlerLogger turns plugin log lines into slog records. The timestamp
+// go-plugin adds to lines the plugin logged as JSON becomes the record
+// time. generation is set once the plugin has started.
+type slogHandlerLogger struct {
+	h          slog.Handler
+	generation *atomic.Uint64
+}
+
+func (s slogHandlerLogger) Log(level LogLevel, msg string, args ...any) {
+	ctx := context.Background()
+	l := slogLevel(level)
+	if !s.h.Enabled(ctx, l) {
+		return
+	}
+
+	t := time.Now()
+	attrs := make([]any, 0, len(args))
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			attrs = append(attrs, args[i])
+			break
+		}
+		if args[i] == "timestamp" {
+			if ts, ok := args[i+1].(string); ok {
+				if parsed, err := time.Parse(hclog.TimeFormat, ts); err == nil {
+					t = parsed
+					continue
+				}
+			}
+		}
+		attrs = append(attrs, args[i], args[i+1])
+	}
+	r := slog.NewRecord(t, l, msg, 0)
+	if gen := s.generation.Load(); gen > 0 {
+		r.AddAttrs(slog.Uint64(LogKeyGeneration, gen))
+	}
+	r.Add(attrs...)
+	s.h.Handle(ctx, r)
+}
+
+// pluginOutput returns the logger for the output of a plugin instance,
+// with attributes identifying it. Levels are left to the handler.
+func (m *Manager[C]) pluginOutput(pm PluginInfo, generation *atomic.Uint64) hclog.Logger {
+	h := m.config.PluginLogHandler.WithAttrs([]slog.Attr{slog.String(LogKeyPlugin, pm.Key)})
+	return newLogBridge(slogHandlerLogger{h: h, generation: generation}, "", hclog.Trace)
+}
@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// PprofEnv holds the unix socket plugins serve net/http/pprof on when
+// ManagerConfig.Pprof is set. Plugins call ServePprof to use it.
+const PprofEnv = "PLUGIN_PPROF_SOCKET"
+
+// ServePprof is used by plugins to serve net/http/pprof on the socket the
+// host gave them, which the host proxies through its admin API. It does
+// nothing when the host did not ask for profiles.
+func ServePprof() error {
+	addr := os.Getenv(PprofEnv)
+	if addr == "" {
+		return nil
+	}
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	go http.Serve(l, mux)
+	return nil
+}
+
+// pprofSocket creates the directory holding the pprof socket of a plugin
+// instance in root.
+func pprofSocket(root string) (string, string, error) {
+	dir, err := os.MkdirTemp(root, "plugin-pprof-")
+	if err != nil {
+		return "", "", err
+	}
+	return dir, filepath.Join(dir, "pprof.sock"), nil
+}
+
+// checkLoopbackAddr checks that addr is a host:port on the loopback
+// interface.
+func checkLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%q is not localhost or a loopback address", host)
+	}
+	return nil
+}
+
+// adminPprof proxies /debug/pprof/{key}/... to the pprof endpoints of the
+// plugin, served on the socket from ServePprof or on PluginInfo.PprofAddr.
+func (m *Manager[C]) adminPprof(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	p, ok := m.getPlugin(key)
+	if !ok {
+		writeAdminError(w, fmt.Errorf("%w: %v", ErrPluginNotFound, key))
+		return
+	}
+	network, addr := "unix", p.pprof
+	var dialer net.Dialer
+	if p.Info.PprofAddr != "" {
+		if err := checkLoopbackAddr(p.Info.PprofAddr); err != nil {
+			http.Error(w, fmt.Sprintf("plugin %v: pprof address: %v", key, err), http.StatusForbidden)
+			return
+		}
+		network, addr = "tcp", p.Info.PprofAddr
+		// localhost is checked again once resolved.
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+				return fmt.Errorf("%v is not a loopback address", host)
+			}
+			return nil
+		}
+	}
+	if addr == "" {
+		http.Error(w, fmt.Sprintf("plugin %v does not serve pprof", key), http.StatusNotFound)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = "plugin"
+			pr.Out.URL.Path = "/debug/pprof/" + r.PathValue("path")
+			pr.Out.URL.RawPath = ""
+			pr.Out.Host = "plugin"
+		},
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,27 @@
+package manager
+
+import "testing"
+
+func TestCheckLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{"localhost:6060", true},
+		{"127.0.0.1:6060", true},
+		{"127.0.0.53:6060", true},
+		{"[::1]:6060", true},
+		{"10.0.0.1:6060", false},
+		{"169.254.169.254:80", false},
+		{"metadata.internal:80", false},
+		{"[::]:6060", false},
+		{"localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := checkLoopbackAddr(tt.addr); (err == nil) != tt.ok {
+				t.Errorf("checkLoopbackAddr(%q) = %v, want ok %v", tt.addr, err, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// preflighted is what preflight learned about a plugin on its way to being
+// launched.
+type preflighted struct {
+	prov *Provenance
+	scan *ScanResult
+	// unpin, if set, lets the fetched binary be evicted from the cache.
+	unpin func()
+}
+
+func (pre preflighted) release() {
+	if pre.unpin != nil {
+		pre.unpin()
+	}
+}
+
+// preflight runs the checks every launch of pm must pass, whether it is
+// loaded as a plugin or run as a task, and waits for its readiness gates. It
+// returns pm with its binary fetched and its host compatibility set. A
+// fetched binary stays in the cache until pre.release is called.
+func (m *Manager[C]) preflight(pm PluginInfo, st *startupTimer) (_ PluginInfo, pre preflighted, err error) {
+	defer func() {
+		if err != nil {
+			pre.release()
+		}
+	}()
+	if q, ok := m.quarantine(pm.Key); ok {
+		return pm, pre, fmt.Errorf("%w: %v since %v", ErrQuarantined, pm.Key, q.Since.Format(time.RFC3339))
+	}
+	if err := m.fdPaused(); err != nil {
+		return pm, pre, err
+	}
+	if err := m.checkLifecycle(pm); err != nil {
+		return pm, pre, err
+	}
+	if err := m.checkLicense(&pm, 0); err != nil {
+		if errors.Is(err, ErrLicenseExpired) || errors.Is(err, ErrLicenseInvalid) {
+			m.events.publish(pluginEvent(EventLicenseExpired, pm, err.Error()))
+		}
+		return pm, pre, err
+	}
+	compat, err := checkHostVersion(m.config.HostVersion, pm)
+	if err != nil {
+		return pm, pre, err
+	}
+	pm.HostCompatibility = compat
+
+	if pm.BinPath == "" && len(pm.Mirrors) > 0 {
+		if m.config.Fetcher == nil {
+			return pm, pre, fmt.Errorf("plugin %v has no binary path and no fetcher is configured", pm.Key)
+		}
+		// preflight runs without m.mu, so a slow download only holds up
+		// this plugin, and shutting down abandons it.
+		ctx := m.ctx
+		timeout := st.timeouts.Exec
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		pre.unpin = m.config.Fetcher.Cache.pin(pm.Checksum)
+		path, err := m.config.Fetcher.Fetch(ctx, pm.Checksum, pm.Mirrors)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %v", &StartupTimeoutError{Key: pm.Key, Phase: PhaseExec, Timeout: timeout}, err)
+			}
+			return pm, pre, err
+		}
+		pm.BinPath = path
+	}
+	if err := checkBinary(pm.BinPath); err != nil {
+		return pm, pre, err
+	}
+	if pm.Checksum != "" {
+		digest, err := m.binaryDigest(pm.BinPath)
+		if err == nil {
+			err = matchChecksum(pm, digest)
+		}
+		if err != nil {
+			return pm, pre, err
+		}
+	}
+	if err := m.checkApproval(pm); err != nil {
+		return pm, pre, err
+	}
+	if pre.prov, err = m.verifyProvenance(pm); err != nil {
+		return pm, pre, err
+	}
+	if err := m.admit(pm, pre.prov); err != nil {
+		return pm, pre, err
+	}
+	if pre.scan, err = m.scan(pm); err != nil {
+		return pm, pre, err
+	}
+	if err := m.awaitGates(pm); err != nil {
+		return pm, pre, err
+	}
+	return pm, pre, nil
+}
@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EventPressureChanged is published, with LoadShedding set, when the
+// pressure level of a plugin changes. Its Data is the Pressure.
+const EventPressureChanged EventType = "pressure_changed"
+
+var ErrShedLoad = errors.New("plugin is shedding load")
+
+type PressureLevel int
+
+const (
+	PressureNormal PressureLevel = iota
+	// PressureElevated plugins have a bulkhead filling up.
+	PressureElevated
+	// PressureCritical plugins are degraded or have a full bulkhead.
+	PressureCritical
+)
+
+var pressureNames = []string{"normal", "elevated", "critical"}
+
+func (l PressureLevel) String() string {
+	if l < 0 || int(l) >= len(pressureNames) {
+		return fmt.Sprintf("PressureLevel(%d)", int(l))
+	}
+	return pressureNames[l]
+}
+
+func (l PressureLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Pressure tells hosts how loaded a plugin is, so they can slow down or
+// route elsewhere before calls fail.
+type Pressure struct {
+	Key      string        `json:"key"`
+	Level    PressureLevel `json:"level"`
+	Degraded bool          `json:"degraded,omitempty"`
+	// Saturation is the bulkhead saturation, when the plugin has one.
+	Saturation float64 `json:"saturation,omitempty"`
+}
+
+func (p Pressure) reason() string {
+	if p.Degraded {
+		return "degraded"
+	}
+	return fmt.Sprintf("%.0f%% of bulkhead in use", p.Saturation*100)
+}
+
+// LoadShedConfig makes Call fail fast with ErrShedLoad on plugins under
+// pressure instead of adding to their load, and publishes
+// EventPressureChanged.
+type LoadShedConfig struct {
+	// ShedAt is the level from which calls are shed, PressureCritical by
+	// default. Calls are never shed with PressureNormal.
+	ShedAt PressureLevel
+	// ElevatedAt is the bulkhead saturation from which pressure is
+	// elevated, 0.75 by default.
+	ElevatedAt float64
+}
+
+func loadShedDefaults(c *LoadShedConfig) {
+	if c.ShedAt == PressureNormal {
+		c.ShedAt = PressureCritical
+	}
+	if c.ElevatedAt == 0 {
+		c.ElevatedAt = 0.75
+	}
+}
+
+// Pressure returns the current pressure of a running plugin.
+func (m *Manager[C]) Pressure(pluginKey string) (Pressure, error) {
+	if m.isClosed() {
+		return Pressure{}, ErrManagerClosed
+	}
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return Pressure{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	return m.pressure(p), nil
+}
+
+func (m *Manager[C]) pressure(p *pluginInstance[C]) Pressure {
+	elevatedAt := 0.75
+	if c := m.config.LoadShedding; c != nil {
+		elevatedAt = c.ElevatedAt
+	}
+	pr := Pressure{Key: p.Info.Key, Degraded: p.pings.snapshot().Degraded}
+	if stats, ok := m.BulkheadStats(p.Info.Key); ok {
+		pr.Saturation = stats.Saturation
+	}
+	switch {
+	case pr.Degraded || pr.Saturation >= 1:
+		pr.Level = PressureCritical
+	case pr.Saturation >= elevatedAt:
+		pr.Level = PressureElevated
+	}
+	return pr
+}
+
+// notePressure publishes EventPressureChanged if the level of p changed
+// since it was last noted.
+func (m *Manager[C]) notePressure(p *pluginInstance[C]) Pressure {
+	pr := m.pressure(p)
+	if m.config.LoadShedding == nil {
+		return pr
+	}
+	m.pressureMu.Lock()
+	last := m.pressures[pr.Key]
+	m.pressures[pr.Key] = pr.Level
+	m.pressureMu.Unlock()
+	if last != pr.Level {
+		e := pluginEvent(EventPressureChanged, p.Info, fmt.Sprintf("%v: %v", pr.Level, pr.reason()))
+		e.Data = pr
+		m.events.publish(e)
+	}
+	return pr
+}
+
+// shed fails calls to p while it is under the pressure LoadShedding sheds
+// at.
+func (m *Manager[C]) shed(p *pluginInstance[C]) error {
+	c := m.config.LoadShedding
+	if c == nil {
+		return nil
+	}
+	if pr := m.notePressure(p); pr.Level >= c.ShedAt {
+		return fmt.Errorf("%w: %v is %v", ErrShedLoad, pr.Key, pr.reason())
+	}
+	return nil
+}
+
+// publishHealth publishes the events of health checks, noting the pressure
+// of plugins that became degraded or recovered.
+func (m *Manager[C]) publishHealth(e Event) {
+	m.events.publish(e)
+	if e.Type != EventDegraded && e.Type != EventRecovered {
+		return
+	}
+	m.mu.RLock()
+	p, ok := m.plugins[e.Key]
+	m.mu.RUnlock()
+	if ok && p.Info.Generation == e.Generation {
+		m.notePressure(p)
+	}
+}
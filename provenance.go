@@ -0,0 +1,210 @@
+package manager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrProvenance = errors.New("plugin provenance verification failed")
+
+const (
+	inTotoStatementV01 = "https://in-toto.io/Statement/v0.1"
+	inTotoStatementV1  = "https://in-toto.io/Statement/v1"
+	dssePayloadType    = "application/vnd.in-toto+json"
+)
+
+// Provenance is the verified provenance of a plugin binary.
+type Provenance struct {
+	PredicateType string `json:"predicateType"`
+	BuilderID     string `json:"builderId,omitempty"`
+	SourceURI     string `json:"sourceUri,omitempty"`
+	// Subject is the name the attestation gives the binary.
+	Subject string `json:"subject"`
+	Digest  string `json:"digest"`
+	Signed  bool   `json:"signed"`
+	// SBOMFormat and SBOMDigest describe the SBOM attached to the plugin.
+	SBOMFormat string `json:"sbomFormat,omitempty"`
+	SBOMDigest string `json:"sbomDigest,omitempty"`
+}
+
+// DSSEEnvelope is a signed attestation envelope.
+type DSSEEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []DSSESignature `json:"signatures"`
+}
+
+type DSSESignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// EnvelopeVerifier checks the signatures of attestation envelopes. The
+// manager only checks that the attestation covers the binary; trust in the
+// signer is left to the verifier.
+type EnvelopeVerifier interface {
+	VerifyEnvelope(env DSSEEnvelope) error
+}
+
+type inTotoStatement struct {
+	Type    string `json:"_type"`
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string `json:"predicateType"`
+	Predicate     struct {
+		// SLSA v0.2
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Invocation struct {
+			ConfigSource struct {
+				URI string `json:"uri"`
+			} `json:"configSource"`
+		} `json:"invocation"`
+		// SLSA v1
+		RunDetails struct {
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+		} `json:"runDetails"`
+		BuildDefinition struct {
+			ResolvedDependencies []struct {
+				URI string `json:"uri"`
+			} `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+	} `json:"predicate"`
+}
+
+// verifyProvenance checks the attestation and SBOM attached to the plugin.
+// It returns nil when the plugin has neither and none is required.
+func (m *Manager[C]) verifyProvenance(pm PluginInfo) (*Provenance, error) {
+	if pm.Provenance == "" && pm.SBOM == "" {
+		if m.config.RequireProvenance {
+			return nil, fmt.Errorf("%w: plugin %v has no provenance", ErrProvenance, pm.Key)
+		}
+		return nil, nil
+	}
+	digest, err := m.binaryDigest(pm.BinPath)
+	if err != nil {
+		return nil, err
+	}
+
+	prov := &Provenance{Digest: digest}
+	if pm.Provenance != "" {
+		if err := m.readProvenance(pm, prov); err != nil {
+			return nil, fmt.Errorf("%w: plugin %v: %v", ErrProvenance, pm.Key, err)
+		}
+	} else if m.config.RequireProvenance {
+		return nil, fmt.Errorf("%w: plugin %v has no provenance", ErrProvenance, pm.Key)
+	}
+	if pm.SBOM != "" {
+		if err := readSBOM(pm.SBOM, prov); err != nil {
+			return nil, fmt.Errorf("%w: plugin %v: %v", ErrProvenance, pm.Key, err)
+		}
+	}
+	if len(m.config.TrustedBuilders) > 0 && pm.Provenance != "" && !contains(m.config.TrustedBuilders, prov.BuilderID) {
+		return nil, fmt.Errorf("%w: plugin %v was built by untrusted builder %q", ErrProvenance, pm.Key, prov.BuilderID)
+	}
+	return prov, nil
+}
+
+func (m *Manager[C]) readProvenance(pm PluginInfo, prov *Provenance) error {
+	data, err := os.ReadFile(pm.Provenance)
+	if err != nil {
+		return err
+	}
+
+	var env DSSEEnvelope
+	if err := json.Unmarshal(data, &env); err == nil && env.PayloadType != "" {
+		if env.PayloadType != dssePayloadType {
+			return fmt.Errorf("unsupported payload type %q", env.PayloadType)
+		}
+		if m.config.EnvelopeVerifier != nil {
+			if err := m.config.EnvelopeVerifier.VerifyEnvelope(env); err != nil {
+				return err
+			}
+			prov.Signed = true
+		}
+		if data, err = base64.StdEncoding.DecodeString(env.Payload); err != nil {
+			return fmt.Errorf("decoding attestation payload: %w", err)
+		}
+	}
+	if m.config.EnvelopeVerifier != nil && !prov.Signed {
+		return fmt.Errorf("attestation is not signed")
+	}
+
+	var st inTotoStatement
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("decoding attestation: %w", err)
+	}
+	if st.Type != inTotoStatementV01 && st.Type != inTotoStatementV1 {
+		return fmt.Errorf("unsupported statement type %q", st.Type)
+	}
+	for _, s := range st.Subject {
+		if s.Digest["sha256"] == prov.Digest {
+			prov.Subject = s.Name
+		}
+	}
+	if prov.Subject == "" {
+		return fmt.Errorf("attestation does not cover binary sha256:%v", prov.Digest)
+	}
+
+	prov.PredicateType = st.PredicateType
+	prov.BuilderID = st.Predicate.Builder.ID
+	prov.SourceURI = st.Predicate.Invocation.ConfigSource.URI
+	if prov.BuilderID == "" {
+		prov.BuilderID = st.Predicate.RunDetails.Builder.ID
+	}
+	if prov.SourceURI == "" && len(st.Predicate.BuildDefinition.ResolvedDependencies) > 0 {
+		prov.SourceURI = st.Predicate.BuildDefinition.ResolvedDependencies[0].URI
+	}
+	return nil
+}
+
+// readSBOM records the format and digest of a CycloneDX or SPDX JSON SBOM.
+func readSBOM(path string, prov *Provenance) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("decoding SBOM: %w", err)
+	}
+	switch {
+	case doc.BOMFormat == "CycloneDX":
+		prov.SBOMFormat = "cyclonedx"
+	case doc.SPDXVersion != "":
+		prov.SBOMFormat = "spdx"
+	default:
+		return fmt.Errorf("unsupported SBOM format")
+	}
+	prov.SBOMDigest, err = fileSHA256(path)
+	return err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, n int) bool {
+	for _, v := range list {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
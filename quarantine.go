@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	EventQuarantined   EventType = "quarantined"
+	EventUnquarantined EventType = "unquarantined"
+)
+
+var ErrQuarantined = errors.New("plugin is quarantined")
+
+// CrashLoopConfig quarantines plugins that keep crashing shortly after
+// they start.
+type CrashLoopConfig struct {
+	// Threshold is the number of consecutive crashes, each within Window of
+	// the plugin starting, after which it is quarantined. Zero disables
+	// crash loop detection.
+	Threshold int
+	Window    time.Duration
+}
+
+type Quarantine struct {
+	Key     string
+	Since   time.Time
+	Crashes int
+	Exit    ExitReason
+}
+
+// observeCrash counts crash loops and reports whether the plugin was
+// quarantined.
+func (m *Manager[C]) observeCrash(pm PluginInfo, exit ExitReason) bool {
+	config := m.config.RestartConfig.CrashLoop
+	if config.Threshold <= 0 {
+		return false
+	}
+
+	m.quarMu.Lock()
+	if !exit.Failed() || exit.Uptime >= config.Window {
+		delete(m.crashLoops, pm.Key)
+		m.quarMu.Unlock()
+		return false
+	}
+	m.crashLoops[pm.Key]++
+	crashes := m.crashLoops[pm.Key]
+	if crashes < config.Threshold {
+		m.quarMu.Unlock()
+		return false
+	}
+	delete(m.crashLoops, pm.Key)
+	m.quarantined[pm.Key] = Quarantine{Key: pm.Key, Since: m.config.Clock.Now(), Crashes: crashes, Exit: exit}
+	m.quarMu.Unlock()
+
+	msg := fmt.Sprintf("crashed %v times within %v of starting: %v", crashes, config.Window, exit)
+	m.logFor(pm).Error("quarantined crash looping plugin", "crashes", crashes, "exit", exit.String())
+	e := pluginEvent(EventQuarantined, pm, msg)
+	e.Data = exit
+	m.events.publish(e)
+	return true
+}
+
+func (m *Manager[C]) quarantine(pluginKey string) (Quarantine, bool) {
+	m.quarMu.Lock()
+	defer m.quarMu.Unlock()
+	q, ok := m.quarantined[pluginKey]
+	return q, ok
+}
+
+// Quarantined lists the quarantined plugins.
+func (m *Manager[C]) Quarantined() []Quarantine {
+	m.quarMu.Lock()
+	defer m.quarMu.Unlock()
+	list := make([]Quarantine, 0, len(m.quarantined))
+	for _, key := range sortedKeys(m.quarantined) {
+		list = append(list, m.quarantined[key])
+	}
+	return list
+}
+
+// Unquarantine lets a quarantined plugin run again and restarts it.
+func (m *Manager[C]) Unquarantine(pluginKey string) error {
+	m.quarMu.Lock()
+	_, ok := m.quarantined[pluginKey]
+	delete(m.quarantined, pluginKey)
+	m.quarMu.Unlock()
+	if !ok {
+		return fmt.Errorf("plugin %v is not quarantined", pluginKey)
+	}
+
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return nil
+	}
+	pm := p.Info
+	m.events.publish(pluginEvent(EventUnquarantined, pm, ""))
+	return m.RestartPlugin(pm)
+}
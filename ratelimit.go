@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("plugin call rate limited")
+
+type RateLimitMode int
+
+const (
+	// RateLimitReject fails calls over the limit with ErrRateLimited.
+	RateLimitReject RateLimitMode = iota
+	// RateLimitWait delays calls over the limit until a token is available,
+	// failing with ErrRateLimited if that is past the call's deadline.
+	RateLimitWait
+)
+
+// Limit is a token bucket refilled at Rate tokens per second holding at
+// most Burst tokens. A zero Rate means no limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimitConfig struct {
+	Global    Limit
+	PerPlugin Limit
+	Mode      RateLimitMode
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit Limit) *tokenBucket {
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// reserve takes a token, possibly going into debt, and returns how long the
+// caller has to wait before the token is actually available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+}
+
+// cancel gives back a token taken by reserve.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+type rateLimiter struct {
+	config  RateLimitConfig
+	global  *tokenBucket
+	mu      sync.Mutex
+	plugins map[string]*tokenBucket
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	l := &rateLimiter{config: config, plugins: make(map[string]*tokenBucket)}
+	if config.Global.Rate > 0 {
+		l.global = newTokenBucket(config.Global)
+	}
+	return l
+}
+
+func (l *rateLimiter) bucket(pluginKey string) *tokenBucket {
+	if l.config.PerPlugin.Rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.plugins[pluginKey]
+	if !ok {
+		b = newTokenBucket(l.config.PerPlugin)
+		l.plugins[pluginKey] = b
+	}
+	return b
+}
+
+func (l *rateLimiter) wait(ctx context.Context, pluginKey string) error {
+	buckets := []*tokenBucket{}
+	for _, b := range []*tokenBucket{l.global, l.bucket(pluginKey)} {
+		if b != nil {
+			buckets = append(buckets, b)
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var delay time.Duration
+	for _, b := range buckets {
+		delay = max(delay, b.reserve(now))
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	giveBack := func() {
+		for _, b := range buckets {
+			b.cancel()
+		}
+	}
+	if l.config.Mode == RateLimitReject {
+		giveBack()
+		return ErrRateLimited
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		giveBack()
+		return ErrRateLimited
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		giveBack()
+		return ctx.Err()
+	}
+}
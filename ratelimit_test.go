@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	type reservation struct {
+		at   time.Duration // since start
+		wait time.Duration
+	}
+	tests := []struct {
+		name         string
+		limit        Limit
+		reservations []reservation
+	}{
+		{
+			name:  "burst",
+			limit: Limit{Rate: 1, Burst: 3},
+			reservations: []reservation{
+				{0, 0}, {0, 0}, {0, 0}, {0, time.Second}, {0, 2 * time.Second},
+			},
+		},
+		{
+			name:  "refill",
+			limit: Limit{Rate: 2, Burst: 1},
+			reservations: []reservation{
+				{0, 0}, {0, 500 * time.Millisecond}, {time.Second, 0}, {time.Second, 500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "refill capped at burst",
+			limit: Limit{Rate: 10, Burst: 2},
+			reservations: []reservation{
+				{0, 0}, {time.Hour, 0}, {time.Hour, 0}, {time.Hour, 100 * time.Millisecond},
+			},
+		},
+		{
+			name:         "zero burst allows one",
+			limit:        Limit{Rate: 1},
+			reservations: []reservation{{0, 0}, {0, time.Second}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTokenBucket(tt.limit)
+			b.last = start
+			for i, r := range tt.reservations {
+				if got := b.reserve(start.Add(r.at)); got != r.wait {
+					t.Fatalf("reservation %v at %v: wait %v, want %v", i, r.at, got, r.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RateLimitConfig
+		timeout time.Duration
+		keys    []string
+		// errs are the results of waiting for each key in turn.
+		errs []error
+	}{
+		{
+			name:   "unlimited",
+			config: RateLimitConfig{},
+			keys:   []string{"a", "a", "a"},
+			errs:   []error{nil, nil, nil},
+		},
+		{
+			name:   "reject per plugin",
+			config: RateLimitConfig{PerPlugin: Limit{Rate: 1, Burst: 1}},
+			keys:   []string{"a", "b", "a", "b"},
+			errs:   []error{nil, nil, ErrRateLimited, ErrRateLimited},
+		},
+		{
+			name:   "reject global",
+			config: RateLimitConfig{Global: Limit{Rate: 1, Burst: 2}},
+			keys:   []string{"a", "b", "c"},
+			errs:   []error{nil, nil, ErrRateLimited},
+		},
+		{
+			name:   "rejected calls give their token back",
+			config: RateLimitConfig{Global: Limit{Rate: 1, Burst: 2}, PerPlugin: Limit{Rate: 1, Burst: 1}},
+			keys:   []string{"a", "a", "b", "c"},
+			errs:   []error{nil, ErrRateLimited, nil, ErrRateLimited},
+		},
+		{
+			name:    "wait",
+			config:  RateLimitConfig{PerPlugin: Limit{Rate: 100, Burst: 1}, Mode: RateLimitWait},
+			timeout: time.Second,
+			keys:    []string{"a", "a", "a"},
+			errs:    []error{nil, nil, nil},
+		},
+		{
+			name:    "wait past the deadline",
+			config:  RateLimitConfig{PerPlugin: Limit{Rate: 1, Burst: 1}, Mode: RateLimitWait},
+			timeout: 100 * time.Millisecond,
+			keys:    []string{"a", "a", "b"},
+			errs:    []error{nil, ErrRateLimited, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(tt.config)
+			for i, key := range tt.keys {
+				ctx := context.Background()
+				if tt.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+					defer cancel()
+				}
+				if err := l.wait(ctx, key); !errors.Is(err, tt.errs[i]) {
+					t.Fatalf("call %v to %v: %v, want %v", i, key, err, tt.errs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package manager
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Role grants access to the admin API. Each role includes the ones before
+// it.
+type Role int
+
+const (
+	// RoleViewer lists and describes plugins, streams events and logs and
+	// reads metrics.
+	RoleViewer Role = iota + 1
+	// RoleOperator also starts, stops and restarts plugins.
+	RoleOperator
+	// RoleAdmin also unquarantines plugins, changes log levels and reads
+	// pprof profiles.
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrUnauthorized    = errors.New("unauthorized")
+)
+
+// adminActions are the roles required by the admin API actions. Actions
+// missing from it are denied to every role.
+var adminActions = map[string]Role{
+	"list":         RoleViewer,
+	"describe":     RoleViewer,
+	"events":       RoleViewer,
+	"logs":         RoleViewer,
+	"metrics":      RoleViewer,
+	"inventory":    RoleViewer,
+	"plan-restart": RoleViewer,
+	"doctor":       RoleViewer,
+	"start":        RoleOperator,
+	"stop":         RoleOperator,
+	"restart":      RoleOperator,
+	"unquarantine": RoleAdmin,
+	"log-level":    RoleAdmin,
+	"pprof":        RoleAdmin,
+}
+
+// Principal is an authenticated caller of the admin API.
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// AuditEntry records an action attempted by an authenticated caller.
+type AuditEntry struct {
+	Time      time.Time
+	Principal string
+	Role      Role
+	Action    string
+	Key       string
+	Allowed   bool
+}
+
+// AdminAuth authenticates the callers of AdminHandler, ObserverHandler and
+// the control and observer services, and authorizes their actions by role.
+type AdminAuth struct {
+	// Tokens maps bearer tokens to principals.
+	Tokens map[string]Principal
+	// ClientCerts maps the common names of client certificates to
+	// principals. Only certificates verified by the server's tls.Config,
+	// with ClientAuth set to VerifyClientCertIfGiven or stricter, count.
+	ClientCerts map[string]Principal
+	// Audit receives an entry for every action of an authenticated caller.
+	// Entries are logged when it is nil.
+	Audit func(AuditEntry)
+}
+
+// authenticate returns the principal with the client certificate or bearer
+// token, trying the certificate first.
+func (a *AdminAuth) authenticate(chains [][]*x509.Certificate, authorization string) (Principal, error) {
+	if len(chains) > 0 && len(chains[0]) > 0 {
+		if p, ok := a.ClientCerts[chains[0][0].Subject.CommonName]; ok {
+			return p, nil
+		}
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	// Compare against every token so the time taken does not reveal them.
+	var found Principal
+	for t, p := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			found = p
+		}
+	}
+	if found.Role == 0 {
+		return Principal{}, ErrUnauthenticated
+	}
+	return found, nil
+}
+
+// authorize checks that p may perform action on key and audits the attempt.
+func (m *Manager[C]) authorize(p Principal, action, key string) error {
+	required, known := adminActions[action]
+	allowed := known && p.Role >= required
+	entry := AuditEntry{Time: time.Now(), Principal: p.Name, Role: p.Role, Action: action, Key: key, Allowed: allowed}
+	if audit := m.config.AdminAuth.Audit; audit != nil {
+		audit(entry)
+	} else {
+		m.config.Logger.Info("admin action",
+			"principal", entry.Principal, "role", entry.Role, "action", action, LogKeyPlugin, key, "allowed", allowed)
+	}
+	if !known {
+		return fmt.Errorf("%w: unknown action %v", ErrUnauthorized, action)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %v requires the %v role", ErrUnauthorized, action, required)
+	}
+	return nil
+}
+
+// guard wraps an admin API handler with authentication and authorization
+// when ManagerConfig.AdminAuth is set.
+func (m *Manager[C]) guard(action string, h http.HandlerFunc) http.HandlerFunc {
+	auth := m.config.AdminAuth
+	if auth == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var chains [][]*x509.Certificate
+		if r.TLS != nil {
+			chains = r.TLS.VerifiedChains
+		}
+		p, err := auth.authenticate(chains, r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		key := r.PathValue("key")
+		if key == "" {
+			key = r.URL.Query().Get("plugin")
+		}
+		if err := m.authorize(p, action, key); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// authorize authenticates the caller of a control service RPC when
+// ManagerConfig.AdminAuth is set and checks it may perform action on key.
+func (s *controlServer[C]) authorize(ctx context.Context, action, key string) error {
+	auth := s.m.config.AdminAuth
+	if auth == nil {
+		return nil
+	}
+	var chains [][]*x509.Certificate
+	if pr, ok := peer.FromContext(ctx); ok {
+		if info, ok := pr.AuthInfo.(credentials.TLSInfo); ok {
+			chains = info.State.VerifiedChains
+		}
+	}
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			authorization = v[0]
+		}
+	}
+	p, err := auth.authenticate(chains, authorization)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err := s.m.authorize(p, action, key); err != nil {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	alice := Principal{Name: "alice", Role: RoleAdmin}
+	bob := Principal{Name: "bob", Role: RoleViewer}
+	auth := &AdminAuth{
+		Tokens:      map[string]Principal{"alice-token": alice},
+		ClientCerts: map[string]Principal{"bob": bob},
+	}
+	cert := func(cn string) [][]*x509.Certificate {
+		return [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}
+	}
+	tests := []struct {
+		name          string
+		chains        [][]*x509.Certificate
+		authorization string
+		want          Principal
+		err           error
+	}{
+		{name: "token", authorization: "Bearer alice-token", want: alice},
+		{name: "certificate", chains: cert("bob"), want: bob},
+		{name: "certificate first", chains: cert("bob"), authorization: "Bearer alice-token", want: bob},
+		{name: "unknown certificate falls back to token", chains: cert("eve"), authorization: "Bearer alice-token", want: alice},
+		{name: "unknown certificate", chains: cert("eve"), err: ErrUnauthenticated},
+		{name: "unknown token", authorization: "Bearer eve-token", err: ErrUnauthenticated},
+		{name: "token prefix", authorization: "Bearer alice", err: ErrUnauthenticated},
+		{name: "not bearer", authorization: "Basic YWxpY2U6", err: ErrUnauthenticated},
+		{name: "nothing", err: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auth.authenticate(tt.chains, tt.authorization)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("authenticate = %v, %v, want %v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		role    Role
+		action  string
+		allowed bool
+	}{
+		{RoleViewer, "list", true},
+		{RoleViewer, "events", true},
+		{RoleViewer, "restart", false},
+		{RoleViewer, "pprof", false},
+		{RoleOperator, "describe", true},
+		{RoleOperator, "restart", true},
+		{RoleOperator, "unquarantine", false},
+		{RoleOperator, "log-level", false},
+		{RoleAdmin, "stop", true},
+		{RoleAdmin, "pprof", true},
+		{0, "list", false},
+		{RoleAdmin, "unknown", false},
+		{0, "unknown", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role.String()+"/"+tt.action, func(t *testing.T) {
+			var entries []AuditEntry
+			m := NewManager[any]("test", &ManagerConfig{AdminAuth: &AdminAuth{
+				Audit: func(e AuditEntry) { entries = append(entries, e) },
+			}})
+			defer m.Shutdown()
+
+			p := Principal{Name: "p", Role: tt.role}
+			err := m.authorize(p, tt.action, "k")
+			if (err == nil) != tt.allowed || err != nil && !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("authorize = %v, want allowed %v", err, tt.allowed)
+			}
+			want := AuditEntry{Principal: "p", Role: tt.role, Action: tt.action, Key: "k", Allowed: tt.allowed}
+			if len(entries) != 1 {
+				t.Fatalf("audited %v entries, want 1", len(entries))
+			}
+			got := entries[0]
+			got.Time = time.Time{}
+			if got != want {
+				t.Errorf("audited %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	m := NewManager[any]("test", &ManagerConfig{AdminAuth: &AdminAuth{
+		Tokens: map[string]Principal{
+			"viewer":   {Name: "v", Role: RoleViewer},
+			"operator": {Name: "o", Role: RoleOperator},
+		},
+		Audit: func(AuditEntry) {},
+	}})
+	defer m.Shutdown()
+	h := m.guard("restart", func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		token string
+		code  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"unknown", http.StatusUnauthorized},
+		{"viewer", http.StatusForbidden},
+		{"operator", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/plugins/restart?plugin=p", nil)
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != tt.code {
+			t.Errorf("token %q: status %v, want %v", tt.token, w.Code, tt.code)
+		}
+	}
+}
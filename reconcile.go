@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ReconcileResult lists the plugins Reconcile changed.
+type ReconcileResult struct {
+	Started   []string
+	Stopped   []string
+	Restarted []string
+}
+
+// Reconcile makes the running plugins match desired: plugins that are not
+// desired are stopped, missing ones are started and those whose spec changed
+// are restarted. Plugins with a Schedule are scheduled rather than started.
+// It carries on after failures and returns them joined.
+// With ManagerConfig.FeatureFlags, plugins whose flag is off are not
+// desired.
+func (m *Manager[C]) Reconcile(desired []PluginInfo) (ReconcileResult, error) {
+	var result ReconcileResult
+	if m.isClosed() {
+		return result, ErrManagerClosed
+	}
+	if m.config.FeatureFlags != nil {
+		desired = m.gateFeatureFlags(desired)
+	}
+
+	want := make(map[string]PluginInfo, len(desired))
+	for _, pm := range desired {
+		if _, ok := want[pm.Key]; ok {
+			return result, fmt.Errorf("plugin %v is desired more than once", pm.Key)
+		}
+		want[pm.Key] = pm
+	}
+
+	m.mu.RLock()
+	running := make(map[string]PluginInfo, len(m.plugins))
+	for key, p := range m.plugins {
+		running[key] = p.Info
+	}
+	singletons := make(map[string]bool, len(m.singletons))
+	for key := range m.singletons {
+		singletons[key] = true
+	}
+	m.mu.RUnlock()
+	scheduled := m.scheduled()
+
+	var errs []error
+	for _, key := range sortedKeys(scheduled) {
+		if pm, ok := want[key]; ok && pm.Schedule != nil {
+			continue
+		}
+		if m.unschedule(key) {
+			result.Stopped = append(result.Stopped, key)
+		}
+		delete(scheduled, key)
+	}
+	for _, key := range sortedKeys(singletons) {
+		if _, ok := want[key]; ok {
+			continue
+		}
+		delete(running, key)
+		if err := m.StopSingleton(key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Stopped = append(result.Stopped, key)
+	}
+	for _, key := range sortedKeys(running) {
+		if pm, ok := want[key]; ok && pm.Schedule == nil {
+			continue
+		}
+		if err := m.StopPlugin(running[key]); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(scheduled, key)
+		result.Stopped = append(result.Stopped, key)
+	}
+	for _, key := range sortedKeys(want) {
+		pm := want[key]
+		current, ok := running[key]
+		if pm.Schedule != nil {
+			current, ok := scheduled[key]
+			if ok && sameSpec(current, pm) {
+				continue
+			}
+			if ok {
+				m.unschedule(key)
+			}
+			if err := m.Schedule(pm); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if ok {
+				result.Restarted = append(result.Restarted, key)
+			} else {
+				result.Started = append(result.Started, key)
+			}
+			continue
+		}
+		switch {
+		case pm.Singleton && !singletons[key]:
+			if err := m.StartSingleton(pm); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result.Started = append(result.Started, key)
+		case pm.Singleton:
+			// Runs wherever its lock is held.
+		case !ok:
+			if _, err := m.StartPlugin(pm); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result.Started = append(result.Started, key)
+		case !sameSpec(current, pm):
+			if err := m.RestartPlugin(pm); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result.Restarted = append(result.Restarted, key)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// sameSpec reports whether the running plugin was started from desired,
+// ignoring the fields the manager fills in. Specs are compared in their JSON
+// encoding so that nil and empty collections are equal.
+func sameSpec(running, desired PluginInfo) bool {
+	for _, pm := range []*PluginInfo{&running, &desired} {
+		pm.HostCompatibility = ""
+		pm.Restarts = 0
+		pm.Generation = 0
+		pm.NetworkIsolated = false
+		pm.LastExit = nil
+		pm.SharedBinary = nil
+		pm.StagedPath = ""
+		if pm.Schedule != nil && pm.Schedule.Overlap == "" {
+			schedule := *pm.Schedule
+			schedule.Overlap = OverlapSkip
+			pm.Schedule = &schedule
+		}
+	}
+	if desired.BinPath == "" {
+		// The binary was fetched from a mirror.
+		running.BinPath = ""
+	}
+	a, err := json.Marshal(running)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(desired)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
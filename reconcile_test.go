@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSameSpec(t *testing.T) {
+	base := PluginInfo{Key: "p", BinPath: "/bin/p", Version: "1.0.0"}
+	with := func(f func(*PluginInfo)) PluginInfo {
+		pm := base
+		f(&pm)
+		return pm
+	}
+	tests := []struct {
+		name             string
+		running, desired PluginInfo
+		same             bool
+	}{
+		{name: "equal", running: base, desired: base, same: true},
+		{name: "fields filled in by the manager", running: with(func(pm *PluginInfo) {
+			pm.Restarts = 3
+			pm.Generation = 4
+			pm.HostCompatibility = CompatibilityCompatible
+			pm.NetworkIsolated = true
+			pm.LastExit = &ExitReason{Kind: ExitCrash}
+			pm.SharedBinary = []string{"q"}
+			pm.StagedPath = "/var/lib/p"
+		}), desired: base, same: true},
+		{name: "nil and empty collections", running: with(func(pm *PluginInfo) { pm.Labels = map[string]string{} }), desired: base, same: true},
+		{name: "fetched binary", running: base, desired: with(func(pm *PluginInfo) { pm.BinPath = "" }), same: true},
+		{name: "version", running: base, desired: with(func(pm *PluginInfo) { pm.Version = "1.1.0" })},
+		{name: "labels", running: base, desired: with(func(pm *PluginInfo) { pm.Labels = map[string]string{"a": "b"} })},
+		{name: "binary", running: base, desired: with(func(pm *PluginInfo) { pm.BinPath = "/bin/q" })},
+		{
+			name:    "default overlap",
+			running: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *", Overlap: OverlapSkip} }),
+			desired: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *"} }),
+			same:    true,
+		},
+		{
+			name:    "overlap",
+			running: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *", Overlap: OverlapSkip} }),
+			desired: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *", Overlap: OverlapQueue} }),
+		},
+		{
+			name:    "cron",
+			running: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *"} }),
+			desired: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "0 * * * *"} }),
+		},
+		{
+			name:    "scheduled",
+			running: base,
+			desired: with(func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "* * * * *"} }),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := tt.desired
+			if got := sameSpec(tt.running, tt.desired); got != tt.same {
+				t.Errorf("sameSpec = %v, want %v", got, tt.same)
+			}
+			if !reflect.DeepEqual(desired, tt.desired) {
+				t.Error("sameSpec modified desired")
+			}
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	m := newTestManager(t, ManagerConfig{})
+	plugin := func(key string, f func(*PluginInfo)) PluginInfo {
+		pm := testPluginInfo(t, key)
+		if f != nil {
+			f(&pm)
+		}
+		return pm
+	}
+	// Schedules far enough away not to run during the test.
+	yearly := func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "0 0 1 1 *"} }
+
+	steps := []struct {
+		name      string
+		desired   []PluginInfo
+		want      ReconcileResult
+		running   []string
+		scheduled []string
+	}{
+		{
+			name:      "start",
+			desired:   []PluginInfo{plugin("a", nil), plugin("b", nil), plugin("s", yearly)},
+			want:      ReconcileResult{Started: []string{"a", "b", "s"}},
+			running:   []string{"a", "b"},
+			scheduled: []string{"s"},
+		},
+		{
+			name:      "unchanged",
+			desired:   []PluginInfo{plugin("a", nil), plugin("b", nil), plugin("s", yearly)},
+			running:   []string{"a", "b"},
+			scheduled: []string{"s"},
+		},
+		{
+			name: "default overlap is unchanged",
+			desired: []PluginInfo{plugin("a", nil), plugin("b", nil), plugin("s", func(pm *PluginInfo) {
+				pm.Schedule = &TaskSchedule{Cron: "0 0 1 1 *", Overlap: OverlapSkip}
+			})},
+			running:   []string{"a", "b"},
+			scheduled: []string{"s"},
+		},
+		{
+			name: "changed",
+			desired: []PluginInfo{
+				plugin("a", func(pm *PluginInfo) { pm.Labels = map[string]string{"v": "2"} }),
+				plugin("b", nil),
+				plugin("s", func(pm *PluginInfo) { pm.Schedule = &TaskSchedule{Cron: "0 0 1 2 *"} }),
+			},
+			want:      ReconcileResult{Restarted: []string{"a", "s"}},
+			running:   []string{"a", "b"},
+			scheduled: []string{"s"},
+		},
+		{
+			name:      "running plugin scheduled",
+			desired:   []PluginInfo{plugin("a", yearly), plugin("b", nil)},
+			want:      ReconcileResult{Started: []string{"a"}, Stopped: []string{"s", "a"}},
+			running:   []string{"b"},
+			scheduled: []string{"a"},
+		},
+		{
+			name:      "stop",
+			desired:   nil,
+			want:      ReconcileResult{Stopped: []string{"a", "b"}},
+			running:   []string{},
+			scheduled: []string{},
+		},
+	}
+	for _, step := range steps {
+		got, err := m.Reconcile(step.desired)
+		if err != nil {
+			t.Fatalf("%v: Reconcile: %v", step.name, err)
+		}
+		if !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%v: Reconcile = %+v, want %+v", step.name, got, step.want)
+		}
+		running, scheduled := testRunning(t, m), sortedKeys(m.scheduled())
+		if !reflect.DeepEqual(running, step.running) || !reflect.DeepEqual(scheduled, step.scheduled) {
+			t.Errorf("%v: running %v and scheduled %v, want %v and %v", step.name, running, scheduled, step.running, step.scheduled)
+		}
+	}
+}
+
+func TestReconcileFeatureFlags(t *testing.T) {
+	var mu sync.Mutex
+	flags := map[string]bool{}
+	setFlag := func(flag string, on bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		flags[flag] = on
+	}
+	m := newTestManager(t, ManagerConfig{FeatureFlags: &FeatureFlagConfig{
+		Provider: FeatureFlagProviderFunc(func(ctx context.Context, flag string, pm PluginInfo) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return flags[flag], nil
+		}),
+		Interval: 10 * time.Millisecond,
+	}})
+	a := testPluginInfo(t, "a")
+	a.FeatureFlag = "new-a"
+	desired := []PluginInfo{a, testPluginInfo(t, "b")}
+
+	got, err := m.Reconcile(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (ReconcileResult{Started: []string{"b"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile with the flag off = %+v, want %+v", got, want)
+	}
+
+	// Flipping the flag reconciles the last desired plugins again.
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	setFlag("new-a", true)
+	waitFor(t, "a to start", func() bool {
+		return reflect.DeepEqual(testRunning(t, m), []string{"a", "b"})
+	})
+	setFlag("new-a", false)
+	waitFor(t, "a to stop", func() bool {
+		return reflect.DeepEqual(testRunning(t, m), []string{"b"})
+	})
+}
+
+func testRunning[C any](t *testing.T, m *Manager[C]) []string {
+	t.Helper()
+	infos, err := m.ListPlugins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := []string{}
+	for _, pm := range infos {
+		running = append(running, pm.Key)
+	}
+	sort.Strings(running)
+	return running
+}
@@ -0,0 +1,233 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned when replaying a call that is not in the
+// recording.
+var ErrNotRecorded = errors.New("call not recorded")
+
+type RecordingMode int
+
+const (
+	// RecordCalls passes calls to the plugins and appends them to the
+	// recording.
+	RecordCalls RecordingMode = iota
+	// ReplayCalls serves calls from the recording without calling the
+	// plugins, which need not be started.
+	ReplayCalls
+)
+
+// RecordedCall is a line of a recording.
+type RecordedCall struct {
+	Time   time.Time `json:"time"`
+	Plugin string    `json:"plugin"`
+	Method string    `json:"method"`
+	// Digest identifies the request before redaction, so replay matches
+	// requests whose recorded copy was redacted.
+	Digest   string          `json:"digest"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// RecordingConfig records the calls made through CallRecorded to a JSON
+// lines file, or replays them from it.
+type RecordingConfig struct {
+	Path string
+	Mode RecordingMode
+	// Redact, when set, is applied to calls before they are written, after
+	// the sensitive values of the plugin were masked.
+	Redact func(call *RecordedCall)
+}
+
+type recorder struct {
+	config RecordingConfig
+
+	mu    sync.Mutex
+	file  *os.File
+	calls map[cacheKey][]RecordedCall
+	err   error
+}
+
+func newRecorder(config RecordingConfig) *recorder {
+	return &recorder{config: config}
+}
+
+func (r *recorder) record(call RecordedCall) error {
+	data, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		if r.file, err = os.OpenFile(r.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600); err != nil {
+			return err
+		}
+	}
+	_, err = r.file.Write(append(data, '\n'))
+	return err
+}
+
+// replay returns the next recorded call for key. Calls recorded more than
+// once are replayed in order, the last one repeating.
+func (r *recorder) replay(key cacheKey) (RecordedCall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil && r.err == nil {
+		r.calls, r.err = loadRecording(r.config.Path)
+	}
+	if r.err != nil {
+		return RecordedCall{}, r.err
+	}
+	queue := r.calls[key]
+	if len(queue) == 0 {
+		return RecordedCall{}, fmt.Errorf("%w: plugin %v method %v", ErrNotRecorded, key.plugin, key.method)
+	}
+	call := queue[0]
+	if len(queue) > 1 {
+		r.calls[key] = queue[1:]
+	}
+	return call, nil
+}
+
+func loadRecording(path string) (map[cacheKey][]RecordedCall, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	calls := make(map[cacheKey][]RecordedCall)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		var call RecordedCall
+		if err := json.Unmarshal(sc.Bytes(), &call); err != nil {
+			return nil, fmt.Errorf("%v:%v: %w", path, line, err)
+		}
+		key := cacheKey{plugin: call.Plugin, method: call.Method, digest: call.Digest}
+		calls[key] = append(calls[key], call)
+	}
+	return calls, sc.Err()
+}
+
+func (r *recorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// CallRecorded is Call for methods whose request and response can be
+// encoded as JSON. With ManagerConfig.Recording it records them, masking
+// the plugin's sensitive values, or replays them instead of calling the
+// plugin; otherwise it behaves like Call.
+func CallRecorded[C, R any](
+	ctx context.Context,
+	m *Manager[C],
+	pluginKey string,
+	method string,
+	req any,
+	fn func(context.Context, C) (R, error),
+) (R, error) {
+	var result R
+	rec := m.recorder
+	if rec == nil {
+		err := m.Call(ctx, pluginKey, method, func(ctx context.Context, impl C) error {
+			var err error
+			result, err = fn(ctx, impl)
+			return err
+		})
+		return result, err
+	}
+	key, err := requestKey(pluginKey, method, req)
+	if err != nil {
+		return result, err
+	}
+
+	if rec.config.Mode == ReplayCalls {
+		if m.isClosed() {
+			return result, ErrManagerClosed
+		}
+		call, err := rec.replay(key)
+		if err != nil {
+			return result, err
+		}
+		if len(call.Response) > 0 {
+			if err := json.Unmarshal(call.Response, &result); err != nil {
+				return result, fmt.Errorf("decoding recorded response of %v: %w", method, err)
+			}
+		}
+		if call.Error != "" {
+			return result, errors.New(call.Error)
+		}
+		return result, nil
+	}
+
+	var secrets []string
+	if p, ok := m.getPlugin(pluginKey); ok {
+		secrets = p.Info.secrets()
+	}
+	start := time.Now()
+	err = m.Call(ctx, pluginKey, method, func(ctx context.Context, impl C) error {
+		var err error
+		result, err = fn(ctx, impl)
+		return err
+	})
+	call := RecordedCall{
+		Time:     start,
+		Plugin:   pluginKey,
+		Method:   method,
+		Digest:   key.digest,
+		Duration: time.Since(start),
+	}
+	call.Request, _ = json.Marshal(req)
+	if err != nil {
+		call.Error = err.Error()
+	} else if call.Response, err = json.Marshal(result); err != nil {
+		m.config.Logger.Warn("failed to record call", LogKeyPlugin, pluginKey, "method", method, LogKeyReason, err)
+		return result, nil
+	}
+	redactCall(&call, secrets)
+	if rec.config.Redact != nil {
+		rec.config.Redact(&call)
+	}
+	if rerr := rec.record(call); rerr != nil {
+		m.config.Logger.Warn("failed to record call", LogKeyPlugin, pluginKey, "method", method, LogKeyReason, rerr)
+	}
+	return result, err
+}
+
+// redactCall masks secrets in a recorded call, in their JSON encoded form
+// in the request and response.
+func redactCall(call *RecordedCall, secrets []string) {
+	if len(secrets) == 0 {
+		return
+	}
+	pairs := make([]string, 0, 4*len(secrets))
+	for _, s := range secrets {
+		encoded, _ := json.Marshal(s)
+		pairs = append(pairs, strings.Trim(string(encoded), `"`), RedactedValue, s, RedactedValue)
+	}
+	r := strings.NewReplacer(pairs...)
+	call.Request = json.RawMessage(r.Replace(string(call.Request)))
+	if call.Response != nil {
+		call.Response = json.RawMessage(r.Replace(string(call.Response)))
+	}
+	call.Error = r.Replace(call.Error)
+}
@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// RedactedValue replaces sensitive values.
+const RedactedValue = "[REDACTED]"
+
+// Redacted returns a copy of pm with the values named in Sensitive masked,
+// as shown in the admin API.
+func (pm PluginInfo) Redacted() PluginInfo {
+	if len(pm.Sensitive) == 0 {
+		return pm
+	}
+	pm.Env = redactMap(pm.Env, pm.Sensitive)
+	pm.Annotations = redactMap(pm.Annotations, pm.Sensitive)
+	if pm.Args != nil {
+		args := make([]string, len(pm.Args))
+		for i, arg := range pm.Args {
+			if prefix, _, ok := pm.sensitiveArg(i); ok {
+				arg = prefix + RedactedValue
+			}
+			args[i] = arg
+		}
+		pm.Args = args
+	}
+	return pm
+}
+
+// sensitiveArg reports whether Args[i] holds the value of a sensitive flag,
+// either as -flag=value or following -flag, and splits it.
+func (pm PluginInfo) sensitiveArg(i int) (prefix, value string, ok bool) {
+	arg := pm.Args[i]
+	if name, value, ok := strings.Cut(arg, "="); ok && pm.sensitiveFlag(name) {
+		return name + "=", value, true
+	}
+	if i > 0 && !strings.Contains(pm.Args[i-1], "=") && pm.sensitiveFlag(pm.Args[i-1]) {
+		return "", arg, true
+	}
+	return "", "", false
+}
+
+func (pm PluginInfo) sensitiveFlag(arg string) bool {
+	return strings.HasPrefix(arg, "-") && contains(pm.Sensitive, strings.TrimLeft(arg, "-"))
+}
+
+// secrets returns the sensitive values of pm.
+func (pm PluginInfo) secrets() []string {
+	var secrets []string
+	for _, name := range pm.Sensitive {
+		if v := pm.Env[name]; v != "" {
+			secrets = append(secrets, v)
+		}
+		if v := pm.Annotations[name]; v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	for i := range pm.Args {
+		if _, v, ok := pm.sensitiveArg(i); ok && v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+	return secrets
+}
+
+// redact masks the sensitive values of pm in s, such as an error message
+// quoting the command line of the plugin.
+func (pm PluginInfo) redact(s string) string {
+	r := secretReplacer(pm.secrets())
+	if r == nil {
+		return s
+	}
+	return r.Replace(s)
+}
+
+func secretReplacer(secrets []string) *strings.Replacer {
+	if len(secrets) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, s := range secrets {
+		pairs = append(pairs, s, RedactedValue)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func redactMap(m map[string]string, sensitive []string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if contains(sensitive, k) {
+			v = RedactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactLogger masks secrets in messages and string values logged through
+// it, such as the command line go-plugin logs when starting a plugin.
+type redactLogger struct {
+	hclog.Logger
+	r *strings.Replacer
+}
+
+func newRedactLogger(l hclog.Logger, secrets []string) hclog.Logger {
+	r := secretReplacer(secrets)
+	if r == nil {
+		return l
+	}
+	return &redactLogger{Logger: l, r: r}
+}
+
+func (l *redactLogger) redact(args []interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			out[i] = l.r.Replace(v)
+		case []string:
+			masked := make([]string, len(v))
+			for j, s := range v {
+				masked[j] = l.r.Replace(s)
+			}
+			out[i] = masked
+		case error:
+			out[i] = l.r.Replace(v.Error())
+		default:
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+func (l *redactLogger) Log(level hclog.Level, msg string, args ...interface{}) {
+	l.Logger.Log(level, l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) Trace(msg string, args ...interface{}) {
+	l.Logger.Trace(l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) Debug(msg string, args ...interface{}) {
+	l.Logger.Debug(l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) Info(msg string, args ...interface{}) {
+	l.Logger.Info(l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) Warn(msg string, args ...interface{}) {
+	l.Logger.Warn(l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) Error(msg string, args ...interface{}) {
+	l.Logger.Error(l.r.Replace(msg), l.redact(args)...)
+}
+
+func (l *redactLogger) With(args ...interface{}) hclog.Logger {
+	return &redactLogger{Logger: l.Logger.With(l.redact(args)...), r: l.r}
+}
+
+func (l *redactLogger) Named(name string) hclog.Logger {
+	return &redactLogger{Logger: l.Logger.Named(name), r: l.r}
+}
+
+func (l *redactLogger) ResetNamed(name string) hclog.Logger {
+	return &redactLogger{Logger: l.Logger.ResetNamed(name), r: l.r}
+}
@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name string
+		pm   PluginInfo
+		want PluginInfo
+	}{
+		{
+			name: "nothing sensitive",
+			pm:   PluginInfo{Args: []string{"-token=abc"}, Env: map[string]string{"TOKEN": "abc"}},
+			want: PluginInfo{Args: []string{"-token=abc"}, Env: map[string]string{"TOKEN": "abc"}},
+		},
+		{
+			name: "env and annotations",
+			pm: PluginInfo{
+				Sensitive:   []string{"TOKEN"},
+				Env:         map[string]string{"TOKEN": "abc", "HOME": "/home/p"},
+				Annotations: map[string]string{"TOKEN": "abc", "owner": "team"},
+			},
+			want: PluginInfo{
+				Sensitive:   []string{"TOKEN"},
+				Env:         map[string]string{"TOKEN": RedactedValue, "HOME": "/home/p"},
+				Annotations: map[string]string{"TOKEN": RedactedValue, "owner": "team"},
+			},
+		},
+		{
+			name: "flags",
+			pm: PluginInfo{
+				Sensitive: []string{"token", "password"},
+				Args:      []string{"-token=abc", "--password", "hunter2", "-v", "--token=", "-user=me"},
+			},
+			want: PluginInfo{
+				Sensitive: []string{"token", "password"},
+				Args:      []string{"-token=" + RedactedValue, "--password", RedactedValue, "-v", "--token=" + RedactedValue, "-user=me"},
+			},
+		},
+		{
+			name: "value of a flag given with =",
+			pm:   PluginInfo{Sensitive: []string{"token"}, Args: []string{"-token=abc", "positional"}},
+			want: PluginInfo{Sensitive: []string{"token"}, Args: []string{"-token=" + RedactedValue, "positional"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.pm
+			origEnv := maps.Clone(tt.pm.Env)
+			origArgs := append([]string(nil), tt.pm.Args...)
+			if got := tt.pm.Redacted(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redacted() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(orig.Env, origEnv) || !reflect.DeepEqual(orig.Args, origArgs) {
+				t.Error("Redacted modified the original")
+			}
+		})
+	}
+}
+
+func TestPluginEventRedacts(t *testing.T) {
+	pm := PluginInfo{
+		Key:       "p",
+		Sensitive: []string{"TOKEN", "password"},
+		Env:       map[string]string{"TOKEN": "s3cret"},
+		Args:      []string{"--password", "hunter2"},
+	}
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"", ""},
+		{"plugin exited", "plugin exited"},
+		{"exec: TOKEN=s3cret failed", "exec: TOKEN=" + RedactedValue + " failed"},
+		{"/bin/p --password hunter2: exit status 1", "/bin/p --password " + RedactedValue + ": exit status 1"},
+	}
+	for _, tt := range tests {
+		e := pluginEvent(EventExited, pm, tt.message)
+		if e.Message != tt.want {
+			t.Errorf("pluginEvent(%q).Message = %q, want %q", tt.message, e.Message, tt.want)
+		}
+		if e.Key != pm.Key {
+			t.Errorf("pluginEvent(%q).Key = %q, want %q", tt.message, e.Key, pm.Key)
+		}
+	}
+}
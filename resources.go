@@ -0,0 +1,9 @@
+package manager
+
+import "time"
+
+// ResourceUsage is the CPU time and resident memory of a plugin process.
+type ResourceUsage struct {
+	CPUTime time.Duration
+	RSS     uint64
+}
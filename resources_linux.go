@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the times in /proc/<pid>/stat, which
+// is 100 on every Linux platform Go supports.
+const clockTicks = 100
+
+func processUsage(pid int) (ResourceUsage, error) {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	// The command name may contain spaces, the fields start after it.
+	i := strings.LastIndexByte(string(b), ')')
+	if i < 0 {
+		return ResourceUsage{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(b[i+1:]))
+	if len(fields) < 22 {
+		return ResourceUsage{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	// fields[0] is the state, field 3 of the file.
+	utime, err1 := strconv.ParseUint(fields[11], 10, 64)
+	stime, err2 := strconv.ParseUint(fields[12], 10, 64)
+	rss, err3 := strconv.ParseUint(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return ResourceUsage{}, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	return ResourceUsage{
+		CPUTime: time.Duration(utime+stime) * time.Second / clockTicks,
+		RSS:     rss * uint64(os.Getpagesize()),
+	}, nil
+}
+
+func processFDs(pid int) (int, error) {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package manager
+
+import "errors"
+
+func processUsage(pid int) (ResourceUsage, error) {
+	return ResourceUsage{}, errors.New("resource usage is only supported on Linux")
+}
+
+func processFDs(pid int) (int, error) {
+	return 0, errors.New("file descriptor counts are only supported on Linux")
+}
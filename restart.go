@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// Crash describes a plugin exit the supervisor noticed.
+type Crash struct {
+	Time time.Time
+	Exit ExitReason
+}
+
+// RestartStrategy decides whether and after how long the supervisor
+// restarts a plugin that exited. info.Restarts counts the restarts so far.
+type RestartStrategy interface {
+	ShouldRestart(info PluginInfo, crash Crash) (time.Duration, bool)
+}
+
+type RestartStrategyFunc func(info PluginInfo, crash Crash) (time.Duration, bool)
+
+func (f RestartStrategyFunc) ShouldRestart(info PluginInfo, crash Crash) (time.Duration, bool) {
+	return f(info, crash)
+}
+
+// RestartAlways restarts plugins immediately, without limit, even when they
+// exited cleanly.
+func RestartAlways() RestartStrategy {
+	return RestartStrategyFunc(func(PluginInfo, Crash) (time.Duration, bool) {
+		return 0, true
+	})
+}
+
+// RestartNever leaves exited plugins stopped.
+func RestartNever() RestartStrategy {
+	return RestartStrategyFunc(func(PluginInfo, Crash) (time.Duration, bool) {
+		return 0, false
+	})
+}
+
+// RestartOnFailure restarts failed plugins immediately until they were
+// restarted maxRestarts times. It is the default, with
+// RestartConfig.MaxRestarts.
+func RestartOnFailure(maxRestarts int) RestartStrategy {
+	return RestartStrategyFunc(func(info PluginInfo, crash Crash) (time.Duration, bool) {
+		return 0, crash.Exit.Failed() && info.Restarts < maxRestarts
+	})
+}
+
+// RestartBackoff restarts failed plugins after a delay starting at initial
+// and doubling with every restart, up to max.
+func RestartBackoff(initial, max time.Duration) RestartStrategy {
+	return RestartStrategyFunc(func(info PluginInfo, crash Crash) (time.Duration, bool) {
+		if !crash.Exit.Failed() {
+			return 0, false
+		}
+		delay := initial
+		for i := 0; i < info.Restarts && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay, true
+	})
+}
+
+// RestartBudget restarts each failed plugin at most max times within any
+// window, like systemd's StartLimitBurst and StartLimitIntervalSec.
+func RestartBudget(max int, window time.Duration) RestartStrategy {
+	return &restartBudget{max: max, window: window, restarts: make(map[string][]time.Time)}
+}
+
+type restartBudget struct {
+	max      int
+	window   time.Duration
+	mu       sync.Mutex
+	restarts map[string][]time.Time
+}
+
+func (b *restartBudget) ShouldRestart(info PluginInfo, crash Crash) (time.Duration, bool) {
+	if !crash.Exit.Failed() {
+		return 0, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recent := b.restarts[info.Key][:0]
+	for _, t := range b.restarts[info.Key] {
+		if crash.Time.Sub(t) < b.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= b.max {
+		b.restarts[info.Key] = recent
+		return 0, false
+	}
+	b.restarts[info.Key] = append(recent, crash.Time)
+	return 0, true
+}
@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+var testFailedExit = ExitReason{Kind: ExitCrash, ExitCode: 1}
+
+func TestRestartBackoff(t *testing.T) {
+	strategy := RestartBackoff(time.Second, 10*time.Second)
+	failed := Crash{Exit: testFailedExit}
+	tests := []struct {
+		restarts int
+		crash    Crash
+		delay    time.Duration
+		ok       bool
+	}{
+		{restarts: 0, crash: failed, delay: time.Second, ok: true},
+		{restarts: 1, crash: failed, delay: 2 * time.Second, ok: true},
+		{restarts: 3, crash: failed, delay: 8 * time.Second, ok: true},
+		{restarts: 4, crash: failed, delay: 10 * time.Second, ok: true},
+		{restarts: 100, crash: failed, delay: 10 * time.Second, ok: true},
+		{restarts: 0, crash: Crash{Exit: ExitReason{Kind: ExitClean}}},
+	}
+	for _, tt := range tests {
+		delay, ok := strategy.ShouldRestart(PluginInfo{Key: "p", Restarts: tt.restarts}, tt.crash)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("ShouldRestart after %v restarts of %v = %v, %v, want %v, %v",
+				tt.restarts, tt.crash.Exit.Kind, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
+func TestRestartBudget(t *testing.T) {
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		crashes []time.Duration // since start
+		exit    ExitReason
+		want    []bool
+	}{
+		{name: "within budget", crashes: []time.Duration{0, 10 * time.Second}, exit: testFailedExit, want: []bool{true, true}},
+		{name: "over budget", crashes: []time.Duration{0, 10 * time.Second, 20 * time.Second}, exit: testFailedExit, want: []bool{true, true, false}},
+		{name: "window passed", crashes: []time.Duration{0, 10 * time.Second, 20 * time.Second, 70 * time.Second}, exit: testFailedExit, want: []bool{true, true, false, true}},
+		{name: "clean exit", crashes: []time.Duration{0}, exit: ExitReason{Kind: ExitClean}, want: []bool{false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := RestartBudget(2, time.Minute)
+			for i, at := range tt.crashes {
+				_, ok := strategy.ShouldRestart(PluginInfo{Key: "p"}, Crash{Time: start.Add(at), Exit: tt.exit})
+				if ok != tt.want[i] {
+					t.Errorf("crash at %v: restart %v, want %v", at, ok, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RestartPlan is what PlanRestart expects restarting plugins to affect.
+type RestartPlan struct {
+	Plugins []PluginRestartPlan `json:"plugins"`
+	// Dependents are the running plugins, other than the restarted ones,
+	// that depend on them directly or transitively.
+	Dependents []string `json:"dependents,omitempty"`
+	// Downtime is the sum of the expected downtimes, plugins being
+	// restarted one at a time.
+	Downtime time.Duration `json:"downtime"`
+}
+
+// OK reports whether every plugin is expected to restart.
+func (p RestartPlan) OK() bool {
+	for _, pp := range p.Plugins {
+		if len(pp.Blockers) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PluginRestartPlan is what restarting a plugin would affect.
+type PluginRestartPlan struct {
+	Key        string `json:"key"`
+	Generation uint64 `json:"generation,omitempty"`
+	// Dependents are the running plugins depending on this one, directly
+	// or transitively, which lose it while it restarts.
+	Dependents []string `json:"dependents,omitempty"`
+	// InFlight is the number of calls in progress, including those made
+	// through a Handle, that the restart would cut off.
+	InFlight int64 `json:"inFlight"`
+	// ExpectedDowntime is the median launch time of the plugin's recorded
+	// starts, out of StartSamples, or the duration of its last start. It is
+	// zero when the plugin has a warm standby, which takes over right away.
+	ExpectedDowntime time.Duration `json:"expectedDowntime"`
+	StartSamples     int           `json:"startSamples"`
+	// Deployment is set when a deployment in progress would be rolled back.
+	Deployment bool `json:"deployment,omitempty"`
+	Standby    bool `json:"standby,omitempty"`
+	// Blockers are the reasons the restart is expected to fail.
+	Blockers []string `json:"blockers,omitempty"`
+}
+
+// PlanRestart reports what restarting the plugins with the given keys would
+// affect, without restarting anything.
+func (m *Manager[C]) PlanRestart(keys []string) (RestartPlan, error) {
+	if m.isClosed() {
+		return RestartPlan{}, ErrManagerClosed
+	}
+	m.mu.RLock()
+	running := make(map[string]*pluginInstance[C], len(m.plugins))
+	for key, p := range m.plugins {
+		running[key] = p
+	}
+	deploys := make(map[string]bool, len(m.deploys))
+	for key := range m.deploys {
+		deploys[key] = true
+	}
+	standbys := make(map[string]bool, len(m.standbys))
+	for key := range m.standbys {
+		standbys[key] = true
+	}
+	m.mu.RUnlock()
+
+	dependents := make(map[string][]string)
+	for key, p := range running {
+		for _, dep := range p.Info.DependsOn {
+			dependents[dep] = append(dependents[dep], key)
+		}
+	}
+	restarted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		restarted[key] = true
+	}
+
+	var plan RestartPlan
+	affected := make(map[string]bool)
+	for _, key := range keys {
+		pp := PluginRestartPlan{Key: key}
+		p, ok := running[key]
+		if !ok {
+			pp.Blockers = append(pp.Blockers, "plugin is not running")
+			plan.Plugins = append(plan.Plugins, pp)
+			continue
+		}
+		pp.Generation = p.Info.Generation
+		pp.InFlight = p.inFlight.Load()
+		pp.Deployment = deploys[key]
+		pp.Standby = standbys[key]
+		pp.Dependents = transitiveDependents(key, dependents)
+		for _, dep := range pp.Dependents {
+			if !restarted[dep] {
+				affected[dep] = true
+			}
+		}
+		pp.ExpectedDowntime, pp.StartSamples = m.expectedStartTime(key)
+		if pp.Standby {
+			pp.ExpectedDowntime = 0
+		}
+		pp.Blockers = m.restartBlockers(p.Info)
+		plan.Downtime += pp.ExpectedDowntime
+		plan.Plugins = append(plan.Plugins, pp)
+	}
+	plan.Dependents = sortedKeys(affected)
+	return plan, nil
+}
+
+func transitiveDependents(key string, dependents map[string][]string) []string {
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	var out []string
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[next] {
+			if !seen[d] {
+				seen[d] = true
+				out = append(out, d)
+				queue = append(queue, d)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *Manager[C]) expectedStartTime(key string) (time.Duration, int) {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+	samples := m.startTimes[key]
+	if len(samples) == 0 {
+		if r, ok := m.startups[key]; ok && r.Error == "" {
+			return r.Total, 1
+		}
+		return 0, 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return percentile(sorted, 0.5), len(sorted)
+}
+
+// restartBlockers checks what would make loadPlugin refuse pm, without its
+// side effects.
+func (m *Manager[C]) restartBlockers(pm PluginInfo) []string {
+	var blockers []string
+	if q, ok := m.quarantine(pm.Key); ok {
+		blockers = append(blockers, fmt.Sprintf("quarantined since %v", q.Since.Format(time.RFC3339)))
+	}
+	if pm.Lifecycle == LifecycleEOL && !m.config.AllowEOL {
+		blockers = append(blockers, ErrPluginEOL.Error())
+	}
+	if pm.License != nil && !pm.License.Expires.IsZero() && !m.config.Clock.Now().Before(pm.License.Expires) {
+		blockers = append(blockers, ErrLicenseExpired.Error())
+	}
+	if _, err := checkHostVersion(m.config.HostVersion, pm); err != nil {
+		blockers = append(blockers, err.Error())
+	}
+	if pm.BinPath != "" {
+		if err := checkBinary(pm.BinPath); err != nil {
+			blockers = append(blockers, err.Error())
+		} else if err := verifyChecksum(pm); err != nil {
+			blockers = append(blockers, err.Error())
+		}
+	}
+	return blockers
+}
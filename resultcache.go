@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type ResultCacheConfig struct {
+	// TTL is how long a result is served from the cache. Zero means results
+	// only expire through eviction or invalidation.
+	TTL time.Duration
+	// MaxEntries bounds the number of cached results. Defaults to 1024.
+	MaxEntries int
+}
+
+type ResultCacheStats struct {
+	Entries   int
+	Hits      int64
+	Misses    int64
+	Evictions int64
+}
+
+type cacheKey struct {
+	plugin string
+	method string
+	digest string
+}
+
+type cacheEntry struct {
+	key        cacheKey
+	generation uint64
+	value      any
+	expires    time.Time
+}
+
+type resultCache struct {
+	mu      sync.Mutex
+	config  ResultCacheConfig
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	stats   ResultCacheStats
+}
+
+func newResultCache(config ResultCacheConfig) *resultCache {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 1024
+	}
+	return &resultCache{
+		config:  config,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func requestKey(pluginKey, method string, req any) (cacheKey, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return cacheKey{}, err
+	}
+	sum := sha256.Sum256(data)
+	return cacheKey{plugin: pluginKey, method: method, digest: hex.EncodeToString(sum[:])}, nil
+}
+
+// get returns the cached result for key if it was produced by the given
+// plugin generation and has not expired.
+func (c *resultCache) get(key cacheKey, generation uint64) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if e.generation != generation || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+		c.removeLocked(el)
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+	return e.value, true
+}
+
+func (c *resultCache) put(key cacheKey, generation uint64, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := &cacheEntry{key: key, generation: generation, value: value}
+	if c.config.TTL > 0 {
+		e.expires = time.Now().Add(c.config.TTL)
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.config.MaxEntries {
+		c.removeLocked(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *resultCache) removeLocked(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
+
+func (c *resultCache) invalidate(match func(cacheKey) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		if match(key) {
+			c.removeLocked(el)
+		}
+	}
+}
+
+// CallCached is Call for idempotent methods: results are cached by plugin,
+// method and the JSON encoding of req, and served from the cache until they
+// expire or the plugin is restarted. Without ManagerConfig.ResultCache it
+// behaves like Call.
+func CallCached[C, R any](
+	ctx context.Context,
+	m *Manager[C],
+	pluginKey string,
+	method string,
+	req any,
+	fn func(context.Context, C) (R, error),
+) (R, error) {
+	var result R
+	call := func(ctx context.Context, impl C) error {
+		var err error
+		result, err = fn(ctx, impl)
+		return err
+	}
+	if m.results == nil {
+		err := m.Call(ctx, pluginKey, method, call)
+		return result, err
+	}
+
+	key, err := requestKey(pluginKey, method, req)
+	if err != nil {
+		return result, err
+	}
+	p, ok := m.getPlugin(pluginKey)
+	if ok {
+		if v, ok := m.results.get(key, p.Info.Generation); ok {
+			return v.(R), nil
+		}
+	}
+
+	err = m.Call(ctx, pluginKey, method, call)
+	if err != nil {
+		return result, err
+	}
+	if ok {
+		m.results.put(key, p.Info.Generation, result)
+	}
+	return result, nil
+}
+
+func (m *Manager[C]) ResultCacheStats() ResultCacheStats {
+	if m.results == nil {
+		return ResultCacheStats{}
+	}
+	m.results.mu.Lock()
+	defer m.results.mu.Unlock()
+	stats := m.results.stats
+	stats.Entries = m.results.lru.Len()
+	return stats
+}
+
+// InvalidateResults drops every cached result of a plugin.
+func (m *Manager[C]) InvalidateResults(pluginKey string) {
+	if m.results == nil {
+		return
+	}
+	m.results.invalidate(func(key cacheKey) bool { return key.plugin == pluginKey })
+}
+
+// InvalidateResult drops the cached result of a single request.
+func (m *Manager[C]) InvalidateResult(pluginKey, method string, req any) error {
+	if m.results == nil {
+		return nil
+	}
+	target, err := requestKey(pluginKey, method, req)
+	if err != nil {
+		return err
+	}
+	m.results.invalidate(func(key cacheKey) bool { return key == target })
+	return nil
+}
@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const readinessPollInterval = 100 * time.Millisecond
+
+type RollingRestartOptions struct {
+	// MaxUnavailable is the number of plugins restarted at the same time.
+	// Defaults to 1.
+	MaxUnavailable int
+	// ReadinessTimeout bounds how long a restarted plugin may take to answer
+	// a ping. Defaults to the ping timeout.
+	ReadinessTimeout time.Duration
+	// Update, when set, returns the info a plugin is restarted with, e.g. to
+	// point it at a new binary and checksum.
+	Update func(PluginInfo) PluginInfo
+	// Analyzer, when set, is consulted after each batch becomes ready with
+	// the calls served by the batch and by the members not yet restarted
+	// during AnalysisInterval. A rollback decision aborts the restart.
+	Analyzer         Analyzer
+	AnalysisInterval time.Duration
+}
+
+// RollingRestartError is returned when a rolling restart is aborted. It
+// carries what is needed to roll the group back.
+type RollingRestartError struct {
+	Group string
+	// Previous holds the info of every plugin that was restarted, including
+	// the failed ones, as it was before the restart.
+	Previous []PluginInfo
+	Failed   map[string]error
+	Pending  []string
+}
+
+func (e *RollingRestartError) Error() string {
+	keys := make([]string, 0, len(e.Failed))
+	for key, err := range e.Failed {
+		keys = append(keys, fmt.Sprintf("%v: %v", key, err))
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf(
+		"rolling restart of group %v aborted: %v",
+		e.Group,
+		strings.Join(keys, "; "),
+	)
+}
+
+func (m *Manager[C]) RollingRestart(group string, opts RollingRestartOptions) error {
+	if opts.MaxUnavailable <= 0 {
+		opts.MaxUnavailable = 1
+	}
+	if opts.ReadinessTimeout == 0 {
+		opts.ReadinessTimeout = m.config.RestartConfig.PingTimeout
+	}
+	if opts.Analyzer != nil && opts.AnalysisInterval <= 0 {
+		opts.AnalysisInterval = time.Minute
+	}
+
+	infos, err := m.ListPlugins()
+	if err != nil {
+		return err
+	}
+	members := []PluginInfo{}
+	for _, info := range infos {
+		if info.Group == group {
+			members = append(members, info)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Key < members[j].Key })
+
+	var previous []PluginInfo
+	for start := 0; start < len(members); start += opts.MaxUnavailable {
+		end := min(start+opts.MaxUnavailable, len(members))
+		batch := members[start:end]
+		previous = append(previous, batch...)
+
+		failed := m.restartBatch(batch, opts)
+		if len(failed) == 0 && opts.Analyzer != nil && end < len(members) {
+			if m.analyzeBatch(group, batch, members[end:], opts) == DecisionRollback {
+				for _, info := range batch {
+					failed[info.Key] = ErrAnalysisRollback
+				}
+			}
+		}
+		if len(failed) > 0 {
+			pending := []string{}
+			for _, info := range members[end:] {
+				pending = append(pending, info.Key)
+			}
+			return &RollingRestartError{
+				Group:    group,
+				Previous: previous,
+				Failed:   failed,
+				Pending:  pending,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager[C]) restartBatch(batch []PluginInfo, opts RollingRestartOptions) map[string]error {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = map[string]error{}
+	)
+	for _, info := range batch {
+		wg.Add(1)
+		go func(info PluginInfo) {
+			defer wg.Done()
+			if opts.Update != nil {
+				info = opts.Update(info)
+			}
+			err := m.RestartPlugin(info)
+			if err == nil {
+				err = m.waitReady(info.Key, opts.ReadinessTimeout)
+			}
+			if err != nil {
+				mu.Lock()
+				failed[info.Key] = err
+				mu.Unlock()
+			}
+		}(info)
+	}
+	wg.Wait()
+	return failed
+}
+
+func (m *Manager[C]) waitReady(pluginKey string, timeout time.Duration) error {
+	clock := m.config.Clock
+	deadline := clock.Now().Add(timeout)
+	for {
+		p, ok := m.getPlugin(pluginKey)
+		if ok && p.Ping() == nil {
+			return nil
+		}
+		if clock.Now().After(deadline) {
+			return fmt.Errorf("plugin %v not ready after %v", pluginKey, timeout)
+		}
+		if err := sleep(m.ctx, clock, readinessPollInterval); err != nil {
+			return ErrManagerClosed
+		}
+	}
+}
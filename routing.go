@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+)
+
+// Common route attributes. Hosts may use any others.
+const (
+	RouteTenant = "tenant"
+	RouteRegion = "region"
+	RouteBucket = "bucket"
+)
+
+// RouteAttributes describe a call to the rules of a deployment, such as the
+// tenant or region it is made for or its experiment bucket.
+type RouteAttributes map[string]string
+
+type routeAttributesKey struct{}
+
+// WithRouteAttributes returns a context whose calls are routed by attrs,
+// merged over the attributes ctx already carries.
+func WithRouteAttributes(ctx context.Context, attrs RouteAttributes) context.Context {
+	merged := make(RouteAttributes, len(attrs))
+	for k, v := range RouteAttributesFrom(ctx) {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, routeAttributesKey{}, merged)
+}
+
+func RouteAttributesFrom(ctx context.Context) RouteAttributes {
+	attrs, _ := ctx.Value(routeAttributesKey{}).(RouteAttributes)
+	return attrs
+}
+
+// RouteRule routes Percent of the calls whose attributes include all of
+// Match to the deployed instance: 100 pins a traffic slice to it and 0
+// keeps the slice on the old one.
+type RouteRule struct {
+	Match   RouteAttributes `json:"match"`
+	Percent int             `json:"percent"`
+}
+
+func (r RouteRule) matches(attrs RouteAttributes) bool {
+	for k, v := range r.Match {
+		if got, ok := attrs[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+func validateRouteRules(rules []RouteRule) error {
+	for i, r := range rules {
+		if len(r.Match) == 0 {
+			return fmt.Errorf("route rule %v matches no attributes", i)
+		}
+		if r.Percent < 0 || r.Percent > 100 {
+			return fmt.Errorf("route rule %v: invalid traffic percentage %v", i, r.Percent)
+		}
+	}
+	return nil
+}
+
+// routeGreen decides whether a call with attrs goes to the deployed
+// instance. The first matching rule picks the percentage, opts.Percent
+// applies otherwise. With opts.HashAttribute set, calls carrying it are
+// split by its hash so the same tenant or user always lands on the same
+// instance.
+func routeGreen(opts DeployOptions, attrs RouteAttributes) bool {
+	percent := opts.Percent
+	for _, r := range opts.Rules {
+		if r.matches(attrs) {
+			percent = r.Percent
+			break
+		}
+	}
+	switch percent {
+	case 0:
+		return false
+	case 100:
+		return true
+	}
+	if v, ok := attrs[opts.HashAttribute]; ok && opts.HashAttribute != "" {
+		h := fnv.New32a()
+		h.Write([]byte(v))
+		return int(h.Sum32()%100) < percent
+	}
+	return rand.Intn(100) < percent
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrManagerStarted = errors.New("plugin manager already started")
+
+// Start launches the supervisor and the health checks of every loaded
+// plugin. Plugins loaded afterwards are watched as soon as they are loaded.
+func (m *Manager[C]) Start() error {
+	if err := m.selfCheck(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrManagerClosed
+	}
+	if m.started {
+		return ErrManagerStarted
+	}
+	m.started = true
+
+	mac := CheckMAC()
+	m.config.Logger.Debug("mandatory access control", "apparmor", mac.AppArmor, "selinux", mac.SELinux)
+
+	if m.config.RestartConfig.Managed {
+		go m.supervisor()
+	}
+	if m.config.CertRotationInterval > 0 && (m.config.AutoMTLS || m.config.TLSConfig != nil) {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopRotation = cancel
+		go m.rotateCertificates(ctx, m.config.CertRotationInterval)
+	}
+	if m.config.BinaryCheckInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopBinaryWatch = cancel
+		go m.watchBinaries(ctx, m.config.BinaryCheckInterval)
+	}
+	if m.config.Janitor != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopJanitor = cancel
+		go m.janitor(ctx, m.config.Janitor.Interval)
+	}
+	if m.config.Webhooks != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopWebhooks = cancel
+		m.startWebhooks(ctx, m.config.Webhooks)
+	}
+	if m.config.Alerts != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopAlerts = cancel
+		m.startAlerts(ctx, m.config.Alerts)
+	}
+	if len(m.config.EventSinks) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopSinks = cancel
+		m.startEventSinks(ctx, m.config.EventSinks)
+	}
+	if m.config.Licenses != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopLicenses = cancel
+		go m.revalidateLicenses(ctx, m.config.Licenses.Interval)
+	}
+	if m.config.FeatureFlags != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.stopFlags = cancel
+		go m.watchFeatureFlags(ctx, m.config.FeatureFlags.Interval)
+	}
+	for _, p := range m.plugins {
+		m.watchLocked(p)
+	}
+	for _, d := range m.deploys {
+		m.watchLocked(d.green)
+	}
+	for _, s := range m.standbys {
+		m.watchLocked(s)
+	}
+	return nil
+}
+
+// Run starts the manager and shuts it down once ctx is done.
+func (m *Manager[C]) Run(ctx context.Context) error {
+	if err := m.Start(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return m.Shutdown()
+}
+
+// watchLocked starts the health check of p if the manager is running. It
+// must be called with m.mu held.
+func (m *Manager[C]) watchLocked(p *pluginInstance[C]) {
+	if !m.started {
+		return
+	}
+	p.startWatch(func() {
+		p.Watch(m.logFor(p.Info), m.config.RestartConfig, p.killed, m.publishHealth, m.watcherPanicked)
+	})
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"os/exec"
+)
+
+// SandboxConfig restricts what a plugin process may do. Options that the
+// host platform cannot enforce make the plugin fail to start.
+type SandboxConfig struct {
+	// AppArmorProfile confines the plugin to the named AppArmor profile.
+	AppArmorProfile string `json:"appArmorProfile,omitempty" yaml:"appArmorProfile,omitempty"`
+	// SELinuxLabel runs the plugin in the given SELinux context.
+	SELinuxLabel string `json:"seLinuxLabel,omitempty" yaml:"seLinuxLabel,omitempty"`
+	// NoNetwork runs the plugin in its own network namespace without any
+	// interfaces but loopback. The plugin still reaches the host over its
+	// unix socket.
+	NoNetwork bool `json:"noNetwork,omitempty" yaml:"noNetwork,omitempty"`
+	// ReadOnlyRoot gives the plugin a read-only view of the filesystem in
+	// which only WritablePaths, the directory holding its sockets and a
+	// private, empty temporary directory can be written. It requires
+	// bubblewrap (bwrap); Landlock is not used.
+	ReadOnlyRoot  bool     `json:"readOnlyRoot,omitempty" yaml:"readOnlyRoot,omitempty"`
+	WritablePaths []string `json:"writablePaths,omitempty" yaml:"writablePaths,omitempty"`
+	// KeepPrivileges opts out of launching the plugin without capabilities
+	// and with no_new_privs set, which is the default on Linux and needs
+	// setpriv, see ManagerConfig.AllowUnsandboxed.
+	KeepPrivileges bool `json:"keepPrivileges,omitempty" yaml:"keepPrivileges,omitempty"`
+}
+
+// AppliedSandbox records the restrictions a plugin was actually started
+// with.
+type AppliedSandbox struct {
+	NoNewPrivs bool
+	// CapabilitiesDropped is set when the plugin was started without
+	// inheritable and ambient capabilities, and by a root host without a
+	// bounding set either.
+	CapabilitiesDropped bool
+	NetworkIsolated     bool
+	ReadOnlyRoot        bool
+	AppArmorProfile     string
+	SELinuxLabel        string
+}
+
+// MACStatus reports which mandatory access control systems are enforced on
+// the host.
+type MACStatus struct {
+	AppArmor bool
+	SELinux  bool
+}
+
+// command builds the command launching the plugin, wrapped in the helpers
+// applying its sandbox, and records in pm which isolation was applied.
+// writable lists paths the plugin must be able to write to under
+// ReadOnlyRoot, besides those it was configured with, such as its socket
+// directory. Without allowUnsandboxed, failing to drop privileges fails.
+func command(ctx context.Context, pm *PluginInfo, allowUnsandboxed bool, writable ...string) (*exec.Cmd, AppliedSandbox, error) {
+	config := SandboxConfig{}
+	if pm.Sandbox != nil {
+		config = *pm.Sandbox
+	}
+	config.WritablePaths = append(append([]string(nil), config.WritablePaths...), writable...)
+
+	argv, applied, err := sandboxArgs(config, allowUnsandboxed, append([]string{pm.BinPath}, pm.Args...))
+	if err != nil {
+		return nil, applied, err
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	// The plugin inherits the host environment, pm.Env overriding it.
+	cmd.Env = os.Environ()
+	for _, k := range sortedKeys(pm.Env) {
+		cmd.Env = append(cmd.Env, k+"="+pm.Env[k])
+	}
+	if err := sandboxProcess(config, cmd); err != nil {
+		return nil, applied, err
+	}
+	applied.NetworkIsolated = config.NoNetwork
+	pm.NetworkIsolated = config.NoNetwork
+	return cmd, applied, nil
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func CheckMAC() MACStatus {
+	status := MACStatus{}
+	if data, err := os.ReadFile("/sys/module/apparmor/parameters/enabled"); err == nil {
+		status.AppArmor = strings.TrimSpace(string(data)) == "Y"
+	}
+	if data, err := os.ReadFile("/sys/fs/selinux/enforce"); err == nil {
+		status.SELinux = strings.TrimSpace(string(data)) == "1"
+	}
+	return status
+}
+
+func sandboxProcess(config SandboxConfig, cmd *exec.Cmd) error {
+	if !config.NoNetwork {
+		return nil
+	}
+	attr := &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWNET}
+	if os.Geteuid() != 0 {
+		// Unprivileged processes need a user namespace to create a network
+		// namespace. Map the current user so file permissions are unchanged.
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Geteuid(), HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getegid(), HostID: os.Getegid(), Size: 1}}
+	}
+	cmd.SysProcAttr = attr
+	return nil
+}
+
+// sandboxArgs wraps argv in the helpers applying the sandbox. Privileges
+// are dropped innermost, right before the plugin is executed, so the outer
+// helpers can still change the security context.
+func sandboxArgs(config SandboxConfig, allowUnsandboxed bool, argv []string) ([]string, AppliedSandbox, error) {
+	applied := AppliedSandbox{}
+	if config.AppArmorProfile != "" && config.SELinuxLabel != "" {
+		return nil, applied, errors.New("sandbox cannot apply both an AppArmor profile and an SELinux label")
+	}
+
+	if !config.KeepPrivileges {
+		path, err := exec.LookPath("setpriv")
+		switch {
+		case err == nil:
+			wrapped := []string{path, "--no-new-privs", "--inh-caps=-all", "--ambient-caps=-all"}
+			if os.Geteuid() == 0 {
+				// Dropping the bounding set needs CAP_SETPCAP.
+				wrapped = append(wrapped, "--bounding-set=-all")
+			}
+			argv = append(append(wrapped, "--"), argv...)
+			applied.NoNewPrivs = true
+			applied.CapabilitiesDropped = true
+		case !allowUnsandboxed:
+			return nil, applied, fmt.Errorf("dropping privileges: %w; install util-linux or set ManagerConfig.AllowUnsandboxed", err)
+		}
+	}
+
+	mac := CheckMAC()
+	if config.AppArmorProfile != "" {
+		if !mac.AppArmor {
+			return nil, applied, errors.New("AppArmor is not enabled on this host")
+		}
+		path, err := exec.LookPath("aa-exec")
+		if err != nil {
+			return nil, applied, fmt.Errorf("applying AppArmor profile: %w", err)
+		}
+		argv = append([]string{path, "-p", config.AppArmorProfile, "--"}, argv...)
+		applied.AppArmorProfile = config.AppArmorProfile
+	}
+	if config.ReadOnlyRoot {
+		path, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, applied, fmt.Errorf("applying read-only filesystem: %w", err)
+		}
+		// The plugin gets a private temporary directory rather than the
+		// host's, which holds the sockets of the other plugins. Writable
+		// paths under it are bound over it.
+		wrapped := []string{path, "--ro-bind", "/", "/", "--dev-bind", "/dev", "/dev", "--tmpfs", os.TempDir()}
+		for _, p := range config.WritablePaths {
+			if !filepath.IsAbs(p) {
+				return nil, applied, fmt.Errorf("writable path %q is not absolute", p)
+			}
+			wrapped = append(wrapped, "--bind", p, p)
+		}
+		argv = append(append(wrapped, "--"), argv...)
+		applied.ReadOnlyRoot = true
+		// bwrap always sets no_new_privs.
+		applied.NoNewPrivs = true
+	}
+	if config.SELinuxLabel != "" {
+		if !mac.SELinux {
+			return nil, applied, errors.New("SELinux is not enforcing on this host")
+		}
+		path, err := exec.LookPath("runcon")
+		if err != nil {
+			return nil, applied, fmt.Errorf("applying SELinux label: %w", err)
+		}
+		argv = append([]string{path, config.SELinuxLabel}, argv...)
+		applied.SELinuxLabel = config.SELinuxLabel
+	}
+	return argv, applied, nil
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package manager
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func CheckMAC() MACStatus {
+	return MACStatus{}
+}
+
+func sandboxProcess(config SandboxConfig, cmd *exec.Cmd) error {
+	if config.NoNetwork {
+		return errors.New("network isolation is only supported on Linux")
+	}
+	return nil
+}
+
+func sandboxArgs(config SandboxConfig, allowUnsandboxed bool, argv []string) ([]string, AppliedSandbox, error) {
+	if config.AppArmorProfile != "" || config.SELinuxLabel != "" {
+		return nil, AppliedSandbox{}, errors.New("mandatory access control is only supported on Linux")
+	}
+	if config.ReadOnlyRoot {
+		return nil, AppliedSandbox{}, errors.New("read-only filesystem sandboxing is only supported on Linux")
+	}
+	return argv, AppliedSandbox{}, nil
+}
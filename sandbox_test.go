@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPluginEnvOverridesHost(t *testing.T) {
+	t.Setenv(testGreetingEnv, "host")
+	m := newTestManager(t, ManagerConfig{})
+
+	pm := testPluginInfo(t, "p")
+	pm.Env = map[string]string{testGreetingEnv: "plugin"}
+	if _, err := m.StartPlugin(pm); err != nil {
+		t.Fatal(err)
+	}
+	g, err := m.GetPlugin(pm.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := g.Greet(); err != nil || got != "plugin" {
+		t.Errorf("plugin Greet = %q, %v, want %q", got, err, "plugin")
+	}
+
+	task := PluginInfo{
+		Key:     "task",
+		BinPath: "/bin/sh",
+		Args:    []string{"-c", "printf %s \"$" + testGreetingEnv + "\""},
+		Env:     map[string]string{testGreetingEnv: "task"},
+	}
+	result, err := m.RunTask(context.Background(), task, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(result.Output); got != "task" {
+		t.Errorf("task output = %q, want %q", got, "task")
+	}
+}
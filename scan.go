@@ -0,0 +1,372 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventVulnerable is published when a scan finds vulnerabilities at or
+// above ScanPolicy.WarnAt, whether or not the plugin is blocked.
+const EventVulnerable EventType = "vulnerable"
+
+var ErrVulnerable = errors.New("plugin binary has vulnerabilities")
+
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"unknown", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity accepts the severities of common scanners in any case;
+// negligible counts as low.
+func ParseSeverity(s string) Severity {
+	s = strings.ToLower(s)
+	if s == "negligible" {
+		return SeverityLow
+	}
+	for i, name := range severityNames {
+		if s == name {
+			return Severity(i)
+		}
+	}
+	return SeverityUnknown
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(b []byte) error {
+	*s = ParseSeverity(string(b))
+	return nil
+}
+
+type Vulnerability struct {
+	ID           string   `json:"id"`
+	Severity     Severity `json:"severity"`
+	Package      string   `json:"package,omitempty"`
+	Version      string   `json:"version,omitempty"`
+	FixedVersion string   `json:"fixedVersion,omitempty"`
+	Title        string   `json:"title,omitempty"`
+}
+
+// ScanRequest is the artifact a Scanner checks.
+type ScanRequest struct {
+	Plugin PluginInfo
+	// Path is the binary that is about to be launched.
+	Path     string
+	Checksum string
+}
+
+// ScanAction is what the ScanPolicy decided for a ScanResult.
+type ScanAction string
+
+const (
+	ScanAllow ScanAction = "allow"
+	ScanWarn  ScanAction = "warn"
+	ScanBlock ScanAction = "block"
+)
+
+type ScanResult struct {
+	Scanner         string          `json:"scanner,omitempty"`
+	Checksum        string          `json:"checksum"`
+	Time            time.Time       `json:"time"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+	// Action and Highest are set by the manager.
+	Action  ScanAction `json:"action"`
+	Highest Severity   `json:"highest"`
+}
+
+// Scanner checks plugin binaries for known vulnerabilities before they are
+// launched. TrivyScanner and GrypeScanner call out to those tools.
+type Scanner interface {
+	Scan(ctx context.Context, req ScanRequest) (ScanResult, error)
+}
+
+type ScannerFunc func(ctx context.Context, req ScanRequest) (ScanResult, error)
+
+func (f ScannerFunc) Scan(ctx context.Context, req ScanRequest) (ScanResult, error) {
+	return f(ctx, req)
+}
+
+// ScanPolicy decides what happens to plugins with vulnerabilities.
+type ScanPolicy struct {
+	Scanner Scanner
+	// BlockAt refuses plugins with vulnerabilities of this severity or
+	// higher. Defaults to SeverityCritical.
+	BlockAt Severity
+	// WarnAt logs and publishes EventVulnerable for vulnerabilities of this
+	// severity or higher. Defaults to SeverityHigh.
+	WarnAt Severity
+	// AllowOnError starts plugins whose scan failed instead of refusing
+	// them.
+	AllowOnError bool
+	// Timeout bounds each scan, 5 minutes by default.
+	Timeout time.Duration
+	// CacheTTL is how long the result for a checksum is reused, including
+	// across restarts, 24 hours by default.
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]ScanResult
+	// pending holds a channel per checksum being scanned, closed when the
+	// scan ends, so concurrent starts of a binary share one scan.
+	pending map[string]chan struct{}
+}
+
+func scanDefaults(p *ScanPolicy) {
+	if p.BlockAt == SeverityUnknown {
+		p.BlockAt = SeverityCritical
+	}
+	if p.WarnAt == SeverityUnknown {
+		p.WarnAt = SeverityHigh
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 5 * time.Minute
+	}
+	if p.CacheTTL == 0 {
+		p.CacheTTL = 24 * time.Hour
+	}
+}
+
+// runScan scans the binary of pm, caches the result and wakes the starts
+// waiting for it.
+func (m *Manager[C]) runScan(pm PluginInfo, checksum string) (ScanResult, error) {
+	policy := m.config.Scan
+	ctx, cancel := context.WithTimeout(m.ctx, policy.Timeout)
+	result, err := policy.Scanner.Scan(ctx, ScanRequest{Plugin: pm, Path: pm.BinPath, Checksum: checksum})
+	cancel()
+	if err == nil {
+		result.Checksum = checksum
+		if result.Time.IsZero() {
+			result.Time = time.Now()
+		}
+	}
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+	if err == nil {
+		if policy.cache == nil {
+			policy.cache = make(map[string]ScanResult)
+		}
+		policy.cache[checksum] = result
+	}
+	close(policy.pending[checksum])
+	delete(policy.pending, checksum)
+	return result, err
+}
+
+// scan runs the configured Scanner on the binary of pm and applies the
+// policy.
+func (m *Manager[C]) scan(pm PluginInfo) (*ScanResult, error) {
+	policy := m.config.Scan
+	if policy == nil || policy.Scanner == nil {
+		return nil, nil
+	}
+	checksum, err := m.binaryDigest(pm.BinPath)
+	if err != nil {
+		return nil, err
+	}
+	log := m.logFor(pm)
+
+	// Scans run without m.mu, and are abandoned on shutdown.
+	var result ScanResult
+	for {
+		policy.mu.Lock()
+		cached, ok := policy.cache[checksum]
+		fresh := ok && time.Since(cached.Time) <= policy.CacheTTL
+		wait, scanning := policy.pending[checksum]
+		if !fresh && !scanning {
+			if policy.pending == nil {
+				policy.pending = make(map[string]chan struct{})
+			}
+			policy.pending[checksum] = make(chan struct{})
+		}
+		policy.mu.Unlock()
+		if fresh || !scanning {
+			result = cached
+			if !fresh {
+				result, err = m.runScan(pm, checksum)
+			}
+			break
+		}
+		select {
+		case <-wait:
+		case <-m.ctx.Done():
+			return nil, ErrManagerClosed
+		}
+	}
+	if err != nil {
+		if policy.AllowOnError {
+			log.Warn("failed to scan plugin binary", LogKeyReason, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning plugin %v: %w", pm.Key, err)
+	}
+
+	result.Highest = SeverityUnknown
+	var flagged []string
+	for _, v := range result.Vulnerabilities {
+		if v.Severity > result.Highest {
+			result.Highest = v.Severity
+		}
+		if v.Severity >= policy.WarnAt || v.Severity >= policy.BlockAt {
+			flagged = append(flagged, v.ID)
+		}
+	}
+	switch {
+	case result.Highest >= policy.BlockAt:
+		result.Action = ScanBlock
+	case result.Highest >= policy.WarnAt:
+		result.Action = ScanWarn
+	default:
+		result.Action = ScanAllow
+	}
+	if result.Action == ScanAllow {
+		return &result, nil
+	}
+
+	msg := fmt.Sprintf("%v %v or higher vulnerabilities: %v", len(flagged), min(policy.WarnAt, policy.BlockAt), strings.Join(flagged, ", "))
+	e := pluginEvent(EventVulnerable, pm, msg)
+	e.Data = result
+	m.events.publish(e)
+	if result.Action == ScanBlock {
+		return nil, fmt.Errorf("%w: plugin %v: %v", ErrVulnerable, pm.Key, msg)
+	}
+	log.Warn("plugin binary has vulnerabilities", "highest", result.Highest, "vulnerabilities", flagged)
+	return &result, nil
+}
+
+// TrivyScanner scans binaries with "trivy rootfs".
+type TrivyScanner struct {
+	// Path defaults to "trivy" in PATH.
+	Path string
+	Args []string
+}
+
+func (s TrivyScanner) Scan(ctx context.Context, req ScanRequest) (ScanResult, error) {
+	path := s.Path
+	if path == "" {
+		path = "trivy"
+	}
+	args := append([]string{"rootfs", "--format", "json", "--quiet"}, s.Args...)
+	out, err := runScanner(ctx, path, append(args, req.Path)...)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	var report struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+			}
+		}
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return ScanResult{}, fmt.Errorf("decoding trivy report: %w", err)
+	}
+	result := ScanResult{Scanner: "trivy"}
+	for _, r := range report.Results {
+		for _, v := range r.Vulnerabilities {
+			result.Vulnerabilities = append(result.Vulnerabilities, Vulnerability{
+				ID:           v.VulnerabilityID,
+				Severity:     ParseSeverity(v.Severity),
+				Package:      v.PkgName,
+				Version:      v.InstalledVersion,
+				FixedVersion: v.FixedVersion,
+				Title:        v.Title,
+			})
+		}
+	}
+	return result, nil
+}
+
+// GrypeScanner scans binaries with grype.
+type GrypeScanner struct {
+	// Path defaults to "grype" in PATH.
+	Path string
+	Args []string
+}
+
+func (s GrypeScanner) Scan(ctx context.Context, req ScanRequest) (ScanResult, error) {
+	path := s.Path
+	if path == "" {
+		path = "grype"
+	}
+	args := append([]string{"--output", "json", "--quiet"}, s.Args...)
+	out, err := runScanner(ctx, path, append(args, "file:"+req.Path)...)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string `json:"id"`
+				Severity    string `json:"severity"`
+				Description string `json:"description"`
+				Fix         struct {
+					Versions []string `json:"versions"`
+				} `json:"fix"`
+			} `json:"vulnerability"`
+			Artifact struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"artifact"`
+		} `json:"matches"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return ScanResult{}, fmt.Errorf("decoding grype report: %w", err)
+	}
+	result := ScanResult{Scanner: "grype"}
+	for _, match := range report.Matches {
+		v := Vulnerability{
+			ID:       match.Vulnerability.ID,
+			Severity: ParseSeverity(match.Vulnerability.Severity),
+			Package:  match.Artifact.Name,
+			Version:  match.Artifact.Version,
+			Title:    match.Vulnerability.Description,
+		}
+		if len(match.Vulnerability.Fix.Versions) > 0 {
+			v.FixedVersion = match.Vulnerability.Fix.Versions[0]
+		}
+		result.Vulnerabilities = append(result.Vulnerabilities, v)
+	}
+	return result, nil
+}
+
+func runScanner(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %w: %v", path, err, msg)
+		}
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return out, nil
+}
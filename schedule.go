@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type OverlapPolicy string
+
+const (
+	// OverlapSkip skips a run while the previous one is still running.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue starts a run as soon as the previous one finishes. At
+	// most one run is queued.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapReplace cancels the running task and starts a new one.
+	OverlapReplace OverlapPolicy = "replace"
+)
+
+const (
+	EventTaskFailed  EventType = "task_failed"
+	EventTaskSkipped EventType = "task_skipped"
+)
+
+const taskHistorySize = 100
+
+// TaskSchedule runs a plugin in task mode on a cron schedule.
+type TaskSchedule struct {
+	Cron    string        `json:"cron" yaml:"cron"`
+	Overlap OverlapPolicy `json:"overlap,omitempty" yaml:"overlap,omitempty"`
+	Input   []byte        `json:"input,omitempty" yaml:"input,omitempty"`
+}
+
+type TaskRun struct {
+	Start    time.Time
+	Duration time.Duration
+	ExitCode int
+	Err      string
+}
+
+type scheduledTask[C any] struct {
+	mu      sync.Mutex
+	info    PluginInfo
+	spec    *cronSpec
+	cancel  context.CancelFunc
+	running context.CancelFunc
+	queued  bool
+	history []TaskRun
+}
+
+// Schedule runs the plugin in task mode according to pm.Schedule until
+// Unschedule is called or the manager shuts down.
+func (m *Manager[C]) Schedule(pm PluginInfo) error {
+	if pm.Schedule == nil {
+		return fmt.Errorf("plugin %v has no schedule", pm.Key)
+	}
+	spec, err := parseCron(pm.Schedule.Cron)
+	if err != nil {
+		return err
+	}
+	schedule := *pm.Schedule
+	pm.Schedule = &schedule
+	switch pm.Schedule.Overlap {
+	case "":
+		pm.Schedule.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapReplace:
+	default:
+		return fmt.Errorf("plugin %v has unknown overlap policy %q", pm.Key, pm.Schedule.Overlap)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &scheduledTask[C]{info: pm, spec: spec, cancel: cancel}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		cancel()
+		return ErrManagerClosed
+	}
+	if _, ok := m.schedules[pm.Key]; ok {
+		m.mu.Unlock()
+		cancel()
+		return fmt.Errorf("plugin %v is already scheduled", pm.Key)
+	}
+	m.schedules[pm.Key] = t
+	m.mu.Unlock()
+
+	go m.runSchedule(ctx, t)
+	return nil
+}
+
+func (m *Manager[C]) Unschedule(pluginKey string) error {
+	if !m.unschedule(pluginKey) {
+		return fmt.Errorf("plugin %v is not scheduled", pluginKey)
+	}
+	return nil
+}
+
+func (m *Manager[C]) unschedule(pluginKey string) bool {
+	m.mu.Lock()
+	t, ok := m.schedules[pluginKey]
+	delete(m.schedules, pluginKey)
+	m.mu.Unlock()
+	if ok {
+		t.cancel()
+	}
+	return ok
+}
+
+// scheduled returns the plugins registered with Schedule.
+func (m *Manager[C]) scheduled() map[string]PluginInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	infos := make(map[string]PluginInfo, len(m.schedules))
+	for key, t := range m.schedules {
+		infos[key] = t.info
+	}
+	return infos
+}
+
+// TaskHistory returns the most recent runs of a scheduled plugin, oldest
+// first.
+func (m *Manager[C]) TaskHistory(pluginKey string) ([]TaskRun, error) {
+	m.mu.RLock()
+	t, ok := m.schedules[pluginKey]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("plugin %v is not scheduled", pluginKey)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TaskRun(nil), t.history...), nil
+}
+
+func (m *Manager[C]) runSchedule(ctx context.Context, t *scheduledTask[C]) {
+	clock := m.config.Clock
+	for {
+		now := clock.Now()
+		next := t.spec.next(now)
+		if next.IsZero() {
+			return
+		}
+		if err := sleep(ctx, clock, next.Sub(now)); err != nil {
+			return
+		}
+
+		t.mu.Lock()
+		switch {
+		case t.running == nil:
+			m.startRun(ctx, t)
+		case t.info.Schedule.Overlap == OverlapQueue:
+			t.queued = true
+		case t.info.Schedule.Overlap == OverlapReplace:
+			t.running()
+			t.queued = true
+		default:
+			m.events.publish(pluginEvent(EventTaskSkipped, t.info, "previous run still in progress"))
+		}
+		t.mu.Unlock()
+	}
+}
+
+// startRun runs the task in a new goroutine. It must be called with t.mu
+// held.
+func (m *Manager[C]) startRun(ctx context.Context, t *scheduledTask[C]) {
+	runCtx, cancel := context.WithCancel(ctx)
+	t.running = cancel
+
+	go func() {
+		defer cancel()
+		start := m.config.Clock.Now()
+		result, err := m.RunTask(runCtx, t.info, t.info.Schedule.Input)
+
+		run := TaskRun{Start: start, Duration: m.config.Clock.Now().Sub(start), ExitCode: result.ExitCode}
+		switch {
+		case err != nil:
+			run.Err = err.Error()
+		case result.ExitCode != 0:
+			run.Err = fmt.Sprintf("exit code %v", result.ExitCode)
+		}
+		if run.Err != "" {
+			m.events.publish(pluginEvent(EventTaskFailed, t.info, run.Err))
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.history = append(t.history, run)
+		if len(t.history) > taskHistorySize {
+			t.history = t.history[len(t.history)-taskHistorySize:]
+		}
+		t.running = nil
+		if t.queued && ctx.Err() == nil {
+			t.queued = false
+			m.startRun(ctx, t)
+		}
+	}()
+}
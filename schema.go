@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PluginInfoSchemaVersion is the version of the PluginInfo serialization
+// format shared by manifests, the state store and the admin API.
+const PluginInfoSchemaVersion = 1
+
+// Duration is a time.Duration that serializes as a Go duration string such
+// as "1m30s", in JSON and YAML.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// pluginInfoFields has the fields of PluginInfo without its methods so it
+// can be embedded when marshaling.
+type pluginInfoFields PluginInfo
+
+type pluginInfoDocument struct {
+	SchemaVersion    int `json:"schemaVersion" yaml:"schemaVersion"`
+	pluginInfoFields `yaml:",inline"`
+}
+
+func (p PluginInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pluginInfoDocument{
+		SchemaVersion:    PluginInfoSchemaVersion,
+		pluginInfoFields: pluginInfoFields(p),
+	})
+}
+
+func (p *PluginInfo) UnmarshalJSON(data []byte) error {
+	return p.decode(data, false)
+}
+
+// MarshalYAML and UnmarshalYAML give YAML documents, as read and written by
+// gopkg.in/yaml.v3, the schema version and checks of the JSON format.
+func (p PluginInfo) MarshalYAML() (any, error) {
+	return pluginInfoDocument{
+		SchemaVersion:    PluginInfoSchemaVersion,
+		pluginInfoFields: pluginInfoFields(p),
+	}, nil
+}
+
+func (p *PluginInfo) UnmarshalYAML(unmarshal func(any) error) error {
+	var doc pluginInfoDocument
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	return p.fromDocument(doc)
+}
+
+// ParsePluginInfo decodes a PluginInfo from an untrusted source, such as a
+// manifest or an API request. Unlike json.Unmarshal it rejects unknown
+// fields and trailing data. Invalid fields are reported all at once in a
+// *ValidationError.
+func ParsePluginInfo(data []byte) (PluginInfo, error) {
+	var pm PluginInfo
+	err := pm.decode(data, true)
+	return pm, err
+}
+
+// ParsePluginInfos is ParsePluginInfo for a JSON array of plugins, which
+// must have distinct keys. The problems of every plugin are reported
+// together.
+func ParsePluginInfos(data []byte) ([]PluginInfo, error) {
+	var raw []json.RawMessage
+	if err := strictUnmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	var report ValidationReport
+	infos := make([]PluginInfo, 0, len(raw))
+	keys := make(map[string]bool, len(raw))
+	for i, item := range raw {
+		pm, err := ParsePluginInfo(item)
+		var verr *ValidationError
+		switch {
+		case errors.As(err, &verr):
+			report.Issues = append(report.Issues, verr.Issues...)
+			continue
+		case err != nil:
+			report.add("", fmt.Sprintf("[%v]", i), "%v", err)
+			continue
+		case pm.Key == "":
+			report.add("", fmt.Sprintf("[%v].key", i), "is empty")
+		case keys[pm.Key]:
+			report.add(pm.Key, "key", "is used by more than one plugin")
+		}
+		keys[pm.Key] = true
+		infos = append(infos, pm)
+	}
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+func (p *PluginInfo) decode(data []byte, strict bool) error {
+	var doc pluginInfoDocument
+	unmarshal := json.Unmarshal
+	if strict {
+		unmarshal = strictUnmarshal
+	}
+	if err := unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return p.fromDocument(doc)
+}
+
+// fromDocument checks a decoded document and sets p from it.
+func (p *PluginInfo) fromDocument(doc pluginInfoDocument) error {
+	if doc.SchemaVersion > PluginInfoSchemaVersion {
+		return fmt.Errorf(
+			"plugin info schema version %v is newer than supported version %v",
+			doc.SchemaVersion,
+			PluginInfoSchemaVersion,
+		)
+	}
+
+	pm := PluginInfo(doc.pluginInfoFields)
+	var report ValidationReport
+	checkPluginInfo(pm, &report)
+	if err := report.Err(); err != nil {
+		return err
+	}
+	pm.Checksum, _ = normalizeChecksum(pm.Checksum)
+	*p = pm
+	return nil
+}
+
+func strictUnmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// verifyChecksum checks the plugin binary against its checksum, if it has
+// one.
+func verifyChecksum(pm PluginInfo) error {
+	if pm.Checksum == "" {
+		return nil
+	}
+	got, err := fileSHA256(pm.BinPath)
+	if err != nil {
+		return err
+	}
+	return matchChecksum(pm, got)
+}
+
+// matchChecksum checks the digest of the plugin binary against its
+// checksum, if it has one.
+func matchChecksum(pm PluginInfo, got string) error {
+	if pm.Checksum == "" {
+		return nil
+	}
+	want, err := normalizeChecksum(pm.Checksum)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("plugin %v binary does not match its checksum", pm.Key)
+	}
+	return nil
+}
+
+// normalizeChecksum accepts a hex encoded sha256 digest, optionally prefixed
+// with "sha256:", and returns it in lower case without the prefix.
+func normalizeChecksum(checksum string) (string, error) {
+	if checksum == "" {
+		return "", nil
+	}
+	checksum = strings.TrimPrefix(strings.ToLower(checksum), "sha256:")
+	raw, err := hex.DecodeString(checksum)
+	if err != nil {
+		return "", fmt.Errorf("invalid checksum %q: %w", checksum, err)
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("invalid checksum %q: want 32 bytes, got %v", checksum, len(raw))
+	}
+	return checksum, nil
+}
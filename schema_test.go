@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func FuzzParsePluginInfo(f *testing.F) {
+	f.Add([]byte(`{"key":"a","binPath":"/bin/a"}`))
+	f.Add([]byte(`{"schemaVersion":1,"key":"a","binPath":"/bin/a","checksum":"SHA256:` + strings.Repeat("AB", 32) + `"}`))
+	f.Add([]byte(`{"key":"a","mirrors":["https://example.com/a"],"checksum":"` + strings.Repeat("0", 64) + `"}`))
+	f.Add([]byte(`{"key":"a","binPath":"/bin/a","args":["--dir=${HOME}"],"env":{"A":"b"},"sensitive":["A"]}`))
+	f.Add([]byte(`{"key":"a","binPath":"/bin/a","schedule":{"cron":"*/5 * * * *","overlap":"skip"}}`))
+	f.Add([]byte(`{"key":"a","binPath":"/bin/a","readinessGates":[{"http":"http://localhost/ready"}],"pprofAddr":"localhost:6060"}`))
+	f.Add([]byte(`{"key":"a","binPath":"/bin/a","minHostVersion":"1.2.0","lifecycle":"deprecated","maxInFlight":4}`))
+	f.Add([]byte(`{"key":"a","unknown":true}`))
+	f.Add([]byte(`{"key":"a"} {}`))
+	f.Add([]byte(`{"schemaVersion":99}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pm, err := ParsePluginInfo(data)
+		if err != nil {
+			return
+		}
+		// Whatever is accepted must survive a round trip unchanged.
+		out, err := json.Marshal(pm)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		again, err := ParsePluginInfo(out)
+		if err != nil {
+			t.Fatalf("ParsePluginInfo(%s): %v", out, err)
+		}
+		if out2, _ := json.Marshal(again); !bytes.Equal(out, out2) {
+			t.Fatalf("round trip changed %s to %s", out, out2)
+		}
+	})
+}
+
+func FuzzNormalizeChecksum(f *testing.F) {
+	f.Add("")
+	f.Add(strings.Repeat("ab", 32))
+	f.Add("sha256:" + strings.Repeat("AB", 32))
+	f.Add("SHA256:" + strings.Repeat("0", 64))
+	f.Add(strings.Repeat("a", 63))
+	f.Add(strings.Repeat("a", 66))
+	f.Add("sha256:sha256:" + strings.Repeat("0", 64))
+	f.Add("sha512:" + strings.Repeat("0", 64))
+	f.Fuzz(func(t *testing.T, checksum string) {
+		got, err := normalizeChecksum(checksum)
+		if err != nil || checksum == "" {
+			return
+		}
+		if len(got) != 64 || strings.Trim(got, "0123456789abcdef") != "" {
+			t.Fatalf("normalizeChecksum(%q) = %q, want 64 lower case hex digits", checksum, got)
+		}
+		if again, err := normalizeChecksum(got); err != nil || again != got {
+			t.Fatalf("normalizeChecksum(%q) = %q, %v, want it unchanged", got, again, err)
+		}
+	})
+}
+
+func TestPluginInfoYAML(t *testing.T) {
+	pm := PluginInfo{
+		Key:      "a",
+		BinPath:  "/bin/a",
+		Checksum: strings.Repeat("ab", 32),
+		Args:     []string{"-v"},
+		Env:      map[string]string{"A": "b"},
+		GRPC:     &GRPCOptions{KeepaliveTime: Duration(30 * time.Second), KeepaliveTimeout: Duration(5 * time.Second)},
+		Schedule: &TaskSchedule{Cron: "*/5 * * * *", Overlap: OverlapSkip},
+		ReadinessGates: []ReadinessGate{
+			{TCP: "localhost:5432", Timeout: Duration(time.Minute)},
+		},
+	}
+	out, err := yaml.Marshal(pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"schemaVersion: 1\n", "keepaliveTime: 30s\n", "timeout: 1m0s\n"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("YAML %s does not contain %q", out, want)
+		}
+	}
+	var got PluginInfo
+	if err := yaml.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, pm) {
+		t.Errorf("round trip of\n%s= %+v, want %+v", out, got, pm)
+	}
+
+	if err := yaml.Unmarshal([]byte("schemaVersion: 99\nkey: a\n"), &got); err == nil {
+		t.Error("decoded a newer schema version")
+	}
+}
+
+func TestPluginInfoDurations(t *testing.T) {
+	pm, err := ParsePluginInfo([]byte(`{"key":"a","binPath":"/bin/a",` +
+		`"grpc":{"keepaliveTime":"30s","keepaliveTimeout":"5s"},` +
+		`"readinessGates":[{"file":"/run/ready","timeout":"1m30s"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(pm.GRPC.KeepaliveTime); got != 30*time.Second {
+		t.Errorf("KeepaliveTime = %v, want 30s", got)
+	}
+	if got := time.Duration(pm.GRPC.KeepaliveTimeout); got != 5*time.Second {
+		t.Errorf("KeepaliveTimeout = %v, want 5s", got)
+	}
+	if got := time.Duration(pm.ReadinessGates[0].Timeout); got != 90*time.Second {
+		t.Errorf("readiness gate Timeout = %v, want 1m30s", got)
+	}
+}
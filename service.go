@@ -0,0 +1,40 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// runUntilSignal runs the manager until it receives an interrupt or
+// SIGTERM, which is how launchd and systemd stop daemons.
+func (m *Manager[C]) runUntilSignal() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return m.Run(ctx)
+}
+
+// levelWriter passes the lines written by an hclog logger to a system log
+// with the level of each line.
+type levelWriter struct {
+	write func(level hclog.Level, msg string) error
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	level := hclog.Info
+	for _, l := range []hclog.Level{hclog.Error, hclog.Warn, hclog.Debug, hclog.Trace} {
+		if strings.Contains(msg, "["+strings.ToUpper(l.String())+"]") {
+			level = l
+			break
+		}
+	}
+	if err := w.write(level, msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
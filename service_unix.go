@@ -0,0 +1,37 @@
+//go:build unix
+
+package manager
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// RunService runs the manager until launchd, systemd or the user stops it
+// with SIGTERM or an interrupt, then shuts it down.
+func (m *Manager[C]) RunService(name string) error {
+	return m.runUntilSignal()
+}
+
+// ServiceLogOutput returns a writer for hclog.LoggerOptions.Output logging
+// to syslog with the given tag, for daemons whose output is not collected.
+func ServiceLogOutput(tag string) (io.Writer, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &levelWriter{write: func(level hclog.Level, msg string) error {
+		switch level {
+		case hclog.Error:
+			return w.Err(msg)
+		case hclog.Warn:
+			return w.Warning(msg)
+		case hclog.Debug, hclog.Trace:
+			return w.Debug(msg)
+		default:
+			return w.Info(msg)
+		}
+	}}, nil
+}
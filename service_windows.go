@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"io"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// RunService runs the manager as the Windows service name, shutting it down
+// when the service is stopped or the system shuts down. Outside of the
+// service control manager it runs until interrupted.
+func (m *Manager[C]) RunService(name string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return m.runUntilSignal()
+	}
+	return svc.Run(name, &serviceHandler[C]{m: m})
+}
+
+type serviceHandler[C any] struct {
+	m *Manager[C]
+}
+
+func (h *serviceHandler[C]) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	if err := h.m.Start(); err != nil {
+		h.m.config.Logger.Error("failed to start plugin manager", LogKeyReason, err)
+		return true, 1
+	}
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for r := range requests {
+		switch r.Cmd {
+		case svc.Interrogate:
+			status <- r.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{
+				State:    svc.StopPending,
+				WaitHint: uint32(h.m.config.ShutdownTimeout.Milliseconds()),
+			}
+			if err := h.m.Shutdown(); err != nil {
+				h.m.config.Logger.Error("plugins did not shut down cleanly", LogKeyReason, err)
+				return true, 2
+			}
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// ServiceLogOutput returns a writer for hclog.LoggerOptions.Output logging
+// to the Windows event log under source, which must have been registered
+// when the service was installed.
+func ServiceLogOutput(source string) (io.Writer, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &levelWriter{write: func(level hclog.Level, msg string) error {
+		switch level {
+		case hclog.Error:
+			return l.Error(1, msg)
+		case hclog.Warn:
+			return l.Warning(1, msg)
+		default:
+			return l.Info(1, msg)
+		}
+	}}, nil
+}
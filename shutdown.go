@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrManagerClosed = errors.New("plugin manager is closed")
+
+// ShutdownError lists the plugins that did not exit within the shutdown
+// timeout.
+type ShutdownError struct {
+	Plugins []string
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("plugins did not terminate: %v", strings.Join(e.Plugins, ", "))
+}
+
+func stopAll[C any](instances []*pluginInstance[C], timeout time.Duration) error {
+	stopped := make(chan string, len(instances))
+	for _, p := range instances {
+		go func(p *pluginInstance[C]) {
+			p.Stop()
+			stopped <- p.Info.Key
+		}(p)
+	}
+
+	pending := make(map[string]int, len(instances))
+	for _, p := range instances {
+		pending[p.Info.Key]++
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for range instances {
+		select {
+		case key := <-stopped:
+			if pending[key]--; pending[key] == 0 {
+				delete(pending, key)
+			}
+		case <-timer.C:
+			keys := make([]string, 0, len(pending))
+			for key := range pending {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			return &ShutdownError{Plugins: keys}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EventLockAcquired EventType = "lock_acquired"
+	EventLockLost     EventType = "lock_lost"
+)
+
+// Locker hands out locks shared by the managers of a cluster. Singleton
+// plugins only run on the manager holding their lock.
+type Locker interface {
+	// Lock blocks until the lock is held or ctx is done.
+	Lock(ctx context.Context, name string) (Lease, error)
+}
+
+type Lease interface {
+	// Lost is closed when the lock is lost, e.g. because its session
+	// expired.
+	Lost() <-chan struct{}
+	Unlock() error
+}
+
+type singleton struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	held bool
+}
+
+// StartSingleton runs the plugin only while this manager holds its lock,
+// taking over when the holder dies. It returns immediately; use
+// SingletonHeld to find out whether the plugin runs here and StopSingleton,
+// rather than StopPlugin, to stop it.
+func (m *Manager[C]) StartSingleton(pm PluginInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.startSingletonLocked(pm)
+}
+
+func (m *Manager[C]) startSingletonLocked(pm PluginInfo) error {
+	if m.closed {
+		return ErrManagerClosed
+	}
+	if m.config.Locker == nil {
+		return fmt.Errorf("plugin %v is a singleton but no locker is configured", pm.Key)
+	}
+	if _, ok := m.singletons[pm.Key]; ok {
+		return fmt.Errorf("plugin %v is already started as a singleton", pm.Key)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &singleton{cancel: cancel, done: make(chan struct{})}
+	m.singletons[pm.Key] = s
+	go m.runSingleton(ctx, s, pm)
+	return nil
+}
+
+// StopSingleton stops the plugin if it runs here and gives up its lock.
+func (m *Manager[C]) StopSingleton(pluginKey string) error {
+	m.mu.Lock()
+	s, ok := m.singletons[pluginKey]
+	delete(m.singletons, pluginKey)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("plugin %v is not a singleton", pluginKey)
+	}
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// SingletonHeld reports whether this manager holds the lock of a singleton
+// plugin.
+func (m *Manager[C]) SingletonHeld(pluginKey string) (bool, error) {
+	m.mu.RLock()
+	s, ok := m.singletons[pluginKey]
+	m.mu.RUnlock()
+	if !ok {
+		return false, fmt.Errorf("plugin %v is not a singleton", pluginKey)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.held, nil
+}
+
+func (m *Manager[C]) runSingleton(ctx context.Context, s *singleton, pm PluginInfo) {
+	defer close(s.done)
+	name := m.Name + "/" + pm.Key
+
+	for {
+		lease, err := m.config.Locker.Lock(ctx, name)
+		if ctx.Err() != nil {
+			if err == nil {
+				lease.Unlock()
+			}
+			return
+		}
+		if err != nil {
+			m.logFor(pm).Error("failed to acquire singleton lock", LogKeyReason, err)
+			if sleep(ctx, m.config.Clock, m.config.RestartConfig.PingInterval) != nil {
+				return
+			}
+			continue
+		}
+
+		if _, err := m.StartPlugin(pm); err != nil {
+			m.logFor(pm).Error("failed to start singleton plugin", LogKeyReason, err)
+			lease.Unlock()
+			if sleep(ctx, m.config.Clock, m.config.RestartConfig.PingInterval) != nil {
+				return
+			}
+			continue
+		}
+		s.setHeld(true)
+		m.events.publish(pluginEvent(EventLockAcquired, pm, name))
+
+		select {
+		case <-ctx.Done():
+		case <-lease.Lost():
+			m.events.publish(pluginEvent(EventLockLost, pm, name))
+		}
+		s.setHeld(false)
+		if err := m.StopPlugin(pm); err != nil && !errors.Is(err, ErrManagerClosed) {
+			m.logFor(pm).Error("failed to stop singleton plugin", LogKeyReason, err)
+		}
+		lease.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (s *singleton) setHeld(held bool) {
+	s.mu.Lock()
+	s.held = held
+	s.mu.Unlock()
+}
+
+// FileLocker uses advisory file locks in Dir. It coordinates managers on
+// one host, or on hosts sharing a filesystem with working locks.
+type FileLocker struct {
+	Dir string
+	// PollInterval is how often a held lock is retried. Defaults to 1s.
+	PollInterval time.Duration
+}
+
+func (l *FileLocker) Lock(ctx context.Context, name string) (Lease, error) {
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(l.Dir, strings.ReplaceAll(name, "/", "_")+".lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	poll := l.PollInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			return &fileLease{f: f, lost: make(chan struct{})}, nil
+		}
+		if err := sleepCtx(ctx, poll); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+}
+
+// fileLease is never lost: the lock is held until it is released or the
+// process exits.
+type fileLease struct {
+	f    *os.File
+	lost chan struct{}
+	once sync.Once
+}
+
+func (l *fileLease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *fileLease) Unlock() error {
+	var err error
+	l.once.Do(func() {
+		err = unlockFile(l.f)
+		if cerr := l.f.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// SkewHost is a host release in a version skew matrix: the protocol versions
+// it serves, as in ManagerConfig.VersionedPlugins.
+type SkewHost struct {
+	Name             string
+	VersionedPlugins map[int]goplugin.Plugin
+}
+
+// SkewPlugin is a plugin release in a version skew matrix, usually the
+// binary of a past or upcoming version.
+type SkewPlugin struct {
+	Name string
+	Info PluginInfo
+}
+
+// SkewProbe exercises a started plugin, for instance by calling every method
+// of the interface, and returns an error if it misbehaves. version is the
+// negotiated protocol version.
+type SkewProbe[C any] func(ctx context.Context, version int, impl C) error
+
+// Stages at which a SkewResult failed.
+const (
+	SkewStageStart       = "start"
+	SkewStageConformance = "conformance"
+	SkewStageProbe       = "probe"
+)
+
+type SkewResult struct {
+	Host   string `json:"host"`
+	Plugin string `json:"plugin"`
+	// Version is the negotiated protocol version, when the plugin started.
+	Version    int           `json:"version,omitempty"`
+	Compatible bool          `json:"compatible"`
+	Stage      string        `json:"stage,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+type SkewReport struct {
+	Hosts   []string     `json:"hosts"`
+	Plugins []string     `json:"plugins"`
+	Results []SkewResult `json:"results"`
+}
+
+// Incompatible returns the pairs that failed.
+func (r SkewReport) Incompatible() []SkewResult {
+	var failed []SkewResult
+	for _, res := range r.Results {
+		if !res.Compatible {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the incompatible pairs, or nil.
+func (r SkewReport) Err() error {
+	var errs []error
+	for _, res := range r.Incompatible() {
+		errs = append(errs, fmt.Errorf("host %v, plugin %v: %v: %v", res.Host, res.Plugin, res.Stage, res.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// WriteTable writes the matrix with a row per plugin and a column per host.
+// Compatible cells show the negotiated version.
+func (r SkewReport) WriteTable(w io.Writer) error {
+	cells := make(map[[2]string]SkewResult, len(r.Results))
+	for _, res := range r.Results {
+		cells[[2]string{res.Plugin, res.Host}] = res
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PLUGIN\t%v\n", strings.Join(r.Hosts, "\t"))
+	for _, plugin := range r.Plugins {
+		row := []string{plugin}
+		for _, host := range r.Hosts {
+			res, ok := cells[[2]string{plugin, host}]
+			switch {
+			case !ok:
+				row = append(row, "-")
+			case res.Compatible:
+				row = append(row, fmt.Sprintf("ok v%v", res.Version))
+			default:
+				row = append(row, "FAIL "+res.Stage)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// RunSkewMatrix starts every plugin release under every host release and
+// reports which combinations negotiate a protocol version, conform to C and
+// pass probe, which may be nil. name is the plugin name of the protocol, as
+// passed to NewManager, and base the configuration shared by the hosts;
+// its VersionedPlugins are replaced by each host's. Plugins are started one
+// at a time and stopped right after they are probed.
+func RunSkewMatrix[C any](
+	ctx context.Context,
+	name string,
+	base ManagerConfig,
+	hosts []SkewHost,
+	plugins []SkewPlugin,
+	probe SkewProbe[C],
+) SkewReport {
+	var report SkewReport
+	for _, p := range plugins {
+		report.Plugins = append(report.Plugins, p.Name)
+	}
+	if base.Logger == nil {
+		base.Logger = hclog.NewNullLogger()
+	}
+	for _, host := range hosts {
+		report.Hosts = append(report.Hosts, host.Name)
+		config := base
+		config.VersionedPlugins = host.VersionedPlugins
+		config.RestartConfig.Managed = false
+		m := NewManager[C](name, &config)
+		for _, p := range plugins {
+			res := SkewResult{Host: host.Name, Plugin: p.Name}
+			start := time.Now()
+			res.Version, res.Stage, res.Error = runSkewCase(ctx, m, p.Info, probe)
+			res.Duration = time.Since(start)
+			res.Compatible = res.Error == ""
+			report.Results = append(report.Results, res)
+		}
+		m.Shutdown()
+	}
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Plugin < report.Results[j].Plugin
+	})
+	return report
+}
+
+func runSkewCase[C any](ctx context.Context, m *Manager[C], pm PluginInfo, probe SkewProbe[C]) (int, string, string) {
+	if pm.Key == "" {
+		pm.Key = "skew"
+	}
+	p, err := m.StartPlugin(pm)
+	if errors.Is(err, ErrNotConformant) {
+		return 0, SkewStageConformance, err.Error()
+	}
+	if err != nil {
+		return 0, SkewStageStart, err.Error()
+	}
+	defer m.StopPlugin(PluginInfo{Key: pm.Key})
+	version := p.ConnectionInfo().ProtocolVersion
+	if probe == nil {
+		return version, "", ""
+	}
+	err = m.Call(ctx, pm.Key, "skew_probe", func(ctx context.Context, impl C) error {
+		return probe(ctx, version, impl)
+	})
+	if err != nil {
+		return version, SkewStageProbe, err.Error()
+	}
+	return version, "", ""
+}
@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// TransportSocket runs plugins that are not built with go-plugin: any long
+// running process serving plain gRPC on the unix socket named by
+// LocalSocketEnv, which also replaces $PLUGIN_SOCKET in PluginInfo.Args.
+// The process is started without a handshake and health checked with the
+// standard gRPC health service when it has one, or by the connection
+// alone otherwise.
+const TransportSocket = "socket"
+
+const LocalSocketEnv = "PLUGIN_SOCKET"
+
+// startSocketProcess starts cmd, waits for it to listen on its socket and
+// points config at it. It returns the directory holding the socket, created
+// in root. cmd, built by command, carries the host environment and
+// PluginInfo.Env, to which LocalSocketEnv is added.
+func (m *Manager[C]) startSocketProcess(cmd *exec.Cmd, pm PluginInfo, config *goplugin.ClientConfig, root string, stderr io.Writer) (string, error) {
+	if pm.Protocol == goplugin.ProtocolNetRPC {
+		return "", fmt.Errorf("plugin %v: the socket transport only supports gRPC", pm.Key)
+	}
+	dir, err := os.MkdirTemp(root, "plugin-sock-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "plugin.sock")
+
+	for i, arg := range cmd.Args {
+		cmd.Args[i] = os.Expand(arg, func(name string) string {
+			if name == LocalSocketEnv {
+				return path
+			}
+			return "$" + name
+		})
+	}
+	cmd.Env = append(cmd.Env, LocalSocketEnv+"="+path)
+	cmd.Stdout = stderr
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	exited := make(chan struct{})
+	go func() {
+		// Reap the process; go-plugin only watches its pid.
+		cmd.Wait()
+		close(exited)
+	}()
+
+	timeout := time.NewTimer(m.config.SocketStartTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		select {
+		case <-exited:
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("plugin %v exited before listening on its socket: %v", pm.Key, cmd.ProcessState)
+		case <-timeout.C:
+			cmd.Process.Kill()
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("plugin %v did not listen on its socket within %v", pm.Key, m.config.SocketStartTimeout)
+		case <-ticker.C:
+		}
+	}
+
+	config.Cmd = nil
+	config.AutoMTLS = false
+	config.Reattach = &goplugin.ReattachConfig{
+		Protocol:        goplugin.ProtocolGRPC,
+		ProtocolVersion: int(config.HandshakeConfig.ProtocolVersion),
+		Addr:            &net.UnixAddr{Name: path, Net: "unix"},
+		Pid:             cmd.Process.Pid,
+	}
+	return dir, nil
+}
+
+// socketOutput logs what a socket plugin writes to stdout and stderr, which
+// go-plugin only reads from plugins it launches.
+func socketOutput(l hclog.Logger, capture io.Writer) io.Writer {
+	w := l.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
+	if capture != nil {
+		return io.MultiWriter(w, capture)
+	}
+	return w
+}
+
+// pingSocket checks a socket plugin with the gRPC health service, treating
+// services without one as healthy once they answer.
+func pingSocket(rpcClient goplugin.ClientProtocol) error {
+	c, ok := rpcClient.(*goplugin.GRPCClient)
+	if !ok {
+		return rpcClient.Ping()
+	}
+	_, err := grpc_health_v1.NewHealthClient(c.Conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	switch status.Code(err) {
+	case codes.Unimplemented, codes.NotFound:
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+func TestSocketProcessEnv(t *testing.T) {
+	t.Setenv("MANAGER_TEST_HOST", "host")
+	m := newTestManager(t, ManagerConfig{})
+	out := filepath.Join(t.TempDir(), "env")
+	pm := PluginInfo{
+		Key:     "s",
+		BinPath: "/bin/sh",
+		Args: []string{"-c", `printf '%s %s %s' "$MANAGER_TEST_HOST" "$MANAGER_TEST_PLUGIN_ENV" "$` + LocalSocketEnv + `" >"$MANAGER_TEST_OUT" &&
+			touch "$` + LocalSocketEnv + `" && exec sleep 10`},
+		Env: map[string]string{"MANAGER_TEST_PLUGIN_ENV": "plugin", "MANAGER_TEST_OUT": out},
+	}
+	cmd, _, err := command(context.Background(), &pm, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &goplugin.ClientConfig{HandshakeConfig: testHandshake}
+	dir, err := m.startSocketProcess(cmd, pm, config, t.TempDir(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "host plugin " + filepath.Join(dir, "plugin.sock"); string(got) != want {
+		t.Errorf("socket plugin environment = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// socketRoot returns the private directory holding the sockets of the
+// manager's plugins, creating it with an unpredictable name inside
+// ManagerConfig.SocketDir on first use.
+func (m *Manager[C]) socketRoot() (string, error) {
+	m.sockOnce.Do(func() {
+		parent := m.config.SocketDir
+		if parent != "" {
+			if m.sockErr = os.MkdirAll(parent, 0o700); m.sockErr != nil {
+				return
+			}
+		}
+		m.sockDir, m.sockErr = os.MkdirTemp(parent, "plugin-manager-")
+		if m.sockErr == nil {
+			m.checkSocketRoot()
+			m.sockErr = os.WriteFile(filepath.Join(m.sockDir, socketRootOwner), []byte(strconv.Itoa(os.Getpid())), 0o600)
+		}
+	})
+	return m.sockDir, m.sockErr
+}
+
+// checkSocketRoot warns when other users could reach the sockets, either
+// through the directory's permissions or by replacing it in a parent they
+// can write to.
+func (m *Manager[C]) checkSocketRoot() {
+	log := m.config.Logger
+	if fi, err := os.Stat(m.sockDir); err == nil && fi.Mode().Perm()&0o077 != 0 {
+		log.Warn("plugin socket directory is accessible to other users", "dir", m.sockDir, "mode", fi.Mode().Perm())
+	}
+	parent := filepath.Dir(m.sockDir)
+	if fi, err := os.Stat(parent); err == nil && fi.Mode().Perm()&0o002 != 0 && fi.Mode()&os.ModeSticky == 0 {
+		log.Warn("plugin socket directory is in a world-writable directory without the sticky bit", "dir", parent)
+	}
+}
+
+func (m *Manager[C]) removeSocketRoot() {
+	if m.sockDir != "" {
+		os.RemoveAll(m.sockDir)
+	}
+}
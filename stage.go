@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type stagedBinary struct {
+	source string
+	path   string
+	digest string
+}
+
+// stageBinary copies the plugin binary into DataDir/run and returns the
+// path of the copy, which is verified against the digest of the original.
+// The copy is reused, so restarts relaunch the same bytes, until the
+// plugin's BinPath or Checksum changes or unstage is called. The copy is
+// in use, and kept by the janitor, until release is called.
+func (m *Manager[C]) stageBinary(pm PluginInfo) (path string, release func(), err error) {
+	m.stageMu.Lock()
+	defer m.stageMu.Unlock()
+	if s, ok := m.staged[pm.Key]; ok && s.source == pm.BinPath && matchChecksum(pm, s.digest) == nil {
+		if got, err := m.binaryDigest(s.path); err == nil && got == s.digest {
+			return s.path, m.useStagedLocked(s.path), nil
+		}
+	}
+
+	want, err := m.binaryDigest(pm.BinPath)
+	if err != nil {
+		return "", nil, err
+	}
+	dir := filepath.Join(m.config.DataDir, "run", url.PathEscape(pm.Key))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", nil, err
+	}
+	path = filepath.Join(dir, want[:16]+filepath.Ext(pm.BinPath))
+	tmp, err := copyBinary(pm.BinPath, dir)
+	if err != nil {
+		return "", nil, err
+	}
+	got, err := fileSHA256(tmp)
+	if err == nil && got != want {
+		err = fmt.Errorf("%w: %v changed while it was staged", ErrChecksumChanged, pm.BinPath)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", nil, err
+	}
+
+	if s, ok := m.staged[pm.Key]; ok && s.path != path && m.stagedUse[s.path] == 0 {
+		os.Remove(s.path)
+	}
+	m.staged[pm.Key] = stagedBinary{source: pm.BinPath, path: path, digest: got}
+	return path, m.useStagedLocked(path), nil
+}
+
+// useStagedLocked marks path as in use by one more instance and returns
+// the func ending that use. m.stageMu must be held.
+func (m *Manager[C]) useStagedLocked(path string) func() {
+	m.stagedUse[path]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.stageMu.Lock()
+			defer m.stageMu.Unlock()
+			if m.stagedUse[path]--; m.stagedUse[path] <= 0 {
+				delete(m.stagedUse, path)
+			}
+		})
+	}
+}
+
+// unstage makes the next start of the plugin stage its binary again.
+func (m *Manager[C]) unstage(pluginKey string) {
+	m.stageMu.Lock()
+	defer m.stageMu.Unlock()
+	delete(m.staged, pluginKey)
+}
+
+func copyBinary(src, dir string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(dir, ".staging-")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Chmod(0o500)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
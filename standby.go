@@ -0,0 +1,114 @@
+package manager
+
+import "fmt"
+
+// EventFailedOver is published when the warm standby of a crashed plugin
+// takes over from it.
+const EventFailedOver EventType = "failed_over"
+
+// spawnStandby starts a warm standby for the primary instance pm in the
+// background if pm asks for one. The standby is health-checked like any
+// plugin but serves no calls until failover promotes it.
+func (m *Manager[C]) spawnStandby(pm PluginInfo) {
+	if !pm.Standby {
+		return
+	}
+	primary := pm.Generation
+	pm.Restarts = 0
+	pm.LastExit = nil
+	go func() {
+		killed := make(chan PluginInfo, 1)
+		s, err := m.loadPlugin(pm, killed)
+		if err != nil {
+			m.logFor(pm).Warn("failed to start standby", LogKeyReason, err)
+			return
+		}
+		m.mu.Lock()
+		p, ok := m.plugins[pm.Key]
+		if m.closed || !ok || p.Info.Generation != primary || m.standbys[pm.Key] != nil {
+			m.mu.Unlock()
+			s.Stop()
+			return
+		}
+		m.standbys[pm.Key] = s
+		m.watchLocked(s)
+		m.mu.Unlock()
+		m.logFor(s.Info).Debug("standby ready", "primary", primary)
+		go m.watchStandby(s, killed)
+	}()
+}
+
+// watchStandby handles the exit of a standby. A standby crashing before it
+// is promoted is replaced; once promoted, crashes are forwarded to the
+// supervisor like any other plugin.
+func (m *Manager[C]) watchStandby(s *pluginInstance[C], killed chan PluginInfo) {
+	<-s.done
+
+	var info PluginInfo
+	select {
+	case info = <-killed:
+	default:
+		return
+	}
+
+	m.mu.Lock()
+	if m.plugins[info.Key] == s {
+		m.mu.Unlock()
+		select {
+		case m.killed <- info:
+		default:
+		}
+		return
+	}
+	if m.standbys[info.Key] != s {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.standbys, info.Key)
+	p := m.plugins[info.Key]
+	m.mu.Unlock()
+
+	s.Stop()
+	if p != nil {
+		m.logFor(info).Warn("standby exited, replacing it")
+		m.spawnStandby(p.Info)
+	}
+}
+
+// failover promotes the standby of the crashed plugin pm, if it has one,
+// and starts a new standby.
+func (m *Manager[C]) failover(pm PluginInfo) bool {
+	m.mu.Lock()
+	s, ok := m.standbys[pm.Key]
+	old := m.plugins[pm.Key]
+	if !ok || m.closed || old == nil || old.Info.Generation != pm.Generation {
+		m.mu.Unlock()
+		return false
+	}
+	delete(m.standbys, pm.Key)
+	s.Info.Restarts = old.Info.Restarts + 1
+	s.Info.LastExit = pm.LastExit
+	m.plugins[pm.Key] = s
+	info := s.Info
+	m.mu.Unlock()
+
+	old.Stop()
+	m.logFor(info).Info("standby took over from crashed plugin", "previous", pm.Generation)
+	m.events.publish(pluginEvent(
+		EventFailedOver,
+		info,
+		fmt.Sprintf("standby generation %v took over from generation %v", info.Generation, pm.Generation),
+	))
+	m.spawnStandby(info)
+	return true
+}
+
+func (m *Manager[C]) stopStandby(pluginKey string) {
+	m.mu.Lock()
+	s, ok := m.standbys[pluginKey]
+	delete(m.standbys, pluginKey)
+	m.mu.Unlock()
+	if ok {
+		s.Stop()
+	}
+}
@@ -0,0 +1,285 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// StartupPhase is a step of starting a plugin:
+//   - preflight fetches and verifies the binary and runs the checks of
+//     approval, admission, scans and readiness gates, which may wait on
+//     operators and other services and have their own timeouts
+//   - exec prepares the launch of the verified binary: staging it,
+//     applying its sandbox and building its command
+//   - handshake launches the process and waits for it to announce its
+//     address, or for a TransportSocket plugin to listen
+//   - dispense creates the client and checks it conforms to the interface
+//   - readiness waits for the first successful health check
+type StartupPhase string
+
+const (
+	PhasePreflight StartupPhase = "preflight"
+	PhaseExec      StartupPhase = "exec"
+	PhaseHandshake StartupPhase = "handshake"
+	PhaseDispense  StartupPhase = "dispense"
+	PhaseReadiness StartupPhase = "readiness"
+)
+
+const (
+	EventStartupTimeout EventType = "startup_timeout"
+	EventSlowStart      EventType = "slow_start"
+)
+
+const startupSamples = 64
+
+var ErrStartupTimeout = errors.New("plugin startup timed out")
+
+// StartupTimeouts bounds each phase of starting a plugin. Zero leaves a
+// phase unbounded, except Handshake which defaults to go-plugin's minute.
+// Readiness is only checked when it is set. The preflight phase is not
+// bounded as a whole; Exec also bounds downloading the binary in it.
+type StartupTimeouts struct {
+	Exec      time.Duration
+	Handshake time.Duration
+	Dispense  time.Duration
+	Readiness time.Duration
+}
+
+// SlowStartConfig reports starts whose launch, from starting the process to
+// its handshake, takes more than Factor times the p95 of the launches of the
+// plugin's previous successful starts, once MinSamples were recorded. Time
+// spent in preflight checks is left out.
+type SlowStartConfig struct {
+	// Factor defaults to 2 and MinSamples to 5.
+	Factor     float64
+	MinSamples int
+}
+
+// StartupMetricsSink is implemented by MetricsSinks that also record plugin
+// start durations.
+type StartupMetricsSink interface {
+	ObserveStartup(pluginKey string, labels map[string]string, d time.Duration, slow bool, err error)
+}
+
+type PhaseTiming struct {
+	Phase    StartupPhase  `json:"phase"`
+	Duration time.Duration `json:"duration"`
+}
+
+// StartupReport records how long each phase of a start took. It is the Data
+// of EventStartupTimeout.
+type StartupReport struct {
+	Key        string        `json:"key"`
+	Generation uint64        `json:"generation,omitempty"`
+	Started    time.Time     `json:"started"`
+	Phases     []PhaseTiming `json:"phases"`
+	Total      time.Duration `json:"total"`
+	// Launch is the duration of the handshake phase, from launching the
+	// process to its handshake.
+	Launch time.Duration `json:"launch"`
+	// TimedOut names the phase that exceeded its timeout, if any.
+	TimedOut StartupPhase `json:"timedOut,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	// Baseline is the p95 of the launches of the previous successful
+	// starts, when ManagerConfig.SlowStart is set and enough were recorded,
+	// and Slow whether Launch exceeded it by the configured factor.
+	Baseline time.Duration `json:"baseline,omitempty"`
+	Slow     bool          `json:"slow,omitempty"`
+}
+
+type StartupTimeoutError struct {
+	Key     string
+	Phase   StartupPhase
+	Timeout time.Duration
+}
+
+func (e *StartupTimeoutError) Error() string {
+	return fmt.Sprintf("plugin %v: %v phase exceeded %v", e.Key, e.Phase, e.Timeout)
+}
+
+func (e *StartupTimeoutError) Unwrap() error {
+	return ErrStartupTimeout
+}
+
+// startupTimer times the phases of one start.
+type startupTimer struct {
+	key      string
+	timeouts StartupTimeouts
+	started  time.Time
+	phase    StartupPhase
+	since    time.Time
+	phases   []PhaseTiming
+}
+
+func newStartupTimer(key string, timeouts StartupTimeouts) *startupTimer {
+	now := time.Now()
+	return &startupTimer{key: key, timeouts: timeouts, started: now, phase: PhasePreflight, since: now}
+}
+
+func (t *startupTimer) timeout(phase StartupPhase) time.Duration {
+	switch phase {
+	case PhaseExec:
+		return t.timeouts.Exec
+	case PhaseHandshake:
+		return t.timeouts.Handshake
+	case PhaseDispense:
+		return t.timeouts.Dispense
+	case PhaseReadiness:
+		return t.timeouts.Readiness
+	}
+	return 0
+}
+
+// remaining returns the time left in the current phase, or zero if it is
+// unbounded.
+func (t *startupTimer) remaining() time.Duration {
+	timeout := t.timeout(t.phase)
+	if timeout == 0 {
+		return 0
+	}
+	if left := timeout - time.Since(t.since); left > 0 {
+		return left
+	}
+	return time.Nanosecond
+}
+
+// expired returns a StartupTimeoutError if the current phase ran past its
+// timeout.
+func (t *startupTimer) expired() error {
+	if timeout := t.timeout(t.phase); timeout > 0 && time.Since(t.since) >= timeout {
+		return &StartupTimeoutError{Key: t.key, Phase: t.phase, Timeout: timeout}
+	}
+	return nil
+}
+
+// next ends the current phase and starts phase.
+func (t *startupTimer) next(phase StartupPhase) {
+	now := time.Now()
+	t.phases = append(t.phases, PhaseTiming{Phase: t.phase, Duration: now.Sub(t.since)})
+	t.phase, t.since = phase, now
+}
+
+func (t *startupTimer) report(generation uint64, err error) StartupReport {
+	r := StartupReport{
+		Key:        t.key,
+		Generation: generation,
+		Started:    t.started,
+		Phases:     append(t.phases, PhaseTiming{Phase: t.phase, Duration: time.Since(t.since)}),
+		Total:      time.Since(t.started),
+	}
+	for _, p := range r.Phases {
+		if p.Phase == PhaseHandshake {
+			r.Launch = p.Duration
+		}
+	}
+	var timeout *StartupTimeoutError
+	if errors.As(err, &timeout) {
+		r.TimedOut = timeout.Phase
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// recordStartup keeps the report of the latest start of the plugin and
+// publishes EventStartupTimeout when a phase timed out.
+func (m *Manager[C]) recordStartup(pm PluginInfo, r StartupReport, err error) {
+	m.startMu.Lock()
+	if err == nil {
+		m.checkSlowStartLocked(&r)
+	}
+	m.startups[r.Key] = r
+	m.startMu.Unlock()
+
+	pm.Generation = r.Generation
+	if sink, ok := m.config.Metrics.(StartupMetricsSink); ok {
+		sink.ObserveStartup(r.Key, pm.Labels, r.Total, r.Slow, err)
+	}
+	if r.TimedOut != "" {
+		e := pluginEvent(EventStartupTimeout, pm, fmt.Sprintf("%v phase timed out after %v", r.TimedOut, r.Total))
+		e.Data = r
+		m.events.publish(e)
+	}
+	if r.Slow {
+		m.logFor(pm).Warn("slow plugin start", "launch", r.Launch, "baseline", r.Baseline)
+		e := pluginEvent(EventSlowStart, pm, fmt.Sprintf("launch took %v, p95 of previous launches is %v", r.Launch, r.Baseline))
+		e.Data = r
+		m.events.publish(e)
+	}
+}
+
+// checkSlowStartLocked compares a successful start with the plugin's
+// baseline, then adds it to the baseline.
+func (m *Manager[C]) checkSlowStartLocked(r *StartupReport) {
+	config := m.config.SlowStart
+	samples := m.startTimes[r.Key]
+	if config != nil && len(samples) >= config.MinSamples {
+		sorted := append([]time.Duration(nil), samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		r.Baseline = percentile(sorted, 0.95)
+		r.Slow = float64(r.Launch) > config.Factor*float64(r.Baseline)
+	}
+	if len(samples) == startupSamples {
+		samples = samples[1:]
+	}
+	m.startTimes[r.Key] = append(samples, r.Launch)
+}
+
+// StartupReport returns the report of the latest start of the plugin,
+// whether it succeeded or not.
+func (m *Manager[C]) StartupReport(pluginKey string) (StartupReport, error) {
+	m.startMu.Lock()
+	defer m.startMu.Unlock()
+	r, ok := m.startups[pluginKey]
+	if !ok {
+		return StartupReport{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	return r, nil
+}
+
+// dispense dispenses the plugin, giving up when the dispense phase times
+// out. Killing the client unblocks the abandoned call.
+func (t *startupTimer) dispense(rpcClient goplugin.ClientProtocol, name string) (any, error) {
+	timeout := t.remaining()
+	if timeout == 0 {
+		return rpcClient.Dispense(name)
+	}
+	type result struct {
+		raw any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		raw, err := rpcClient.Dispense(name)
+		done <- result{raw, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.raw, r.err
+	case <-timer.C:
+		return nil, &StartupTimeoutError{Key: t.key, Phase: t.phase, Timeout: t.timeouts.Dispense}
+	}
+}
+
+// awaitReady pings p until it answers or the readiness phase times out.
+func (t *startupTimer) awaitReady(p interface {
+	pingWithTimeout(time.Duration) (time.Duration, error)
+}) error {
+	for {
+		_, err := p.pingWithTimeout(t.remaining())
+		if err == nil {
+			return nil
+		}
+		if expired := t.expired(); expired != nil {
+			return fmt.Errorf("%w: %v", expired, err)
+		}
+		time.Sleep(readinessPollInterval)
+	}
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const latencySamples = 1024
+
+type MetricsSink interface {
+	ObserveCall(pluginKey, method string, labels map[string]string, d time.Duration, err error)
+}
+
+type MethodStats struct {
+	Calls  int64
+	Errors int64
+	// Canceled and DeadlineExceeded count the errors caused by the caller's
+	// context, including those reported by the plugin over gRPC.
+	Canceled         int64
+	DeadlineExceeded int64
+	ErrorRate        float64
+	P50              time.Duration
+	P90              time.Duration
+	P99              time.Duration
+}
+
+type methodStats struct {
+	calls            int64
+	errors           int64
+	canceled         int64
+	deadlineExceeded int64
+	samples          []time.Duration
+	next             int
+}
+
+func (s *methodStats) observe(d time.Duration, err error) {
+	s.calls++
+	if err != nil {
+		s.errors++
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled:
+		s.canceled++
+	case errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded:
+		s.deadlineExceeded++
+	}
+	if len(s.samples) < latencySamples {
+		s.samples = append(s.samples, d)
+		return
+	}
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % latencySamples
+}
+
+func (s *methodStats) snapshot() MethodStats {
+	out := MethodStats{
+		Calls:            s.calls,
+		Errors:           s.errors,
+		Canceled:         s.canceled,
+		DeadlineExceeded: s.deadlineExceeded,
+	}
+	if s.calls > 0 {
+		out.ErrorRate = float64(s.errors) / float64(s.calls)
+	}
+	sorted := append([]time.Duration(nil), s.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	out.P50 = percentile(sorted, 0.50)
+	out.P90 = percentile(sorted, 0.90)
+	out.P99 = percentile(sorted, 0.99)
+	return out
+}
+
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+type callStats struct {
+	mu      sync.Mutex
+	plugins map[string]map[string]*methodStats
+}
+
+func newCallStats() *callStats {
+	return &callStats{plugins: make(map[string]map[string]*methodStats)}
+}
+
+func (c *callStats) observe(pluginKey, method string, d time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	methods, ok := c.plugins[pluginKey]
+	if !ok {
+		methods = make(map[string]*methodStats)
+		c.plugins[pluginKey] = methods
+	}
+	s, ok := methods[method]
+	if !ok {
+		s = &methodStats{}
+		methods[method] = s
+	}
+	s.observe(d, err)
+}
+
+func (c *callStats) snapshot(pluginKey string) (map[string]MethodStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	methods, ok := c.plugins[pluginKey]
+	if !ok {
+		return nil, false
+	}
+	out := make(map[string]MethodStats, len(methods))
+	for name, s := range methods {
+		out[name] = s.snapshot()
+	}
+	return out, true
+}
+
+func (m *Manager[C]) Stats(pluginKey string) (map[string]MethodStats, error) {
+	stats, ok := m.stats.snapshot(pluginKey)
+	if !ok {
+		return nil, fmt.Errorf("no stats for plugin %v", pluginKey)
+	}
+	return stats, nil
+}
+
+func (c *callStats) totals(pluginKeys []string) InstanceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := InstanceStats{}
+	for _, key := range pluginKeys {
+		for _, s := range c.plugins[key] {
+			out.Calls += s.calls
+			out.Errors += s.errors
+		}
+	}
+	if out.Calls > 0 {
+		out.ErrorRate = float64(out.Errors) / float64(out.Calls)
+	}
+	return out
+}
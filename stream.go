@@ -0,0 +1,117 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+type StreamOptions struct {
+	// Backoff is the delay between attempts to reopen a broken stream.
+	// Defaults to one second.
+	Backoff time.Duration
+	// OnResubscribe is called before a broken stream is reopened.
+	OnResubscribe func(pluginKey string, attempt int, cause error)
+}
+
+// Stream keeps a stream to a plugin open across plugin restarts. open opens
+// the stream on the live instance and returns its receive function; every
+// received message is passed to handle. When receiving fails, the stream is
+// opened again on the instance that replaced the failed one.
+//
+// Stream returns nil when the plugin ends the stream with io.EOF, the error
+// of handle if it fails, or the context's error once ctx is done.
+func Stream[C, T any](
+	ctx context.Context,
+	m *Manager[C],
+	pluginKey string,
+	open func(context.Context, C) (func() (T, error), error),
+	handle func(T) error,
+	opts StreamOptions,
+) error {
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p, ok := m.getPlugin(pluginKey)
+		if !ok {
+			if m.isClosed() {
+				return ErrManagerClosed
+			}
+			if err := sleepCtx(ctx, opts.Backoff); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cause := receive(ctx, p.Impl, open, handle)
+		var handlerErr *streamHandlerError
+		switch {
+		case cause == nil:
+			return nil
+		case errors.As(cause, &handlerErr):
+			return handlerErr.err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+
+		if opts.OnResubscribe != nil {
+			opts.OnResubscribe(pluginKey, attempt+1, cause)
+		}
+		if err := sleepCtx(ctx, opts.Backoff); err != nil {
+			return err
+		}
+	}
+}
+
+type streamHandlerError struct {
+	err error
+}
+
+func (e *streamHandlerError) Error() string {
+	return e.err.Error()
+}
+
+func receive[C, T any](
+	ctx context.Context,
+	impl C,
+	open func(context.Context, C) (func() (T, error), error),
+	handle func(T) error,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	recv, err := open(ctx, impl)
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := handle(msg); err != nil {
+			return &streamHandlerError{err: err}
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
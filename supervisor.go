@@ -0,0 +1,224 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	EventSupervisorPanic EventType = "supervisor_panic"
+	EventWatcherPanic    EventType = "watcher_panic"
+	EventChecksumChanged EventType = "checksum_changed"
+	EventRestartFailed   EventType = "restart_failed"
+)
+
+var ErrChecksumChanged = errors.New("plugin binary changed since it was started")
+
+type SupervisorStatus struct {
+	Alive           bool
+	PendingRestarts int
+	// DelayedRestarts counts restarts waiting for the delay set by the
+	// restart strategy.
+	DelayedRestarts int
+	Restarting      string
+	Restarts        int
+	RestartTime     time.Duration
+	LastRestart     time.Duration
+	Panics          int
+	LastPanic       string
+	WatcherPanics   int
+}
+
+type supervisorState struct {
+	mu     sync.Mutex
+	status SupervisorStatus
+}
+
+func (s *supervisorState) update(fn func(*SupervisorStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.status)
+}
+
+func (s *supervisorState) snapshot() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (m *Manager[C]) SupervisorStatus() SupervisorStatus {
+	status := m.superv.snapshot()
+	status.PendingRestarts = len(m.killed)
+	return status
+}
+
+// supervisor runs the restart loop, starting it again if it panics, until
+// the manager is stopped.
+func (m *Manager[C]) supervisor() {
+	defer close(m.done)
+
+	m.superv.update(func(s *SupervisorStatus) { s.Alive = true })
+	defer m.superv.update(func(s *SupervisorStatus) { s.Alive = false })
+
+	for !m.superviseRestarts() {
+	}
+}
+
+func (m *Manager[C]) superviseRestarts() (stopped bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg := fmt.Sprintf("%v", r)
+			m.superv.update(func(s *SupervisorStatus) {
+				s.Panics++
+				s.LastPanic = msg
+				s.Restarting = ""
+			})
+			m.config.Logger.Error("supervisor panicked, restarting it", "panic", msg, "stack", string(debug.Stack()))
+			m.events.publish(Event{Type: EventSupervisorPanic, Message: msg})
+			stopped = false
+		}
+	}()
+
+	for {
+		select {
+		case pm := <-m.killed:
+			crash := Crash{Time: m.config.Clock.Now()}
+			if pm.LastExit != nil {
+				crash.Exit = *pm.LastExit
+			} else {
+				crash.Exit = ExitReason{Kind: ExitCrash, ExitCode: -1, InitiatedBy: InitiatedByPlugin, Time: crash.Time}
+			}
+			if m.observeCrash(pm, crash.Exit) {
+				m.stopStandby(pm.Key)
+				continue
+			}
+			if code := crash.Exit.ExitCode; code >= 0 && containsInt(m.config.RestartConfig.PreventRestartExitCodes, code) {
+				m.stopStandby(pm.Key)
+				m.logFor(pm).Warn("plugin exit code prevents restart", "code", code)
+				m.events.publish(pluginEvent(EventRestartPrevented, pm, fmt.Sprintf("exit code %v", code)))
+				continue
+			}
+			delay, ok := m.config.RestartConfig.Strategy.ShouldRestart(pm, crash)
+			if !ok {
+				m.stopStandby(pm.Key)
+				if crash.Exit.Failed() {
+					m.logFor(pm).Error("restart strategy gave up on plugin", "restarts", pm.Restarts)
+				} else {
+					m.logFor(pm).Info("plugin exited cleanly, not restarting it")
+				}
+				continue
+			}
+			if m.failover(pm) {
+				continue
+			}
+			if delay > 0 {
+				m.logFor(pm).Debug("delaying plugin restart", "delay", delay)
+				m.superv.update(func(s *SupervisorStatus) { s.DelayedRestarts++ })
+				m.config.Clock.AfterFunc(delay, func() {
+					select {
+					case m.due <- pm:
+					case <-m.stop:
+					}
+				})
+				continue
+			}
+			m.restart(pm)
+		case pm := <-m.due:
+			m.superv.update(func(s *SupervisorStatus) { s.DelayedRestarts-- })
+			m.restart(pm)
+		case <-m.stop:
+			return true
+		}
+	}
+}
+
+func (m *Manager[C]) restart(pm PluginInfo) {
+	if err := awaitWriters(m.ctx, m.config.Clock, pm.BinPath, m.config.BinaryLockTimeout); err != nil {
+		m.logFor(pm).Error("refusing to restart plugin", LogKeyReason, err)
+		return
+	}
+	if err := m.reverify(pm.Key); err != nil {
+		if !errors.Is(err, ErrChecksumChanged) || m.config.BinaryCheckInterval == 0 {
+			m.logFor(pm).Error("refusing to restart plugin", LogKeyReason, err)
+			m.events.publish(pluginEvent(EventChecksumChanged, pm, err.Error()))
+			return
+		}
+		m.events.publish(pluginEvent(EventBinaryChanged, pm, "binary replaced, reloading plugin"))
+	}
+	m.superv.update(func(s *SupervisorStatus) { s.Restarting = pm.Key })
+	start := m.config.Clock.Now()
+	err := m.RestartPlugin(pm)
+	elapsed := m.config.Clock.Now().Sub(start)
+	m.superv.update(func(s *SupervisorStatus) {
+		s.Restarting = ""
+		if err == nil {
+			s.Restarts++
+			s.RestartTime += elapsed
+			s.LastRestart = elapsed
+		}
+	})
+	if err != nil {
+		m.restartFailed(pm, err)
+	}
+}
+
+// restartFailed reports a plugin that could not be started again and hands
+// the failure back to the restart strategy as another crash, so that it
+// retries or gives up as it would for a plugin crashing on start.
+func (m *Manager[C]) restartFailed(pm PluginInfo, err error) {
+	msg := pm.redact(err.Error())
+	m.logFor(pm).Error("failed to restart plugin", LogKeyReason, msg)
+	m.events.publish(pluginEvent(EventRestartFailed, pm, msg))
+
+	m.mu.Lock()
+	m.retrying[pm.Key] = true
+	m.mu.Unlock()
+	pm.Restarts++
+	pm.LastExit = &ExitReason{
+		Kind:        ExitCrash,
+		ExitCode:    -1,
+		InitiatedBy: InitiatedByPlugin,
+		Time:        m.config.Clock.Now(),
+		Err:         msg,
+	}
+	// The supervisor is the only reader of m.killed.
+	go func() {
+		select {
+		case m.killed <- pm:
+		case <-m.stop:
+		}
+	}()
+}
+
+// reverify checks that the binary of a crashed plugin is still the one it
+// was started from, in case it was replaced while the plugin ran.
+func (m *Manager[C]) reverify(pluginKey string) error {
+	p, ok := m.getPlugin(pluginKey)
+	if !ok || p.digest == "" {
+		return nil
+	}
+	path := p.Info.BinPath
+	if p.Info.StagedPath != "" {
+		path = p.Info.StagedPath
+	}
+	got, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if got != p.digest {
+		return fmt.Errorf("%w: %v was sha256:%v, is sha256:%v", ErrChecksumChanged, path, p.digest, got)
+	}
+	return nil
+}
+
+// watcherPanicked reports a panic in a plugin's health check loop, which is
+// then started again.
+func (m *Manager[C]) watcherPanicked(info PluginInfo, r any) {
+	msg := fmt.Sprintf("%v", r)
+	m.superv.update(func(s *SupervisorStatus) { s.WatcherPanics++ })
+	m.logFor(info).Error("plugin watcher panicked, restarting it", "panic", msg, "stack", string(debug.Stack()))
+	m.events.publish(pluginEvent(EventWatcherPanic, info, msg))
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRestart(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy RestartStrategy
+		// delays are the restart delays expected after each crash, the
+		// plugin being left stopped after the following one when stopped is
+		// set.
+		delays  []time.Duration
+		stopped bool
+	}{
+		{name: "on failure", strategy: RestartOnFailure(5), delays: []time.Duration{0, 0}},
+		{name: "on failure gives up", strategy: RestartOnFailure(1), delays: []time.Duration{0}, stopped: true},
+		{name: "backoff", strategy: RestartBackoff(10*time.Second, 15*time.Second), delays: []time.Duration{10 * time.Second, 15 * time.Second}},
+		{name: "never", strategy: RestartNever(), stopped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type decision struct {
+				delay time.Duration
+				ok    bool
+			}
+			decisions := make(chan decision, 1)
+			strategy := RestartStrategyFunc(func(info PluginInfo, crash Crash) (time.Duration, bool) {
+				delay, ok := tt.strategy.ShouldRestart(info, crash)
+				decisions <- decision{delay, ok}
+				return delay, ok
+			})
+			clock := NewSimulatedClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+			m := newTestManager(t, ManagerConfig{
+				Clock:         clock,
+				RestartConfig: RestartConfig{Managed: true, PingInterval: time.Second, Strategy: strategy},
+			})
+			if err := m.Start(); err != nil {
+				t.Fatal(err)
+			}
+			pm := testPluginInfo(t, "p")
+			if _, err := m.StartPlugin(pm); err != nil {
+				t.Fatal(err)
+			}
+
+			crashes := len(tt.delays)
+			if tt.stopped {
+				crashes++
+			}
+			for i := 0; i < crashes; i++ {
+				generation := uint64(i + 1)
+				// Wait for the health check of the current instance.
+				clock.BlockUntil(1)
+				d, err := m.DescribePlugin(pm.Key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := syscall.Kill(d.Connection.Pid, syscall.SIGKILL); err != nil {
+					t.Fatal(err)
+				}
+				waitFor(t, "the plugin to exit", func() bool {
+					return errors.Is(syscall.Kill(d.Connection.Pid, 0), syscall.ESRCH)
+				})
+				clock.Advance(time.Second)
+
+				var got decision
+				select {
+				case got = <-decisions:
+				case <-time.After(5 * time.Second):
+					t.Fatal("the crash was not reported to the restart strategy")
+				}
+				if i == len(tt.delays) {
+					if got.ok {
+						t.Fatalf("crash %v: restarted, want the plugin stopped", i+1)
+					}
+					return
+				}
+				if !got.ok || got.delay != tt.delays[i] {
+					t.Fatalf("crash %v: restart %v after %v, want after %v", i+1, got.ok, got.delay, tt.delays[i])
+				}
+
+				if delay := tt.delays[i]; delay > 0 {
+					waitFor(t, "the restart to be delayed", func() bool {
+						return m.SupervisorStatus().DelayedRestarts == 1
+					})
+					clock.Advance(delay - time.Millisecond)
+					time.Sleep(20 * time.Millisecond)
+					if d, _ := m.DescribePlugin(pm.Key); d.Info.Generation != generation {
+						t.Fatalf("crash %v: restarted before the delay", i+1)
+					}
+					clock.Advance(time.Millisecond)
+				}
+				waitFor(t, "the restart", func() bool {
+					d, err := m.DescribePlugin(pm.Key)
+					return err == nil && d.Info.Generation == generation+1
+				})
+				g, err := m.GetPlugin(pm.Key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := g.Greet(); err != nil {
+					t.Fatalf("crash %v: restarted plugin: %v", i+1, err)
+				}
+			}
+			if got := m.SupervisorStatus().Restarts; got != len(tt.delays) {
+				t.Errorf("SupervisorStatus().Restarts = %v, want %v", got, len(tt.delays))
+			}
+		})
+	}
+}
+
+func TestSupervisorRestartFailure(t *testing.T) {
+	decisions := make(chan bool, 4)
+	strategy := RestartStrategyFunc(func(info PluginInfo, crash Crash) (time.Duration, bool) {
+		delay, ok := RestartOnFailure(2).ShouldRestart(info, crash)
+		decisions <- ok
+		return delay, ok
+	})
+	m := newTestManager(t, ManagerConfig{
+		RestartConfig: RestartConfig{Managed: true, PingInterval: 20 * time.Millisecond, Strategy: strategy},
+	})
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := m.Subscribe(16)
+	defer cancel()
+	exitFile := filepath.Join(t.TempDir(), "exit")
+	pm := testPluginInfo(t, "p")
+	pm.Env = map[string]string{testExitFileEnv: exitFile}
+	if _, err := m.StartPlugin(pm); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every restart fails from now on.
+	if err := os.WriteFile(exitFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	d, err := m.DescribePlugin(pm.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(d.Connection.Pid, syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+
+	// The crash and the two failed restarts are each handed to the
+	// strategy, which gives up on the third.
+	for i, want := range []bool{true, true, false} {
+		select {
+		case got := <-decisions:
+			if got != want {
+				t.Fatalf("decision %v: restart %v, want %v", i+1, got, want)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("decision %v was not made", i+1)
+		}
+	}
+	var failed int
+	for failed < 2 {
+		select {
+		case e := <-events:
+			if e.Type == EventRestartFailed {
+				failed++
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v restart_failed events, want 2", failed)
+		}
+	}
+	if _, err := m.DescribePlugin(pm.Key); !errors.Is(err, ErrPluginNotFound) {
+		t.Errorf("DescribePlugin after giving up: %v, want ErrPluginNotFound", err)
+	}
+	if err := m.StopPlugin(pm); err != nil {
+		t.Errorf("StopPlugin after giving up: %v", err)
+	}
+}
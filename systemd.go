@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SystemdConfig runs plugins in transient systemd scope units, created over
+// D-Bus by systemd-run, so that systemd applies resource limits and accounts
+// for them. The manager still launches and talks to the plugins.
+type SystemdConfig struct {
+	// User uses the service manager of the current user instead of the
+	// system one.
+	User bool
+	// Slice places the scopes in the given slice.
+	Slice string
+	// Properties are unit properties applied to every scope, such as
+	// MemoryMax or CPUQuota. PluginInfo.SystemdProperties override them.
+	Properties map[string]string
+}
+
+// UnitStatus is the resource accounting of a plugin's scope.
+type UnitStatus struct {
+	Unit          string
+	ActiveState   string
+	MemoryCurrent uint64
+	CPUUsage      time.Duration
+	TasksCurrent  uint64
+}
+
+// systemdScope wraps cmd in systemd-run so the plugin runs in its own scope
+// and returns the name of the scope.
+func (m *Manager[C]) systemdScope(cmd *exec.Cmd, pm PluginInfo) (string, error) {
+	config := m.config.Systemd
+	path, err := exec.LookPath("systemd-run")
+	if err != nil {
+		return "", fmt.Errorf("running plugin in a systemd scope: %w", err)
+	}
+
+	unit := fmt.Sprintf(
+		"plugin-%v-%v-%v.scope",
+		unitEscape(m.Name),
+		unitEscape(pm.Key),
+		strconv.FormatInt(time.Now().UnixNano(), 36),
+	)
+	args := []string{path, "--scope", "--quiet", "--collect", "--unit=" + unit}
+	if config.User {
+		args = append(args, "--user")
+	}
+	if config.Slice != "" {
+		args = append(args, "--slice="+config.Slice)
+	}
+
+	props := make(map[string]string, len(config.Properties)+len(pm.SystemdProperties))
+	for k, v := range config.Properties {
+		props[k] = v
+	}
+	for k, v := range pm.SystemdProperties {
+		props[k] = v
+	}
+	for _, k := range sortedKeys(props) {
+		args = append(args, "--property="+k+"="+props[k])
+	}
+
+	cmd.Path = path
+	cmd.Args = append(append(args, "--"), cmd.Args...)
+	return unit, nil
+}
+
+// unitEscape replaces the characters systemd does not allow in unit names.
+func unitEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// SystemdStatus returns the accounting systemd keeps for the plugin's scope.
+func (m *Manager[C]) SystemdStatus(pluginKey string) (UnitStatus, error) {
+	p, ok := m.getPlugin(pluginKey)
+	if !ok {
+		return UnitStatus{}, fmt.Errorf("%w: %v", ErrPluginNotFound, pluginKey)
+	}
+	if p.unit == "" {
+		return UnitStatus{}, errors.New("plugin does not run in a systemd scope")
+	}
+
+	args := []string{"show", p.unit, "--property=ActiveState,MemoryCurrent,CPUUsageNSec,TasksCurrent"}
+	if m.config.Systemd.User {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.Command("systemctl", args...).Output()
+	if err != nil {
+		return UnitStatus{}, fmt.Errorf("reading status of %v: %w", p.unit, err)
+	}
+
+	status := UnitStatus{Unit: p.unit}
+	props := parseUnitProperties(out)
+	status.ActiveState = props["ActiveState"]
+	status.MemoryCurrent, _ = strconv.ParseUint(props["MemoryCurrent"], 10, 64)
+	status.TasksCurrent, _ = strconv.ParseUint(props["TasksCurrent"], 10, 64)
+	if ns, err := strconv.ParseInt(props["CPUUsageNSec"], 10, 64); err == nil {
+		status.CPUUsage = time.Duration(ns)
+	}
+	return status, nil
+}
+
+func parseUnitProperties(out []byte) map[string]string {
+	props := make(map[string]string)
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		if k, v, ok := strings.Cut(s.Text(), "="); ok {
+			props[k] = v
+		}
+	}
+	return props
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+type TaskConfig struct {
+	// MaxConcurrent bounds the number of tasks running at the same time.
+	// Zero means no limit.
+	MaxConcurrent int
+	// Timeout bounds every task run. Zero means tasks only stop when their
+	// context is done.
+	Timeout time.Duration
+}
+
+type TaskResult struct {
+	Output   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+func (m *Manager[C]) acquireTask(ctx context.Context) (func(), error) {
+	if m.tasks == nil {
+		return func() {}, nil
+	}
+	select {
+	case m.tasks <- struct{}{}:
+		return func() { <-m.tasks }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *Manager[C]) taskContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.config.Tasks.Timeout > 0 {
+		return context.WithTimeout(ctx, m.config.Tasks.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// RunTask runs the plugin binary once outside of the supervised model,
+// feeding input on stdin and collecting stdout and the exit code. A non-zero
+// exit code is reported in the result, not as an error.
+func (m *Manager[C]) RunTask(ctx context.Context, pm PluginInfo, input []byte) (TaskResult, error) {
+	if m.isClosed() {
+		return TaskResult{}, ErrManagerClosed
+	}
+	// Tasks run the binary directly, so they pass the checks plugins do.
+	pm, pre, err := m.preflight(pm, newStartupTimer(pm.Key, StartupTimeouts{}))
+	if err != nil {
+		return TaskResult{}, err
+	}
+	defer pre.release()
+
+	release, err := m.acquireTask(ctx)
+	if err != nil {
+		return TaskResult{}, err
+	}
+	defer release()
+
+	ctx, cancel := m.taskContext(ctx)
+	defer cancel()
+
+	cmd, _, err := command(ctx, &pm, m.config.AllowUnsandboxed)
+	if err != nil {
+		return TaskResult{}, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	result := TaskResult{
+		Output:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: time.Since(start),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+	return result, nil
+}
+
+// RunPluginTask launches the plugin, makes a single call through fn and
+// tears the plugin down again.
+func RunPluginTask[C, R any](
+	ctx context.Context,
+	m *Manager[C],
+	pm PluginInfo,
+	fn func(context.Context, C) (R, error),
+) (R, error) {
+	var result R
+	if m.isClosed() {
+		return result, ErrManagerClosed
+	}
+
+	release, err := m.acquireTask(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer release()
+
+	ctx, cancel := m.taskContext(ctx)
+	defer cancel()
+
+	p, err := m.loadPlugin(pm, make(chan PluginInfo, 1))
+	if err != nil {
+		return result, err
+	}
+	defer p.Stop()
+
+	return fn(ctx, p.Impl)
+}
@@ -0,0 +1,319 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
+
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// ValidationIssue is a problem ValidatePlugins found with a plugin.
+type ValidationIssue struct {
+	Key     string `json:"key"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (i ValidationIssue) Error() string {
+	if i.Key == "" {
+		return fmt.Sprintf("%v: %v", i.Field, i.Message)
+	}
+	return fmt.Sprintf("plugin %v: %v: %v", i.Key, i.Field, i.Message)
+}
+
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (r ValidationReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Err returns a *ValidationError with the issues, or nil if there are
+// none.
+func (r ValidationReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return &ValidationError{Issues: r.Issues}
+}
+
+// ValidationError lists every problem found in plugin definitions, one per
+// line.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Issues))
+	for i, issue := range e.Issues {
+		errs[i] = issue
+	}
+	return errs
+}
+
+func (r *ValidationReport) add(key, field, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{Key: key, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidatePlugins checks plugins as LoadPlugins would receive them, without
+// launching anything: keys, binaries, checksums and dependencies, which may
+// also name plugins already running.
+func (m *Manager[C]) ValidatePlugins(plugins []PluginInfo) ValidationReport {
+	var report ValidationReport
+	keys := make(map[string]bool, len(plugins))
+	for _, pm := range plugins {
+		if pm.Key == "" {
+			report.add(pm.Key, "key", "is empty")
+		} else if keys[pm.Key] {
+			report.add(pm.Key, "key", "is used by more than one plugin")
+		}
+		keys[pm.Key] = true
+		checkPluginInfo(pm, &report)
+
+		switch {
+		case pm.BinPath != "":
+			if err := checkBinary(pm.BinPath); err != nil {
+				report.add(pm.Key, "binPath", "%v", err)
+			}
+		case len(pm.Mirrors) == 0:
+			report.add(pm.Key, "binPath", "is empty and the plugin has no mirrors")
+		case m.config.Fetcher == nil:
+			report.add(pm.Key, "mirrors", "no fetcher is configured to download the binary")
+		}
+		if _, ok := m.kind(pm.Kind); pm.Kind != "" && !ok {
+			report.add(pm.Key, "kind", "unknown kind %q", pm.Kind)
+		}
+	}
+
+	for _, pm := range plugins {
+		for _, dep := range pm.DependsOn {
+			if dep == pm.Key {
+				report.add(pm.Key, "dependsOn", "depends on itself")
+			} else if _, running := m.getPlugin(dep); !keys[dep] && !running {
+				report.add(pm.Key, "dependsOn", "unknown plugin %v", dep)
+			}
+		}
+	}
+	if _, err := dependencyOrder(plugins); err != nil {
+		report.add("", "dependsOn", "%v", err)
+	}
+	return report
+}
+
+// checkPluginInfo adds the problems of pm that can be found without the
+// manager or the filesystem, so decoding can report them too.
+func checkPluginInfo(pm PluginInfo, r *ValidationReport) {
+	if len(pm.Key) > 253 {
+		r.add(pm.Key, "key", "is longer than 253 bytes")
+	}
+	if strings.IndexFunc(pm.Key, func(c rune) bool { return unicode.IsSpace(c) || unicode.IsControl(c) }) >= 0 {
+		r.add(pm.Key, "key", "contains whitespace or control characters")
+	}
+	if strings.ContainsRune(pm.BinPath, 0) {
+		r.add(pm.Key, "binPath", "contains a NUL byte")
+	}
+	if _, err := normalizeChecksum(pm.Checksum); err != nil {
+		r.add(pm.Key, "checksum", "%v", err)
+	}
+	switch pm.Protocol {
+	case "", goplugin.ProtocolNetRPC, goplugin.ProtocolGRPC:
+	default:
+		r.add(pm.Key, "protocol", "unknown protocol %q", pm.Protocol)
+	}
+	switch {
+	case pm.Transport != "" && pm.Transport != TransportSocket:
+		r.add(pm.Key, "transport", "unknown transport %q", pm.Transport)
+	case pm.Transport == TransportSocket && pm.Protocol == goplugin.ProtocolNetRPC:
+		r.add(pm.Key, "transport", "the socket transport only supports gRPC")
+	}
+	if err := pm.GRPC.validate(); err != nil {
+		r.add(pm.Key, "grpc", "%v", err)
+	}
+	for i, arg := range pm.Args {
+		if err := checkTemplate(arg); err != nil {
+			r.add(pm.Key, fmt.Sprintf("args[%v]", i), "%v", err)
+		}
+	}
+	for _, name := range sortedKeys(pm.Env) {
+		if !validEnvName(name) {
+			r.add(pm.Key, "env", "invalid variable name %q", name)
+		}
+		if strings.ContainsRune(pm.Env[name], 0) {
+			r.add(pm.Key, "env", "value of %v contains a NUL byte", name)
+		}
+	}
+	for i, mirror := range pm.Mirrors {
+		u, err := url.Parse(mirror)
+		switch {
+		case err != nil:
+			r.add(pm.Key, fmt.Sprintf("mirrors[%v]", i), "%v", err)
+		case u.Scheme == "":
+			r.add(pm.Key, fmt.Sprintf("mirrors[%v]", i), "%q is not an absolute URL", mirror)
+		case (u.Scheme == "http" || u.Scheme == "https") && u.Host == "":
+			r.add(pm.Key, fmt.Sprintf("mirrors[%v]", i), "%q has no host", mirror)
+		}
+	}
+	for _, k := range sortedKeys(pm.Labels) {
+		if k == "" || strings.IndexFunc(k, unicode.IsControl) >= 0 {
+			r.add(pm.Key, "labels", "invalid label name %q", k)
+		}
+	}
+	for i, dep := range pm.DependsOn {
+		if dep == "" {
+			r.add(pm.Key, fmt.Sprintf("dependsOn[%v]", i), "is empty")
+		}
+	}
+	if _, err := parseVersion(pm.MinHostVersion); pm.MinHostVersion != "" && err != nil {
+		r.add(pm.Key, "minHostVersion", "%v", err)
+	}
+	if _, err := parseVersion(pm.MaxHostVersion); pm.MaxHostVersion != "" && err != nil {
+		r.add(pm.Key, "maxHostVersion", "%v", err)
+	}
+	switch pm.Lifecycle {
+	case LifecycleActive, LifecycleDeprecated, LifecycleEOL:
+	default:
+		r.add(pm.Key, "lifecycle", "unknown lifecycle %q", pm.Lifecycle)
+	}
+	if pm.MaxInFlight < 0 {
+		r.add(pm.Key, "maxInFlight", "is negative")
+	}
+	if pm.Schedule != nil {
+		if _, err := parseCron(pm.Schedule.Cron); err != nil {
+			r.add(pm.Key, "schedule", "%v", err)
+		}
+	}
+	if pm.PprofAddr != "" {
+		if err := checkLoopbackAddr(pm.PprofAddr); err != nil {
+			r.add(pm.Key, "pprofAddr", "%v", err)
+		}
+	}
+	for i, g := range pm.ReadinessGates {
+		field := fmt.Sprintf("readinessGates[%v]", i)
+		if err := g.validate(); err != nil {
+			r.add(pm.Key, field, "%v", err)
+		} else if g.HTTP != "" {
+			if u, err := url.Parse(g.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				r.add(pm.Key, field, "%q is not an http or https URL", g.HTTP)
+			}
+		}
+	}
+}
+
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c != '_' && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTemplate rejects the ${...} references os.Expand would silently
+// drop, such as an unterminated or empty one.
+func checkTemplate(s string) error {
+	if strings.ContainsRune(s, 0) {
+		return errors.New("contains a NUL byte")
+	}
+	for rest := s; ; {
+		i := strings.Index(rest, "${")
+		if i < 0 {
+			return nil
+		}
+		rest = rest[i+2:]
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return fmt.Errorf("unterminated ${ in %q", s)
+		}
+		if !validEnvName(rest[:end]) {
+			return fmt.Errorf("invalid variable reference ${%v}", rest[:end])
+		}
+		rest = rest[end+1:]
+	}
+}
+
+var (
+	ErrBinaryNotFound = errors.New("plugin binary not found")
+	ErrNotExecutable  = errors.New("plugin binary is not executable")
+)
+
+// checkBinary checks that path is an executable regular file, so a missing
+// or broken binary is reported as such rather than as a failed handshake.
+func checkBinary(path string) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrBinaryNotFound, path)
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: %v is not a regular file", ErrNotExecutable, path)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("%w: %v has no execute permission", ErrNotExecutable, path)
+	}
+	return nil
+}
+
+// dependencyOrder sorts plugins so that each comes after the plugins in the
+// list it depends on, keeping the original order otherwise.
+func dependencyOrder(plugins []PluginInfo) ([]PluginInfo, error) {
+	index := make(map[string]int, len(plugins))
+	for i, pm := range plugins {
+		if _, ok := index[pm.Key]; !ok {
+			index[pm.Key] = i
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(plugins))
+	ordered := make([]PluginInfo, 0, len(plugins))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", strings.Join(append(path, plugins[i].Key), " -> "))
+		}
+		state[i] = visiting
+		for _, dep := range plugins[i].DependsOn {
+			if j, ok := index[dep]; ok && dep != plugins[i].Key {
+				if err := visit(j, append(path, plugins[i].Key)); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = done
+		ordered = append(ordered, plugins[i])
+		return nil
+	}
+	for i := range plugins {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
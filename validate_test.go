@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// validRef matches the ${...} references os.Expand substitutes.
+var validRef = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+func FuzzCheckTemplate(f *testing.F) {
+	f.Add("")
+	f.Add("--dir=${HOME}/data")
+	f.Add("${A}${B_1}$C")
+	f.Add("$${A}")
+	f.Add("${")
+	f.Add("${}")
+	f.Add("${1A}")
+	f.Add("${A")
+	f.Add("${${A}}")
+	f.Add("a\x00b")
+	f.Fuzz(func(t *testing.T, s string) {
+		// Once the valid references are gone, no ${ may be left.
+		want := !strings.ContainsRune(s, 0) && !strings.Contains(validRef.ReplaceAllString(s, "x"), "${")
+		if err := checkTemplate(s); (err == nil) != want {
+			t.Fatalf("checkTemplate(%q) = %v, want ok %v", s, err, want)
+		}
+	})
+}
+
+func FuzzMirrors(f *testing.F) {
+	f.Add("https://example.com/plugins/a")
+	f.Add("http://10.0.0.1:8080/a?v=1")
+	f.Add("file:///srv/plugins/a")
+	f.Add("https:///a")
+	f.Add("/srv/plugins/a")
+	f.Add("example.com/a")
+	f.Add("http://[::1]:namedport")
+	f.Add("https://example.com/%zz")
+	f.Fuzz(func(t *testing.T, mirror string) {
+		var r ValidationReport
+		checkPluginInfo(PluginInfo{Key: "a", Mirrors: []string{mirror}}, &r)
+		for _, issue := range r.Issues {
+			if strings.HasPrefix(issue.Field, "mirrors") {
+				return
+			}
+		}
+		// Accepted mirrors must be usable by the Fetcher.
+		if host := mirrorHost(mirror); host == "" {
+			t.Fatalf("mirrorHost(%q) is empty", mirror)
+		}
+		if _, err := http.NewRequest(http.MethodGet, mirror, nil); err != nil {
+			t.Fatalf("mirror %q was accepted, but: %v", mirror, err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+// Package wasm runs WebAssembly plugins with wazero.
+//
+// WebAssembly plugins are served through go-plugin by a small runner binary
+// built by the host, so the manager starts, health checks, restarts and
+// sandboxes them like native plugins while the plugin code itself is
+// portable and confined to the WebAssembly sandbox:
+//
+//	//go:generate go run github.com/joshwizzy/go-plugin-manager/cmd/plugingen -type Greeter -kind wasm
+//
+//	func main() {
+//		wasm.Serve(handshake, "greeter", func(m *wasm.Module) plugin.Plugin {
+//			return &GreeterPlugin{Impl: GreeterWasm{Module: m}}
+//		})
+//	}
+//
+// with PluginInfo{BinPath: "greeter-wasm-runner", Args: []string{"greeter.wasm"}}.
+// The bindings generated by plugingen implement the host interface by
+// calling Module.Invoke.
+//
+// Modules export alloc(size i32) i32, returning a buffer of size bytes, and
+// one function per method taking the pointer and length of the request and
+// returning an i64 holding the pointer of the response in its upper 32 bits
+// and its length in the lower ones. With Invoke, functions are named after
+// the methods, requests are JSON arrays of the arguments and responses JSON
+// objects {"results": [...], "error": "..."}. Modules built for WASI, such
+// as Go wasip1 reactors, get WASI with both their stdout and stderr written
+// to the runner's stderr, since its stdout carries the go-plugin handshake.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Module is an instantiated WebAssembly plugin. Calls are serialized since
+// modules are single threaded.
+type Module struct {
+	mu      sync.Mutex
+	runtime wazero.Runtime
+	mod     api.Module
+	alloc   api.Function
+}
+
+// Load compiles and instantiates the module at path.
+func Load(ctx context.Context, path string) (*Module, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rt := wazero.NewRuntime(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+
+	compiled, err := rt.CompileModule(ctx, code)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	config := wazero.NewModuleConfig().WithStdout(os.Stderr).WithStderr(os.Stderr)
+	if _, ok := compiled.ExportedFunctions()["_initialize"]; ok {
+		config = config.WithStartFunctions("_initialize")
+	}
+	mod, err := rt.InstantiateModule(ctx, compiled, config)
+	if err != nil {
+		rt.Close(ctx)
+		return nil, err
+	}
+	alloc := mod.ExportedFunction("alloc")
+	if alloc == nil {
+		rt.Close(ctx)
+		return nil, fmt.Errorf("module %v does not export alloc", path)
+	}
+	return &Module{runtime: rt, mod: mod, alloc: alloc}, nil
+}
+
+// Call calls the exported function name with req and returns a copy of its
+// response.
+func (m *Module) Call(ctx context.Context, name string, req []byte) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn := m.mod.ExportedFunction(name)
+	if fn == nil {
+		return nil, fmt.Errorf("module does not export %v", name)
+	}
+	res, err := m.alloc.Call(ctx, uint64(len(req)))
+	if err != nil {
+		return nil, err
+	}
+	ptr := uint32(res[0])
+	if !m.mod.Memory().Write(ptr, req) {
+		return nil, fmt.Errorf("request of %v bytes is out of module memory", len(req))
+	}
+	res, err = fn.Call(ctx, uint64(ptr), uint64(len(req)))
+	if err != nil {
+		return nil, err
+	}
+	out, ok := m.mod.Memory().Read(uint32(res[0]>>32), uint32(res[0]))
+	if !ok {
+		return nil, fmt.Errorf("response of %v is out of module memory", name)
+	}
+	return append([]byte(nil), out...), nil
+}
+
+// Invoke calls the exported function name with the JSON array of args and
+// decodes the results of its response into the pointers in results.
+func (m *Module) Invoke(ctx context.Context, name string, args []any, results []any) error {
+	req, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	out, err := m.Call(ctx, name, req)
+	if err != nil {
+		return err
+	}
+	var res struct {
+		Results []json.RawMessage `json:"results"`
+		Error   string            `json:"error"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return fmt.Errorf("response of %v: %w", name, err)
+	}
+	if res.Error != "" {
+		return errors.New(res.Error)
+	}
+	if len(res.Results) != len(results) {
+		return fmt.Errorf("%v returned %v results, expected %v", name, len(res.Results), len(results))
+	}
+	for i, r := range res.Results {
+		if err := json.Unmarshal(r, results[i]); err != nil {
+			return fmt.Errorf("result %v of %v: %w", i, name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+// Serve loads the module named by the first argument of the runner and
+// serves it as the plugin name through go-plugin. newPlugin wraps the module
+// in the bindings implementing the host interface.
+func Serve(handshake goplugin.HandshakeConfig, name string, newPlugin func(*Module) goplugin.Plugin) {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: runner module.wasm")
+		os.Exit(2)
+	}
+	ctx := context.Background()
+	m, err := Load(ctx, os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer m.Close(ctx)
+
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: handshake,
+		Plugins:         goplugin.PluginSet{name: newPlugin(m)},
+	})
+}
@@ -0,0 +1,174 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of webhook requests. WebhookSignatureHeader is "sha256=" followed
+// by the hex HMAC-SHA256 of the timestamp, a dot and the body.
+const (
+	WebhookEventHeader     = "X-Plugin-Event"
+	WebhookDeliveryHeader  = "X-Plugin-Delivery"
+	WebhookTimestampHeader = "X-Plugin-Timestamp"
+	WebhookSignatureHeader = "X-Plugin-Signature"
+)
+
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookConfig posts events as JSON to URLs, so alerting systems learn
+// about plugin failures. Each URL receives the events in order, with its
+// own queue and retries.
+type WebhookConfig struct {
+	URLs []string
+	// Filter selects the events posted. Defaults to failed exits and
+	// EventRestartPrevented and EventQuarantined.
+	Filter func(Event) bool
+	// Secret, when set, signs requests, see VerifyWebhook.
+	Secret []byte
+	// MaxRetries defaults to 3 and Backoff, doubled after every attempt,
+	// to 1s. Requests failing with a 4xx status other than 429 are not
+	// retried.
+	MaxRetries int
+	Backoff    time.Duration
+	// Timeout bounds each attempt. Defaults to 10s.
+	Timeout time.Duration
+	Client  *http.Client
+	// Buffer is the number of events queued per URL, 256 by default.
+	// Events are dropped while the queue is full.
+	Buffer int
+}
+
+func webhookDefaults(config *WebhookConfig) {
+	if config.Filter == nil {
+		config.Filter = failureEvents
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.Backoff == 0 {
+		config.Backoff = time.Second
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Buffer == 0 {
+		config.Buffer = 256
+	}
+}
+
+func failureEvents(e Event) bool {
+	switch e.Type {
+	case EventRestartPrevented, EventQuarantined, EventFDExhausted:
+		return true
+	case EventExited:
+		r, ok := e.Data.(CrashReport)
+		return !ok || r.Exit.Failed()
+	}
+	return false
+}
+
+// VerifyWebhook checks the signature of a webhook request whose body was
+// read into body, rejecting requests signed more than maxAge ago.
+func VerifyWebhook(secret []byte, r *http.Request, body []byte, maxAge time.Duration) error {
+	ts := r.Header.Get(WebhookTimestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidWebhookSignature)
+	}
+	if maxAge > 0 && time.Since(time.Unix(sec, 0)) > maxAge {
+		return fmt.Errorf("%w: too old", ErrInvalidWebhookSignature)
+	}
+	if !hmac.Equal([]byte(r.Header.Get(WebhookSignatureHeader)), []byte(signWebhook(secret, ts, body))) {
+		return ErrInvalidWebhookSignature
+	}
+	return nil
+}
+
+func signWebhook(secret []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// startWebhooks subscribes a sender for every URL.
+func (m *Manager[C]) startWebhooks(ctx context.Context, config *WebhookConfig) {
+	for _, url := range config.URLs {
+		m.forwardEvents(ctx, config.Buffer, func(e Event) {
+			if !config.Filter(e) {
+				return
+			}
+			if err := m.postWebhook(ctx, config, url, e); err != nil && ctx.Err() == nil {
+				m.config.Logger.Warn("failed to deliver webhook", "url", url, "event", e.Type, LogKeyReason, err)
+			}
+		})
+	}
+}
+
+func (m *Manager[C]) postWebhook(ctx context.Context, config *WebhookConfig, url string, e Event) error {
+	body, err := MarshalEvent(e)
+	if err != nil {
+		return err
+	}
+	delivery, err := newInstanceID()
+	if err != nil {
+		return err
+	}
+
+	backoff := config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := sendWebhook(ctx, config, url, delivery, e.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == config.MaxRetries {
+			return err
+		}
+		if err := sleep(ctx, m.config.Clock, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// sendWebhook makes one attempt, reporting whether a failure is worth
+// retrying.
+func sendWebhook(ctx context.Context, config *WebhookConfig, url, delivery string, t EventType, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(t))
+	req.Header.Set(WebhookDeliveryHeader, delivery)
+	req.Header.Set(WebhookTimestampHeader, ts)
+	if len(config.Secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, signWebhook(config.Secret, ts, body))
+	}
+	resp, err := config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook answered %v", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
@@ -0,0 +1,32 @@
+// Package zaplogger adapts zap loggers to manager.Logger.
+package zaplogger
+
+import (
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type logger struct {
+	l *zap.SugaredLogger
+}
+
+// New logs to l. Trace messages are logged at debug level.
+func New(l *zap.Logger) manager.Logger {
+	return logger{l.WithOptions(zap.AddCallerSkip(3)).Sugar()}
+}
+
+func (z logger) Log(level manager.LogLevel, msg string, args ...any) {
+	var l zapcore.Level
+	switch level {
+	case manager.LevelTrace, manager.LevelDebug:
+		l = zapcore.DebugLevel
+	case manager.LevelInfo:
+		l = zapcore.InfoLevel
+	case manager.LevelWarn:
+		l = zapcore.WarnLevel
+	default:
+		l = zapcore.ErrorLevel
+	}
+	z.l.Logw(l, msg, args...)
+}
@@ -0,0 +1,33 @@
+// Package zerologger adapts zerolog loggers to manager.Logger.
+package zerologger
+
+import (
+	manager "github.com/joshwizzy/go-plugin-manager"
+	"github.com/rs/zerolog"
+)
+
+type logger struct {
+	l zerolog.Logger
+}
+
+// New logs to l.
+func New(l zerolog.Logger) manager.Logger {
+	return logger{l}
+}
+
+func (z logger) Log(level manager.LogLevel, msg string, args ...any) {
+	var l zerolog.Level
+	switch level {
+	case manager.LevelTrace:
+		l = zerolog.TraceLevel
+	case manager.LevelDebug:
+		l = zerolog.DebugLevel
+	case manager.LevelInfo:
+		l = zerolog.InfoLevel
+	case manager.LevelWarn:
+		l = zerolog.WarnLevel
+	default:
+		l = zerolog.ErrorLevel
+	}
+	z.l.WithLevel(l).Fields(args).Msg(msg)
+}